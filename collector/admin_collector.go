@@ -0,0 +1,240 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// AdminCollector collects daemon level metrics through the libvirt admin
+// interface: connected clients, worker pool usage and pending jobs
+type AdminCollector struct {
+	adminUp         *prometheus.Desc
+	clients         *prometheus.Desc
+	clientsMax      *prometheus.Desc
+	unauthClients   *prometheus.Desc
+	workersCurrent  *prometheus.Desc
+	workersFree     *prometheus.Desc
+	workersMin      *prometheus.Desc
+	workersMax      *prometheus.Desc
+	workersPriority *prometheus.Desc
+	jobQueueDepth   *prometheus.Desc
+
+	uri   string
+	mutex sync.Mutex
+	conn  *libvirt.AdmConnect
+}
+
+// NewAdminCollector creates a new AdminCollector for an admin URI such as
+// libvirtd:///system or virtqemud:///system
+func NewAdminCollector(uri string) *AdminCollector {
+	return &AdminCollector{
+		adminUp: prometheus.NewDesc(
+			"libvirt_daemon_admin_up",
+			"Whether the libvirt admin interface is reachable (1=up, 0=down)",
+			[]string{},
+			nil,
+		),
+		clients: prometheus.NewDesc(
+			"libvirt_daemon_clients",
+			"Number of clients connected to the daemon server",
+			[]string{"server"},
+			nil,
+		),
+		clientsMax: prometheus.NewDesc(
+			"libvirt_daemon_clients_max",
+			"Maximum number of clients accepted by the daemon server",
+			[]string{"server"},
+			nil,
+		),
+		unauthClients: prometheus.NewDesc(
+			"libvirt_daemon_unauth_clients",
+			"Number of clients of the daemon server waiting for authentication",
+			[]string{"server"},
+			nil,
+		),
+		workersCurrent: prometheus.NewDesc(
+			"libvirt_daemon_workers",
+			"Current number of workers in the daemon server thread pool",
+			[]string{"server"},
+			nil,
+		),
+		workersFree: prometheus.NewDesc(
+			"libvirt_daemon_workers_free",
+			"Number of idle workers in the daemon server thread pool",
+			[]string{"server"},
+			nil,
+		),
+		workersMin: prometheus.NewDesc(
+			"libvirt_daemon_workers_min",
+			"Minimum number of workers in the daemon server thread pool",
+			[]string{"server"},
+			nil,
+		),
+		workersMax: prometheus.NewDesc(
+			"libvirt_daemon_workers_max",
+			"Maximum number of workers in the daemon server thread pool",
+			[]string{"server"},
+			nil,
+		),
+		workersPriority: prometheus.NewDesc(
+			"libvirt_daemon_workers_priority",
+			"Number of priority workers in the daemon server thread pool",
+			[]string{"server"},
+			nil,
+		),
+		jobQueueDepth: prometheus.NewDesc(
+			"libvirt_daemon_job_queue_depth",
+			"Number of jobs waiting for a worker in the daemon server",
+			[]string{"server"},
+			nil,
+		),
+		uri: uri,
+	}
+}
+
+// Describe implements the prometheus.Collector interface for AdminCollector
+func (c *AdminCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.adminUp
+	ch <- c.clients
+	ch <- c.clientsMax
+	ch <- c.unauthClients
+	ch <- c.workersCurrent
+	ch <- c.workersFree
+	ch <- c.workersMin
+	ch <- c.workersMax
+	ch <- c.workersPriority
+	ch <- c.jobQueueDepth
+}
+
+// Reset implements the Collector interface for AdminCollector
+func (c *AdminCollector) Reset() {
+	// No internal state to reset
+}
+
+// Collect implements the Collector interface for AdminCollector; its metrics
+// are host-wide and sent by CollectHost
+func (c *AdminCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+}
+
+// CollectHost implements the HostCollector interface for AdminCollector
+func (c *AdminCollector) CollectHost(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	includes func(name, uuid string) bool,
+) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	servers, err := c.listServers()
+	var upValue float64
+	if err == nil {
+		upValue = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.adminUp,
+		prometheus.GaugeValue,
+		upValue,
+	)
+	if err != nil {
+		slog.Warn("Failed to query libvirt admin interface", "err", err)
+		return
+	}
+	defer func() {
+		for _, server := range servers {
+			server.Free()
+		}
+	}()
+
+	for i := range servers {
+		c.collectServer(ch, &servers[i])
+	}
+}
+
+// listServers lists the daemon servers, (re)connecting to the admin interface
+// if needed
+func (c *AdminCollector) listServers() ([]libvirt.AdmServer, error) {
+	if c.conn == nil {
+		conn, err := libvirt.NewAdmConnect(c.uri, 0)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+	}
+
+	servers, err := c.conn.ListServers(0)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return nil, err
+	}
+	return servers, nil
+}
+
+// collectServer collects the client and thread pool metrics of a daemon server
+func (c *AdminCollector) collectServer(ch chan<- prometheus.Metric, server *libvirt.AdmServer) {
+	name, err := server.GetName()
+	if err != nil {
+		slog.Warn("Failed to get daemon server name", "err", err)
+		return
+	}
+
+	limits, err := server.GetClientLimits(0)
+	if err != nil {
+		slog.Warn("Failed to get client limits of daemon server", "server", name, "err", err)
+	} else {
+		if limits.CurrentClientsSet {
+			ch <- prometheus.MustNewConstMetric(c.clients, prometheus.GaugeValue, float64(limits.CurrentClients), name)
+		}
+		if limits.MaxClientsSet {
+			ch <- prometheus.MustNewConstMetric(c.clientsMax, prometheus.GaugeValue, float64(limits.MaxClients), name)
+		}
+		if limits.CurrentUnauthClientsSet {
+			ch <- prometheus.MustNewConstMetric(c.unauthClients, prometheus.GaugeValue, float64(limits.CurrentUnauthClients), name)
+		}
+	}
+
+	pool, err := server.GetThreadPoolParameters(0)
+	if err != nil {
+		slog.Warn("Failed to get thread pool of daemon server", "server", name, "err", err)
+		return
+	}
+	if pool.CurrentWorkersSet {
+		ch <- prometheus.MustNewConstMetric(c.workersCurrent, prometheus.GaugeValue, float64(pool.CurrentWorkers), name)
+	}
+	if pool.FreeWorkersSet {
+		ch <- prometheus.MustNewConstMetric(c.workersFree, prometheus.GaugeValue, float64(pool.FreeWorkers), name)
+	}
+	if pool.MinWorkersSet {
+		ch <- prometheus.MustNewConstMetric(c.workersMin, prometheus.GaugeValue, float64(pool.MinWorkers), name)
+	}
+	if pool.MaxWorkersSet {
+		ch <- prometheus.MustNewConstMetric(c.workersMax, prometheus.GaugeValue, float64(pool.MaxWorkers), name)
+	}
+	if pool.PrioWorkersSet {
+		ch <- prometheus.MustNewConstMetric(c.workersPriority, prometheus.GaugeValue, float64(pool.PrioWorkers), name)
+	}
+	if pool.JobQueueDepthSet {
+		ch <- prometheus.MustNewConstMetric(c.jobQueueDepth, prometheus.GaugeValue, float64(pool.JobQueueDepth), name)
+	}
+}
+
+// Close closes the admin connection
+func (c *AdminCollector) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
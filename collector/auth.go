@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"net/url"
+
+	"libvirt.org/go/libvirt"
+)
+
+// newConnectAuth returns the credentials answered to libvirt when a remote
+// connection asks for them (e.g. SASL), nil if none are configured
+func newConnectAuth(username, password string) *libvirt.ConnectAuth {
+	if username == "" && password == "" {
+		return nil
+	}
+
+	return &libvirt.ConnectAuth{
+		CredType: []libvirt.ConnectCredentialType{
+			libvirt.CRED_AUTHNAME,
+			libvirt.CRED_PASSPHRASE,
+			libvirt.CRED_NOECHOPROMPT,
+		},
+		Callback: func(creds []*libvirt.ConnectCredential) {
+			for _, cred := range creds {
+				switch cred.Type {
+				case libvirt.CRED_AUTHNAME:
+					cred.Result = username
+				case libvirt.CRED_PASSPHRASE, libvirt.CRED_NOECHOPROMPT:
+					cred.Result = password
+				default:
+					continue
+				}
+				cred.ResultLen = len(cred.Result)
+			}
+		},
+	}
+}
+
+// remoteURI adds the client certificate directory of TLS transports and the
+// key and known hosts files of SSH transports to a libvirt URI. Parameters
+// already present in the URI are kept; local URIs are returned unchanged.
+func remoteURI(uri string, opts Options) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Host == "" {
+		return uri
+	}
+
+	params := map[string]string{}
+	switch uriTransport(uri) {
+	case "tls":
+		params["pkipath"] = opts.TLSPKIPath
+	case "ssh", "libssh", "libssh2":
+		params["keyfile"] = opts.SSHKeyFile
+		params["known_hosts"] = opts.SSHKnownHosts
+	}
+
+	query := parsed.Query()
+	changed := false
+	for name, value := range params {
+		if value != "" && !query.Has(name) {
+			query.Set(name, value)
+			changed = true
+		}
+	}
+	if !changed {
+		return uri
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// remoteURIs applies remoteURI to a list of URIs
+func remoteURIs(uris []string, opts Options) []string {
+	result := make([]string, len(uris))
+	for i, uri := range uris {
+		result[i] = remoteURI(uri, opts)
+	}
+	return result
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BackgroundCollection runs full scrapes on a fixed interval and keeps the
+// samples of the last one, so that scrapes are answered instantly whatever
+// the responsiveness of libvirt
+type BackgroundCollection struct {
+	interval    time.Duration
+	collect     func(ctx context.Context, ch chan<- prometheus.Metric)
+	mutex       sync.RWMutex
+	metrics     []prometheus.Metric
+	collectedAt time.Time
+	ready       chan struct{} // closed once the first collection finished
+	stop        chan struct{}
+	done        chan struct{}
+	desc        *prometheus.Desc
+}
+
+// NewBackgroundCollection creates a BackgroundCollection running collect
+// every interval. Each collection is bounded by the interval.
+func NewBackgroundCollection(
+	interval time.Duration,
+	collect func(ctx context.Context, ch chan<- prometheus.Metric),
+) *BackgroundCollection {
+	b := &BackgroundCollection{
+		interval: interval,
+		collect:  collect,
+		ready:    make(chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		desc: prometheus.NewDesc(
+			"libvirt_exporter_background_collection_age_seconds",
+			"Seconds since the served metrics were collected in the background",
+			[]string{},
+			nil,
+		),
+	}
+
+	go b.run()
+	return b
+}
+
+// run collects immediately and then every interval until Stop is called
+func (b *BackgroundCollection) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	first := true
+	for {
+		b.refresh()
+		if first {
+			close(b.ready)
+			first = false
+		}
+
+		select {
+		case <-ticker.C:
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// refresh runs one collection and replaces the kept samples
+func (b *BackgroundCollection) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), b.interval)
+	defer cancel()
+
+	start := time.Now()
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var metrics []prometheus.Metric
+	go func() {
+		defer close(done)
+		for metric := range ch {
+			metrics = append(metrics, metric)
+		}
+	}()
+	b.collect(ctx, ch)
+	close(ch)
+	<-done
+
+	slog.Debug("Background collection finished", "samples", len(metrics), "duration", time.Since(start))
+
+	b.mutex.Lock()
+	b.metrics = metrics
+	b.collectedAt = time.Now()
+	b.mutex.Unlock()
+}
+
+// Serve sends the samples of the last background collection, waiting for the
+// first one to finish
+func (b *BackgroundCollection) Serve(ch chan<- prometheus.Metric) {
+	select {
+	case <-b.ready:
+	case <-b.stop:
+		return
+	}
+
+	b.mutex.RLock()
+	metrics := b.metrics
+	collectedAt := b.collectedAt
+	b.mutex.RUnlock()
+
+	for _, metric := range metrics {
+		ch <- metric
+	}
+	ch <- prometheus.MustNewConstMetric(
+		b.desc,
+		prometheus.GaugeValue,
+		time.Since(collectedAt).Seconds(),
+	)
+}
+
+// Describe implements the prometheus.Collector interface
+func (b *BackgroundCollection) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.desc
+}
+
+// Stop stops collecting, waiting for a running collection to finish
+func (b *BackgroundCollection) Stop() {
+	close(b.stop)
+	<-b.done
+}
@@ -0,0 +1,339 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"libvirt.org/go/libvirt"
+	"libvirt.org/go/libvirtxml"
+)
+
+// Collection modes
+const (
+	// CollectionLegacy queries the statistics of each domain with individual calls
+	CollectionLegacy = "legacy"
+	// CollectionBatched fetches the statistics of all domains with a single
+	// GetAllDomainStats call per scrape
+	CollectionBatched = "batched"
+)
+
+// batchedStatsTypes are the statistics fetched by BatchedMetricsCollector
+const batchedStatsTypes = libvirt.DOMAIN_STATS_STATE |
+	libvirt.DOMAIN_STATS_CPU_TOTAL |
+	libvirt.DOMAIN_STATS_BALLOON |
+	libvirt.DOMAIN_STATS_VCPU |
+	libvirt.DOMAIN_STATS_INTERFACE |
+	libvirt.DOMAIN_STATS_BLOCK
+
+// BatchedMetricsCollector is a MetricsCollector reading the CPU, memory, disk
+// and network statistics of all domains from one GetAllDomainStats call made
+// by Prefetch at the start of each scrape. Domains missing from the batch and
+// all other statistics are collected like LibvirtMetricsCollector does.
+type BatchedMetricsCollector struct {
+	*LibvirtMetricsCollector
+	mutex sync.RWMutex
+	stats map[string]*libvirt.DomainStats // keyed by domain UUID
+}
+
+// NewBatchedMetricsCollector creates a new BatchedMetricsCollector on top of
+// a LibvirtMetricsCollector
+func NewBatchedMetricsCollector(mc *LibvirtMetricsCollector) *BatchedMetricsCollector {
+	return &BatchedMetricsCollector{
+		LibvirtMetricsCollector: mc,
+	}
+}
+
+// NewDomainMetricsCollector returns the MetricsCollector of the given
+// collection mode and, for the batched mode, the collector to prefetch
+func NewDomainMetricsCollector(
+	mode string,
+	mc *LibvirtMetricsCollector,
+) (MetricsCollector, *BatchedMetricsCollector, error) {
+	switch mode {
+	case "", CollectionLegacy:
+		return mc, nil, nil
+	case CollectionBatched:
+		batched := NewBatchedMetricsCollector(mc)
+		return batched, batched, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown collection mode %q", mode)
+	}
+}
+
+// Prefetch fetches the statistics of the given domains, replacing the
+// statistics of the previous scrape
+func (b *BatchedMetricsCollector) Prefetch(conn *libvirt.Connect, domains []*libvirt.Domain) error {
+	stats := make(map[string]*libvirt.DomainStats, len(domains))
+	defer func() {
+		b.mutex.Lock()
+		b.stats = stats
+		b.mutex.Unlock()
+	}()
+
+	if len(domains) == 0 {
+		return nil
+	}
+
+	records, err := conn.GetAllDomainStats(domains, batchedStatsTypes, 0)
+	if err != nil {
+		return err
+	}
+
+	for i := range records {
+		record := &records[i]
+		uuid, err := record.Domain.GetUUIDString()
+		if err == nil {
+			stats[uuid] = record
+		}
+		// Only the statistics are kept, the domain is looked up by the caller
+		record.Domain.Free()
+		record.Domain = nil
+	}
+
+	return nil
+}
+
+// lookup returns the prefetched statistics of a domain
+func (b *BatchedMetricsCollector) lookup(domain *libvirt.Domain) (*libvirt.DomainStats, bool) {
+	uuid, err := domain.GetUUIDString()
+	if err != nil {
+		return nil, false
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	stats, ok := b.stats[uuid]
+	return stats, ok
+}
+
+// CollectCPUStats collects CPU statistics from the prefetched batch
+func (b *BatchedMetricsCollector) CollectCPUStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) (*CPUStatsMetrics, error) {
+	stats, ok := b.lookup(domain)
+	if !ok || stats.Cpu == nil {
+		return b.LibvirtMetricsCollector.CollectCPUStats(ctx, conn, domain)
+	}
+
+	domainName, domainUUID, err := b.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &CPUStatsMetrics{
+		Name:       domainName,
+		UUID:       domainUUID,
+		VCPUsMax:   uint(len(stats.Vcpu)),
+		CPUTime:    stats.Cpu.Time,
+		UserTime:   stats.Cpu.User,
+		SystemTime: stats.Cpu.System,
+	}
+
+	// Offline vCPUs are reported without state
+	for i, vcpu := range stats.Vcpu {
+		if !vcpu.StateSet || vcpu.State == libvirt.VCPU_OFFLINE {
+			continue
+		}
+		metrics.VCPUsCurrent++
+		metrics.VCPUs = append(metrics.VCPUs, VCPUMetrics{
+			Number:  uint32(i),
+			State:   vcpuStateToString(vcpu.State),
+			CPUTime: vcpu.Time,
+		})
+	}
+	applyVcpuDelays(metrics, stats.Vcpu)
+	b.applySchedulerParams(domain, metrics)
+	applyVcpuPinning(domain, metrics)
+
+	return metrics, nil
+}
+
+// CollectMemoryStats collects memory statistics from the prefetched batch
+func (b *BatchedMetricsCollector) CollectMemoryStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) (*MemoryStatsMetrics, error) {
+	stats, ok := b.lookup(domain)
+	if !ok || stats.Balloon == nil {
+		return b.LibvirtMetricsCollector.CollectMemoryStats(ctx, conn, domain)
+	}
+
+	domainName, domainUUID, err := b.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	balloon := stats.Balloon
+	metrics := &MemoryStatsMetrics{
+		Name:        domainName,
+		UUID:        domainUUID,
+		BalloonSize: balloon.Current,
+		Unused:      balloon.Unused,
+		Available:   balloon.Available,
+		RSS:         balloon.Rss,
+		SwapIn:      balloon.SwapIn,
+		SwapOut:     balloon.SwapOut,
+		MajorFaults: balloon.MajorFault,
+		MinorFaults: balloon.MinorFault,
+	}
+
+	b.applyMemoryConfig(domain, metrics)
+
+	return metrics, nil
+}
+
+// CollectDiskStats collects disk statistics from the prefetched batch
+func (b *BatchedMetricsCollector) CollectDiskStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) ([]DiskMetrics, error) {
+	stats, ok := b.lookup(domain)
+	if !ok {
+		return b.LibvirtMetricsCollector.CollectDiskStats(ctx, conn, domain)
+	}
+	if !batchedRunning(stats) {
+		return []DiskMetrics{}, nil
+	}
+
+	domainName, domainUUID, err := b.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	disks, _ := b.deviceConfigs(domain)
+
+	var metrics []DiskMetrics
+	for _, block := range stats.Block {
+		if !block.NameSet {
+			continue
+		}
+		metrics = append(metrics, DiskMetrics{
+			Name:        domainName,
+			UUID:        domainUUID,
+			Device:      block.Name,
+			Path:        "/dev/" + block.Name,
+			ReadBytes:   block.RdBytes,
+			WriteBytes:  block.WrBytes,
+			ReadOps:     block.RdReqs,
+			WriteOps:    block.WrReqs,
+			ReadTimeNs:  block.RdTimes,
+			WriteTimeNs: block.WrTimes,
+			FlushOps:    block.FlReqs,
+			FlushTimeNs: block.FlTimes,
+			Capacity:    block.Capacity,
+			Allocation:  block.Allocation,
+			Physical:    block.Physical,
+		})
+	}
+
+	b.finishDiskMetrics(domain, metrics, disks)
+
+	return metrics, nil
+}
+
+// CollectNetworkStats collects network statistics from the prefetched batch.
+// vhost-user and SR-IOV interfaces are not reported by libvirt and are read
+// from their backends by the per-domain path instead.
+func (b *BatchedMetricsCollector) CollectNetworkStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) ([]NetworkMetrics, error) {
+	stats, ok := b.lookup(domain)
+	if !ok {
+		return b.LibvirtMetricsCollector.CollectNetworkStats(ctx, conn, domain)
+	}
+	if !batchedRunning(stats) {
+		return []NetworkMetrics{}, nil
+	}
+
+	_, interfaces := b.deviceConfigs(domain)
+	for _, iface := range interfaces {
+		if (vhostUserPort(iface) != "" && b.vhostUser != nil ||
+			hostdevAddress(iface) != "" && b.sriov != nil) && b.isLocal(conn) {
+			return b.LibvirtMetricsCollector.CollectNetworkStats(ctx, conn, domain)
+		}
+	}
+
+	domainName, domainUUID, err := b.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []NetworkMetrics
+	for _, net := range stats.Net {
+		if !net.NameSet {
+			continue
+		}
+		metrics = append(metrics, NetworkMetrics{
+			Name:      domainName,
+			UUID:      domainUUID,
+			Interface: net.Name,
+			RxBytes:   net.RxBytes,
+			TxBytes:   net.TxBytes,
+			RxPackets: net.RxPkts,
+			TxPackets: net.TxPkts,
+			RxErrors:  net.RxErrs,
+			TxErrors:  net.TxErrs,
+			RxDrops:   net.RxDrop,
+			TxDrops:   net.TxDrop,
+		})
+	}
+
+	b.finishNetworkMetrics(metrics, interfaces)
+
+	return metrics, nil
+}
+
+// deviceConfigs returns the disk and interface definitions of a domain keyed
+// by target device, or nil maps if the domain XML is unavailable
+func (b *BatchedMetricsCollector) deviceConfigs(
+	domain *libvirt.Domain,
+) (map[string]*libvirtxml.DomainDisk, map[string]*libvirtxml.DomainInterface) {
+	domainXML, err := b.getDomainXML(domain)
+	if err != nil {
+		slog.Warn("Failed to get domain XML for device settings", "err", err)
+		return nil, nil
+	}
+	if domainXML.Devices == nil {
+		return nil, nil
+	}
+
+	disks := make(map[string]*libvirtxml.DomainDisk)
+	for i := range domainXML.Devices.Disks {
+		disk := &domainXML.Devices.Disks[i]
+		if disk.Target != nil && disk.Target.Dev != "" {
+			disks[disk.Target.Dev] = disk
+		}
+	}
+
+	interfaces := make(map[string]*libvirtxml.DomainInterface)
+	for i := range domainXML.Devices.Interfaces {
+		iface := &domainXML.Devices.Interfaces[i]
+		name := vhostUserPort(iface)
+		if name == "" {
+			name = hostdevAddress(iface)
+		}
+		if iface.Target != nil && iface.Target.Dev != "" {
+			name = iface.Target.Dev
+		}
+		if name != "" {
+			interfaces[name] = iface
+		}
+	}
+
+	return disks, interfaces
+}
+
+// batchedRunning reports whether the batch saw the domain running; device
+// statistics are only collected for running domains
+func batchedRunning(stats *libvirt.DomainStats) bool {
+	return stats.State != nil && stats.State.StateSet && stats.State.State == libvirt.DOMAIN_RUNNING
+}
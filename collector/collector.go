@@ -1,9 +1,15 @@
 package collector
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"libvirt.org/go/libvirt"
@@ -12,7 +18,11 @@ import (
 // Collector defines the interface for collecting metrics
 type Collector interface {
 	Describe(ch chan<- *prometheus.Desc)
+	// Collect sends the metrics of a domain. ctx is done once the collector's
+	// timeout or the scrape deadline is reached; collectors stop issuing
+	// further libvirt calls then.
 	Collect(
+		ctx context.Context,
 		ch chan<- prometheus.Metric,
 		conn *libvirt.Connect,
 		domain *libvirt.Domain,
@@ -21,84 +31,959 @@ type Collector interface {
 	Reset()
 }
 
+// HostCollector is implemented by sub-collectors of host-wide metrics. Their
+// CollectHost runs once per scrape outside the per-domain workers, bounded by
+// the scrape deadline and the collector's own timeout only, so host metrics
+// are collected even when no domain is selected. Collect is not called for
+// host collectors. includes reports whether a domain is part of the scrape,
+// for host collectors sending per-domain metrics.
+type HostCollector interface {
+	Collector
+	CollectHost(
+		ctx context.Context,
+		ch chan<- prometheus.Metric,
+		conn *libvirt.Connect,
+		includes func(name, uuid string) bool,
+	)
+}
+
+// domainRetainer is implemented by sub-collectors keeping the state of
+// domains across scrapes; retain is called on every scrape with the UUIDs of
+// all existing domains
+type domainRetainer interface {
+	retain(uuids map[string]bool)
+}
+
+// pruner is implemented by sub-collectors caching the state of the domains
+// they collect; prune drops the domains not collected during the scrape and
+// is only called after complete scrapes of all domains
+type pruner interface {
+	prune()
+}
+
+// expired records in gone since when a domain is missing from libvirt and
+// reports whether it has been missing for longer than retention
+func expired(gone *time.Time, exists bool, now time.Time, retention time.Duration) bool {
+	if exists {
+		*gone = time.Time{}
+		return false
+	}
+	if gone.IsZero() {
+		*gone = now
+	}
+	return now.Sub(*gone) > retention
+}
+
+// Options holds the settings used to build a LibvirtCollector
+type Options struct {
+	// FallbackURIs are tried in order when the primary URI is unavailable
+	FallbackURIs []string
+	// AuthUsername and AuthPassword answer the credential requests of remote
+	// connections, e.g. for SASL
+	AuthUsername string
+	AuthPassword string
+	// TLSPKIPath is the client certificate directory of qemu+tls URIs
+	TLSPKIPath string
+	// SSHKeyFile and SSHKnownHosts are the private key and known hosts files
+	// of qemu+ssh URIs
+	SSHKeyFile    string
+	SSHKnownHosts string
+	// ReconnectInterval is the period of the connection health check and the
+	// initial delay between reconnection attempts, doubled after each failure
+	ReconnectInterval time.Duration
+	// HostURIs are further libvirt hosts collected along the primary URI,
+	// each over its own connection; all metrics then carry a host label
+	HostURIs []string
+	// DiscoveryName is a DNS name resolved into further libvirt hosts, which
+	// are added and removed as the record changes (empty disables discovery)
+	DiscoveryName string
+	// DiscoveryType is the record type of DiscoveryName ("srv" or "a")
+	DiscoveryType string
+	// DiscoveryURITemplate builds the URI of each discovered host, replacing
+	// "{host}" and, for SRV records, "{port}"
+	DiscoveryURITemplate string
+	// DiscoveryInterval is how often DiscoveryName is resolved again
+	DiscoveryInterval time.Duration
+	// peer is set for the collectors of further hosts, which start
+	// disconnected when their host is unreachable instead of failing
+	peer bool
+	// ProbeInterval is the period of the background libvirt daemon health
+	// probe (0 disables the probe)
+	ProbeInterval time.Duration
+	// AdminURI is the libvirt admin interface URI used for daemon metrics
+	// (empty disables the admin collector)
+	AdminURI string
+	// LeaderLock is a file on shared storage locked by the active replica;
+	// standby replicas serve their last scrape (empty disables election)
+	LeaderLock string
+	// LeaderRetryInterval is how often standby replicas try to take the lock
+	LeaderRetryInterval time.Duration
+	// Version is the exporter version reported in libvirt_host_info
+	Version string
+	// Commit is the commit the exporter was built from
+	Commit string
+	// LabelPolicy selects how domain names are sanitized into label values
+	LabelPolicy string
+	// MaxConcurrent is the fixed number of domains collected in parallel
+	MaxConcurrent int
+	// Autoscale derives the worker count from the number of domains instead
+	Autoscale bool
+	// DomainsPerWorker is the number of domains handled by each autoscaled worker
+	DomainsPerWorker int
+	// MaxWorkers caps the autoscaled worker count
+	MaxWorkers int
+	// EnableJobs registers the domain job collector
+	EnableJobs bool
+	// EnableMigrations registers the migration history collector, which
+	// listens for job-completed events
+	EnableMigrations bool
+	// EnableSnapshots registers the snapshot collector
+	EnableSnapshots bool
+	// SnapshotInterval is the minimum time between snapshot listings of a domain
+	SnapshotInterval time.Duration
+	// DomainTimeout bounds the collection of a single domain; a domain that
+	// exceeds it is skipped for the scrape (0 disables the timeout)
+	DomainTimeout time.Duration
+	// MaxDomains caps the number of domains collected per scrape (0 = unlimited)
+	MaxDomains int
+	// MemoryLimit is the heap size in bytes above which optional collectors
+	// are skipped (0 = unlimited)
+	MemoryLimit uint64
+	// CounterWrapMode selects how 32-bit counter wraps are handled
+	CounterWrapMode string
+	// Timestamps attaches the collection time to every emitted sample
+	Timestamps bool
+	// MetadataLabels maps the labels attached to all per-domain metrics to
+	// paths within the domain metadata element of MetadataNamespace, e.g.
+	// "owner/project@uuid" (nil disables metadata labels)
+	MetadataLabels    map[string]string
+	MetadataNamespace string
+	// EnableProcess registers the QEMU process collector
+	EnableProcess bool
+	// PIDDir is the directory holding the QEMU PID files written by libvirtd
+	PIDDir string
+	// VHostUserBackend selects where vhost-user interface counters are read
+	// from ("none" or "ovs")
+	VHostUserBackend string
+	// OVSVsctl is the ovs-vsctl command used by the "ovs" vhost-user backend
+	OVSVsctl string
+	// EnableSRIOV reads the counters of SR-IOV interfaces passed through to
+	// domains from the host
+	EnableSRIOV bool
+	// IPCommand is the iproute2 ip command used to read SR-IOV counters
+	IPCommand string
+	// CounterRetention keeps replaying the last counters of stopped domains
+	// for this long (0 disables retention)
+	CounterRetention time.Duration
+	// EventRetention keeps the lifecycle event and migration counts of
+	// domains gone from libvirt for this long
+	EventRetention time.Duration
+	// EnabledMetrics lists the metric groups to collect (nil enables all groups)
+	EnabledMetrics []string
+	// CollectionMode selects how domain statistics are fetched ("legacy" or
+	// "batched")
+	CollectionMode string
+	// DeviceCacheTTL is how long the block devices discovered from a domain
+	// definition are reused while it is unchanged (0 disables the cache)
+	DeviceCacheTTL time.Duration
+	// HostInterfaces selects which host interfaces have their counters
+	// collected ("libvirt", "proc" or "none")
+	HostInterfaces string
+	// EnableDirtyRate registers the memory dirty rate collector
+	EnableDirtyRate bool
+	// DirtyRateInterval is how often a dirty rate calculation is started for
+	// each running domain (0 never starts calculations)
+	DirtyRateInterval time.Duration
+	// DirtyRatePeriod is how long each dirty rate calculation measures
+	DirtyRatePeriod time.Duration
+	// EnablePerf registers the perf event collector
+	EnablePerf bool
+	// PerfEvents lists the perf events enabled on running domains (nil only
+	// reports events enabled by others)
+	PerfEvents []string
+	// EnableResctrl registers the Intel RDT cache and memory bandwidth
+	// monitoring collector
+	EnableResctrl bool
+	// EnableNodeDevices registers the host PCI and USB device inventory
+	// collector
+	EnableNodeDevices bool
+	// EnableLaunchSecurity registers the confidential computing (SEV, TDX)
+	// collector
+	EnableLaunchSecurity bool
+	// EnableFilesystems registers the guest filesystem collector
+	EnableFilesystems bool
+	// EnableGuestHostname reads the hostname of running domains from their
+	// guest agent
+	EnableGuestHostname bool
+	// EnableBlockJobs reads the block job running on each disk of running
+	// domains, one libvirt call per disk
+	EnableBlockJobs bool
+	// EnableDiskThrottle reads the I/O throttle limits of each disk of
+	// running domains, one libvirt call per disk
+	EnableDiskThrottle bool
+	// EnableEvents registers the domain lifecycle event collector, which
+	// listens for events on its own connection
+	EnableEvents bool
+	// EventsNotifyURL is where lifecycle events are forwarded, an http(s) URL
+	// or unix:///path/to/socket (empty disables forwarding)
+	EventsNotifyURL string
+	// EventsNotifyEvents lists the lifecycle events forwarded (nil forwards all)
+	EventsNotifyEvents []string
+	// EventsNotifyTimeout bounds each delivery attempt
+	EventsNotifyTimeout time.Duration
+	// EventsNotifyRetries is how often a failed delivery is retried
+	EventsNotifyRetries int
+	// TracingEndpoint is the OTLP/HTTP endpoint spans of scrapes are exported
+	// to (empty disables tracing)
+	TracingEndpoint string
+	// TracingServiceName is the service.name resource attribute of the spans
+	TracingServiceName string
+	// TracingHeaders are extra HTTP headers sent with each export request
+	TracingHeaders map[string]string
+	// TracingTimeout bounds each export request
+	TracingTimeout time.Duration
+	// TracingSampleRatio is the fraction of scrapes traced
+	TracingSampleRatio float64
+	// BackgroundInterval collects all metrics in the background every
+	// interval and serves full scrapes from the last collection (0 collects
+	// on every scrape)
+	BackgroundInterval time.Duration
+	// Collectors enables or disables sub-collectors by name. Built-in
+	// collectors are enabled unless set to false, custom collectors added
+	// with Register only when set to true
+	Collectors map[string]bool
+	// CollectorTimeouts bounds the run of the named sub-collectors for a
+	// single domain; a run exceeding it is abandoned
+	CollectorTimeouts map[string]time.Duration
+}
+
+// metricGroups maps the metric groups selectable in the configuration to the
+// sub-collectors exporting them. Collectors outside any group are controlled
+// by their own options. vm_status and vm_uptime share the domain collector,
+// which only sends the metrics of the groups enabled.
+var metricGroups = map[string][]string{
+	"vm_status":  {"domain"},
+	"vm_uptime":  {"domain"},
+	"vm_cpu":     {"cpu"},
+	"vm_memory":  {"memory"},
+	"vm_disk":    {"disk"},
+	"vm_network": {"network"},
+	"vm_device":  {"device"},
+	"host":       {"connection", "node_memory", "ksm"},
+}
+
+// laterMetricGroups are the metric groups added after metrics.enabled was
+// first shipped, which the lists of older configurations lack
+var laterMetricGroups = []string{"vm_device", "host"}
+
+// enabledCollectors resolves metric groups into the set of grouped
+// sub-collectors to register, nil if all groups are enabled
+func enabledCollectors(groups []string) (map[string]bool, error) {
+	if groups == nil {
+		return nil, nil
+	}
+
+	// Lists written before these groups existed silently disable them
+	var dropped []string
+	for _, group := range laterMetricGroups {
+		if !groupEnabled(groups, group) {
+			dropped = append(dropped, group)
+		}
+	}
+	if len(dropped) > 0 {
+		slog.Warn("Metric groups missing from metrics.enabled are not collected, add them to keep their metrics",
+			"groups", dropped)
+	}
+
+	enabled := make(map[string]bool)
+	for _, group := range groups {
+		collectors, ok := metricGroups[group]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric group %q", group)
+		}
+		for _, name := range collectors {
+			enabled[name] = true
+		}
+	}
+	return enabled, nil
+}
+
+// groupEnabled reports whether a metric group is enabled, all groups being
+// enabled when none are configured
+func groupEnabled(groups []string, group string) bool {
+	if groups == nil {
+		return true
+	}
+	for _, enabled := range groups {
+		if enabled == group {
+			return true
+		}
+	}
+	return false
+}
+
+// isGrouped reports whether a sub-collector belongs to a metric group
+func isGrouped(name string) bool {
+	for _, collectors := range metricGroups {
+		for _, collector := range collectors {
+			if collector == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// optionalCollectors are skipped while the exporter is over its memory limit
+var optionalCollectors = map[string]bool{
+	"device":     true,
+	"job":        true,
+	"snapshot":   true,
+	"process":    true,
+	"dirty_rate": true,
+	"perf":       true,
+	"filesystem": true,
+}
+
 // LibvirtCollector implements the prometheus.Collector interface
 type LibvirtCollector struct {
-	uri          string
-	conn         *libvirt.Connect
-	mutex        sync.RWMutex
-	collectors   []Collector
-	reconnectErr chan error
+	uris              []string
+	connections       *ConnectionManager
+	mutex             sync.RWMutex
+	collectors        []Collector
+	collectorNames    []string
 	exporterCollector *ExporterCollector
+	sanitizer         *LabelSanitizer
+	counters          *CounterTracker
+	retention         *CounterRetention
+	inventory         *DomainInventory
+	metadata          *DomainMetadata // nil when no metadata labels are configured
+	metricsCollector  *LibvirtMetricsCollector
+	batch             *BatchedMetricsCollector // nil in legacy collection mode
+	probe             *ProbeCollector
+	migrations        *MigrationCollector
+	lifecycle         *LifecycleCollector
+	admin             *AdminCollector
+	elector           *LeaderElector
+	tracer            *Tracer               // nil when tracing is disabled
+	background        *BackgroundCollection // nil when collecting on every scrape
+	timeouts          map[Collector]time.Duration
+	status            statusTracker
+	hostsMutex        sync.RWMutex
+	host              string              // host label value, empty for a single host
+	hosts             []*LibvirtCollector // peers collecting further hosts
+	discovery         *hostDiscoveryLoop  // nil without DNS discovery
+	discovered        bool                // peer added by DNS discovery
+	staleMutex        sync.Mutex
+	stale             []prometheus.Metric // last full scrape, served while standby
+	enabled           map[string]bool     // grouped sub-collectors to register, nil for all
+	opts              Options
 }
 
 // NewLibvirtCollector creates a new LibvirtCollector
-func NewLibvirtCollector(uri string) (*LibvirtCollector, error) {
-	log.Printf("Connecting to libvirt at '%s'", uri)
-	conn, err := libvirt.NewConnect(uri)
+func NewLibvirtCollector(uri string, opts Options) (*LibvirtCollector, error) {
+	sanitizer, err := NewLabelSanitizer(opts.LabelPolicy)
 	if err != nil {
 		return nil, err
 	}
 
-	alive, err := conn.IsAlive()
-	if err != nil || !alive {
-		return nil, fmt.Errorf("connection is not alive")
+	exporterCollector := NewExporterCollector()
+	counters, err := NewCounterTracker(opts.CounterWrapMode, exporterCollector.RecordCounterWrap)
+	if err != nil {
+		return nil, err
+	}
+
+	vhostUser, err := NewVHostUserBackend(opts.VHostUserBackend, opts.OVSVsctl)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := enabledCollectors(opts.EnabledMetrics)
+	if err != nil {
+		return nil, err
+	}
+	for name := range opts.Collectors {
+		if !knownCollector(name) {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+	}
+	for name := range opts.CollectorTimeouts {
+		if !knownCollector(name) {
+			return nil, fmt.Errorf("unknown collector %q in collector timeouts", name)
+		}
 	}
 
-	log.Println("Successfully connected to libvirt")
+	// Events are only delivered to connections opened after the event loop
+	// implementation has been registered
+	if opts.EnableMigrations || opts.EnableEvents {
+		if err := startEventLoop(); err != nil {
+			return nil, fmt.Errorf("failed to start libvirt event loop: %w", err)
+		}
+	}
+
+	// The configured URIs are reported, the ones with transport parameters used
+	configured := append([]string{uri}, opts.FallbackURIs...)
+	uris := remoteURIs(configured, opts)
+	auth := newConnectAuth(opts.AuthUsername, opts.AuthPassword)
+	connections, err := NewConnectionManager(uris, auth, opts.ReconnectInterval, !opts.peer, func(active int) {
+		exporterCollector.SetActiveURI(configured, active)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	collector := &LibvirtCollector{
-		uri:          uri,
-		conn:         conn,
-		reconnectErr: make(chan error),
+		uris:              uris,
+		connections:       connections,
+		exporterCollector: exporterCollector,
+		sanitizer:         sanitizer,
+		counters:          counters,
+		inventory:         NewDomainInventory(),
+		enabled:           enabled,
+		opts:              opts,
+	}
+	if opts.CounterRetention > 0 {
+		collector.retention = NewCounterRetention(opts.CounterRetention)
+	}
+	if len(opts.MetadataLabels) > 0 {
+		collector.metadata, err = NewDomainMetadata(opts.MetadataNamespace, opts.MetadataLabels)
+		if err != nil {
+			connections.Close()
+			return nil, err
+		}
+	}
+
+	// All collectors share one metrics collector so they agree on domain labels
+	var devices *BlockDeviceCache
+	if opts.DeviceCacheTTL > 0 {
+		devices = NewBlockDeviceCache(
+			opts.DeviceCacheTTL,
+			exporterCollector.RecordCacheHit,
+			exporterCollector.RecordCacheMiss,
+		)
+	}
+	var sriov *SRIOVStats
+	if opts.EnableSRIOV {
+		sriov = NewSRIOVStats(opts.IPCommand)
+	}
+	libvirtMetrics, err := NewLibvirtMetricsCollector(
+		sanitizer,
+		counters,
+		opts.PIDDir,
+		vhostUser,
+		sriov,
+		devices,
+		opts.HostInterfaces,
+		opts.EnableGuestHostname,
+		opts.EnableBlockJobs,
+		opts.EnableDiskThrottle,
+	)
+	if err != nil {
+		connections.Close()
+		return nil, err
+	}
+	collector.metricsCollector = libvirtMetrics
+	metricsCollector, batch, err := NewDomainMetricsCollector(opts.CollectionMode, libvirtMetrics)
+	if err != nil {
+		connections.Close()
+		return nil, err
 	}
+	collector.batch = batch
 
 	// Initialize individual collectors
-	collector.exporterCollector = NewExporterCollector()
-	collector.collectors = append(collector.collectors, collector.exporterCollector)
-	collector.collectors = append(collector.collectors, NewDomainInfoCollector())
-	collector.collectors = append(collector.collectors, NewCPUCollector())
-	collector.collectors = append(collector.collectors, NewMemoryCollector())
-	collector.collectors = append(collector.collectors, NewDiskCollector())
-	collector.collectors = append(collector.collectors, NewNetworkCollector())
-	collector.collectors = append(collector.collectors, NewDeviceCollector())
-	collector.collectors = append(collector.collectors, NewConnectionCollector())
+	collector.addCollector("exporter", collector.exporterCollector)
+	collector.addCollector("domain", NewDomainInfoCollector(
+		metricsCollector,
+		collector.inventory,
+		groupEnabled(opts.EnabledMetrics, "vm_status"),
+		groupEnabled(opts.EnabledMetrics, "vm_uptime"),
+	))
+	collector.addCollector("cpu", NewCPUCollector(metricsCollector))
+	collector.addCollector("memory", NewMemoryCollector(metricsCollector))
+	collector.addCollector("disk", NewDiskCollector(metricsCollector))
+	collector.addCollector("network", NewNetworkCollector(metricsCollector))
+	collector.addCollector("device", NewDeviceCollector(metricsCollector))
+	collector.addCollector("connection", NewConnectionCollector(metricsCollector, opts.Version))
+	collector.addCollector("node_memory", NewNodeMemoryCollector(metricsCollector))
+	collector.addCollector("ksm", NewKSMCollector(metricsCollector))
+	if opts.EnableJobs {
+		collector.addCollector("job", NewJobCollector(metricsCollector))
+	}
+	// Collectors running in the background are not even created when disabled
+	if opts.EnableMigrations && !collector.disabled("migration") {
+		collector.migrations = NewMigrationCollector(uris, auth, sanitizer, opts.EventRetention)
+		collector.addCollector("migration", collector.migrations)
+	}
+	if opts.EnableEvents && !collector.disabled("lifecycle") {
+		var notifier *EventNotifier
+		if opts.EventsNotifyURL != "" {
+			notifier, err = NewEventNotifier(
+				opts.EventsNotifyURL,
+				opts.EventsNotifyEvents,
+				opts.EventsNotifyTimeout,
+				opts.EventsNotifyRetries,
+			)
+			if err != nil {
+				connections.Close()
+				return nil, err
+			}
+		}
+		collector.lifecycle = NewLifecycleCollector(uris, auth, sanitizer, notifier, opts.EventRetention)
+		collector.addCollector("lifecycle", collector.lifecycle)
+	}
+	if opts.EnableSnapshots {
+		collector.addCollector("snapshot", NewSnapshotCollector(metricsCollector, opts.SnapshotInterval))
+	}
+	if opts.EnableProcess {
+		collector.addCollector("process", NewProcessCollector(metricsCollector))
+	}
+	if opts.EnableDirtyRate {
+		collector.addCollector("dirty_rate", NewDirtyRateCollector(
+			metricsCollector,
+			opts.DirtyRateInterval,
+			opts.DirtyRatePeriod,
+		))
+	}
+	if opts.EnablePerf {
+		perf, err := NewPerfCollector(metricsCollector, opts.PerfEvents)
+		if err != nil {
+			connections.Close()
+			return nil, err
+		}
+		collector.addCollector("perf", perf)
+	}
+	if opts.EnableResctrl {
+		collector.addCollector("resctrl", NewResctrlCollector(metricsCollector))
+	}
+	if opts.EnableNodeDevices {
+		collector.addCollector("node_device", NewNodeDeviceCollector(metricsCollector))
+	}
+	if opts.EnableLaunchSecurity {
+		collector.addCollector("launch_security", NewLaunchSecurityCollector(metricsCollector))
+		collector.addCollector("launch_security", newLaunchSecurityHostCollector(metricsCollector))
+	}
+	if opts.EnableFilesystems {
+		collector.addCollector("filesystem", NewFilesystemCollector(metricsCollector))
+	}
+	if opts.ProbeInterval > 0 && !collector.disabled("probe") {
+		collector.probe = NewProbeCollector(uris, auth, opts.ProbeInterval)
+		collector.addCollector("probe", collector.probe)
+	}
+	if opts.LeaderLock != "" {
+		collector.elector = NewLeaderElector(opts.LeaderLock, opts.LeaderRetryInterval)
+	}
+	if opts.AdminURI != "" && !collector.disabled("admin") {
+		collector.admin = NewAdminCollector(opts.AdminURI)
+		collector.addCollector("admin", collector.admin)
+	}
+	for _, name := range registeredCollectors() {
+		if !opts.Collectors[name] {
+			continue
+		}
+		custom, err := registeredFactory(name)(metricsCollector, opts)
+		if err != nil {
+			connections.Close()
+			return nil, fmt.Errorf("failed to create collector %q: %w", name, err)
+		}
+		if custom != nil {
+			collector.addCollector(name, custom)
+		}
+	}
+
+	collector.timeouts = make(map[Collector]time.Duration, len(opts.CollectorTimeouts))
+	for i, name := range collector.collectorNames {
+		if timeout := opts.CollectorTimeouts[name]; timeout > 0 {
+			collector.timeouts[collector.collectors[i]] = timeout
+		}
+	}
+	if opts.TracingEndpoint != "" {
+		collector.tracer, err = NewTracer(
+			opts.TracingEndpoint,
+			opts.TracingServiceName,
+			opts.TracingHeaders,
+			opts.TracingTimeout,
+			opts.TracingSampleRatio,
+		)
+		if err != nil {
+			connections.Close()
+			return nil, err
+		}
+	}
+	if opts.BackgroundInterval > 0 {
+		collector.background = NewBackgroundCollection(
+			opts.BackgroundInterval,
+			func(ctx context.Context, ch chan<- prometheus.Metric) {
+				collector.collectNow(ch, scrapeScope{ctx: ctx, collectors: collector.collectors})
+			},
+		)
+	}
+	if len(opts.HostURIs) > 0 {
+		if err := collector.addHosts(uri, opts.HostURIs); err != nil {
+			collector.Close()
+			return nil, err
+		}
+	}
+	if opts.DiscoveryName != "" {
+		if opts.LeaderLock != "" {
+			collector.Close()
+			return nil, fmt.Errorf("leader election cannot be combined with host discovery")
+		}
+		discovery, err := NewHostDiscovery(opts.DiscoveryName, opts.DiscoveryType, opts.DiscoveryURITemplate)
+		if err != nil {
+			collector.Close()
+			return nil, err
+		}
+		collector.host = hostLabel(uri)
+		collector.startDiscovery(discovery, opts.DiscoveryInterval)
+	}
 
 	return collector, nil
 }
 
+// connectAny connects to the first reachable URI of an ordered list and
+// returns the connection and the index of the URI used. auth answers the
+// credential requests of remote connections, nil if none are configured.
+func connectAny(uris []string, auth *libvirt.ConnectAuth) (*libvirt.Connect, int, error) {
+	var lastErr error
+	for i, uri := range uris {
+		slog.Info("Connecting to libvirt", "uri", uri)
+		var conn *libvirt.Connect
+		var err error
+		if auth != nil {
+			conn, err = libvirt.NewConnectWithAuth(uri, auth, 0)
+		} else {
+			conn, err = libvirt.NewConnect(uri)
+		}
+		if err != nil {
+			slog.Warn("Failed to connect to libvirt", "uri", uri, "err", err)
+			lastErr = err
+			continue
+		}
+
+		alive, err := conn.IsAlive()
+		if err != nil || !alive {
+			slog.Warn("Connection to libvirt is not alive", "uri", uri)
+			conn.Close()
+			lastErr = fmt.Errorf("connection is not alive")
+			continue
+		}
+
+		slog.Info("Successfully connected to libvirt", "uri", uri)
+		return conn, i, nil
+	}
+	return nil, 0, lastErr
+}
+
+// addCollector registers a sub-collector under a name usable for selection,
+// unless it or its metric group is disabled
+func (c *LibvirtCollector) addCollector(name string, collector Collector) {
+	if c.disabled(name) {
+		slog.Info("Collector disabled by configuration", "collector", name)
+		return
+	}
+	if c.enabled != nil && isGrouped(name) && !c.enabled[name] {
+		slog.Info("Collector disabled by metrics configuration", "collector", name)
+		return
+	}
+	c.collectors = append(c.collectors, collector)
+	c.collectorNames = append(c.collectorNames, name)
+}
+
+// disabled reports whether a sub-collector is disabled in Options.Collectors
+func (c *LibvirtCollector) disabled(name string) bool {
+	enabled, ok := c.opts.Collectors[name]
+	return ok && !enabled
+}
+
+// Select returns a prometheus.Collector that only runs the named sub-collectors
+// for the given domains. Domains are matched by name or UUID; empty lists select
+// all sub-collectors or all domains. Once ctx is done, the scrape stops
+// collecting further domains and returns what it has collected so far.
+func (c *LibvirtCollector) Select(ctx context.Context, names, domains []string) (prometheus.Collector, error) {
+	if c.multiHost() {
+		return c.selectHosts(ctx, names, domains)
+	}
+
+	scope, err := c.selectScope(ctx, names, domains, false)
+	if err != nil {
+		return nil, err
+	}
+	return &collectorSubset{parent: c, scope: scope}, nil
+}
+
+// selectScope resolves the selected sub-collectors and domains into a scope.
+// Unknown collector names are an error unless ignoreUnknown is set.
+func (c *LibvirtCollector) selectScope(
+	ctx context.Context,
+	names, domains []string,
+	ignoreUnknown bool,
+) (scrapeScope, error) {
+	scope := scrapeScope{ctx: ctx, collectors: c.collectors}
+
+	if len(names) > 0 {
+		scope.collectors = nil
+		seen := make(map[string]bool)
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			// A name may cover a host and a domain collector
+			found := false
+			for i, collectorName := range c.collectorNames {
+				if collectorName == name {
+					scope.collectors = append(scope.collectors, c.collectors[i])
+					found = true
+				}
+			}
+			if !found && !ignoreUnknown {
+				return scrapeScope{}, fmt.Errorf("unknown collector %q", name)
+			}
+		}
+	}
+
+	if len(domains) > 0 {
+		scope.domains = make(map[string]bool, len(domains))
+		for _, domain := range domains {
+			scope.domains[domain] = true
+		}
+	}
+
+	return scope, nil
+}
+
 // Describe implements the prometheus.Collector interface
 func (c *LibvirtCollector) Describe(ch chan<- *prometheus.Desc) {
-	for _, collector := range c.collectors {
-		collector.Describe(ch)
+	// Discovered hosts come and go, so the collector is registered unchecked
+	if c.discovery != nil {
+		return
 	}
+	if c.multiHost() {
+		c.fullHostScrape().Describe(ch)
+		return
+	}
+	c.describe(ch, c.collectors)
 }
 
 // Collect implements the prometheus.Collector interface
 func (c *LibvirtCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.multiHost() {
+		c.fullHostScrape().Collect(ch)
+		return
+	}
+	c.collectWith(ch, scrapeScope{collectors: c.collectors})
+}
+
+// scrapeScope restricts a scrape to some sub-collectors and domains
+type scrapeScope struct {
+	// ctx bounds the scrape, nil for no deadline
+	ctx        context.Context
+	collectors []Collector
+	// domains holds the selected domain names and UUIDs, nil selects all domains
+	domains map[string]bool
+}
+
+// context returns the context bounding the scrape
+func (s scrapeScope) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// filtered reports whether the scope excludes some domains
+func (s scrapeScope) filtered() bool {
+	return s.domains != nil
+}
+
+// includes reports whether a domain is part of the scope
+func (s scrapeScope) includes(name, uuid string) bool {
+	return s.domains == nil || s.domains[name] || s.domains[uuid]
+}
+
+// collectorSubset is a prometheus.Collector running a subset of the scrape
+type collectorSubset struct {
+	parent *LibvirtCollector
+	scope  scrapeScope
+}
+
+// Describe implements the prometheus.Collector interface
+func (s *collectorSubset) Describe(ch chan<- *prometheus.Desc) {
+	s.parent.describe(ch, s.scope.collectors)
+}
+
+// Collect implements the prometheus.Collector interface
+func (s *collectorSubset) Collect(ch chan<- prometheus.Metric) {
+	s.parent.collectWith(ch, s.scope)
+}
+
+// describe sends the descriptors of the given sub-collectors
+func (c *LibvirtCollector) describe(ch chan<- *prometheus.Desc, collectors []Collector) {
+	// Metadata labels are not part of the descriptors, so the collector is
+	// registered unchecked
+	if c.metadata != nil {
+		return
+	}
+	for _, collector := range collectors {
+		collector.Describe(ch)
+	}
+	if c.elector != nil {
+		c.elector.Describe(ch)
+	}
+	c.connections.Describe(ch)
+	if c.exporterCollector != nil {
+		ch <- c.exporterCollector.scrapePartial
+		ch <- c.exporterCollector.domainDuration
+	}
+	if c.background != nil {
+		c.background.Describe(ch)
+	}
+}
+
+// collectWith runs a scrape, or serves the last background collection
+func (c *LibvirtCollector) collectWith(ch chan<- prometheus.Metric, scope scrapeScope) {
+	// Full scrapes are answered from the last background collection
+	if c.background != nil && !scope.filtered() && len(scope.collectors) == len(c.collectors) {
+		c.background.Serve(ch)
+		return
+	}
+	c.collectNow(ch, scope)
+}
+
+// collectNow runs a scrape. Standby replicas replay the last full scrape
+// they made instead of querying libvirt; a standby that has none yet, e.g.
+// right after it started, scrapes libvirt once so it never serves nothing.
+func (c *LibvirtCollector) collectNow(ch chan<- prometheus.Metric, scope scrapeScope) {
+	if c.elector == nil {
+		c.collectTimestamped(ch, scope)
+		return
+	}
+
+	ch <- c.elector.metric()
+	full := !scope.filtered() && len(scope.collectors) == len(c.collectors)
+	if !c.elector.IsLeader() {
+		if !full {
+			return
+		}
+		c.staleMutex.Lock()
+		stale := c.stale
+		c.staleMutex.Unlock()
+		if stale != nil {
+			for _, metric := range stale {
+				ch <- metric
+			}
+			return
+		}
+	} else if !full {
+		c.collectTimestamped(ch, scope)
+		return
+	}
+
+	// Keep the output of full scrapes to serve while standby
+	tee := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var metrics []prometheus.Metric
+	go func() {
+		defer close(done)
+		for metric := range tee {
+			metrics = append(metrics, metric)
+			ch <- metric
+		}
+	}()
+	c.collectTimestamped(tee, scope)
+	close(tee)
+	<-done
+
+	c.staleMutex.Lock()
+	c.stale = metrics
+	c.staleMutex.Unlock()
+}
+
+// collectTimestamped runs a scrape, attaching timestamps if enabled
+func (c *LibvirtCollector) collectTimestamped(ch chan<- prometheus.Metric, scope scrapeScope) {
+	if !c.opts.Timestamps {
+		c.collect(ch, scope)
+		return
+	}
+
+	// Attach the collection time to every sample
+	collectedAt := time.Now()
+	metrics := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for metric := range metrics {
+			ch <- prometheus.NewMetricWithTimestamp(collectedAt, metric)
+		}
+	}()
+
+	c.collect(metrics, scope)
+	close(metrics)
+	<-done
+}
+
+// collect gathers the metrics of the domains in scope
+func (c *LibvirtCollector) collect(ch chan<- prometheus.Metric, scope scrapeScope) {
+	collectors := scope.collectors
+	ctx := scope.context()
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	start := time.Now()
+	var scrapeErr error
+	c.status.scraping(start)
+	defer func() {
+		c.status.scraped(start, scrapeErr)
+	}()
+
+	span := c.tracer.StartScrape("scrape")
+	defer span.End()
+	span.SetAttribute("collectors", strconv.Itoa(len(collectors)))
+
+	c.connections.Collect(ch)
+
+	if c.metadata != nil {
+		labeled, finish := c.metadata.wrap(ch)
+		defer finish()
+		ch = labeled
+	}
+
+	// Reconnecting is left to the connection manager
+	conn, err := c.connections.Conn()
+	if err != nil {
+		slog.Warn("Skipping scrape while disconnected from libvirt", "err", err)
+		span.SetError(err)
+		scrapeErr = err
+		return
+	}
+	defer conn.Close()
+
 	// Check connection health
-	alive, err := c.conn.IsAlive()
+	call := span.Call("virConnectIsAlive")
+	alive, err := conn.IsAlive()
+	call.SetError(err)
+	call.End()
 	if err != nil || !alive {
-		log.Printf("Warning: Connection to libvirt lost, reconnecting...")
-		c.conn.Close()
-
-		conn, err := libvirt.NewConnect(c.uri)
-		if err != nil {
-			log.Printf("Error: Failed to reconnect to libvirt: %v", err)
-			return
-		}
-		c.conn = conn
-		log.Println("Successfully reconnected to libvirt")
+		slog.Warn("Connection to libvirt lost, skipping scrape")
+		span.SetError(errNotConnected)
+		scrapeErr = errNotConnected
+		c.connections.Broken()
+		return
 	}
 
 	// Get all domains
-	domains, err := c.conn.ListAllDomains(
+	call = span.Call("virConnectListAllDomains")
+	domains, err := conn.ListAllDomains(
 		libvirt.CONNECT_LIST_DOMAINS_ACTIVE | libvirt.CONNECT_LIST_DOMAINS_INACTIVE,
 	)
+	call.SetError(err)
+	call.End()
 	if err != nil {
-		log.Printf("Error: Failed to list domains: %v", err)
+		slog.Error("Failed to list domains", "err", err)
+		span.SetError(err)
+		scrapeErr = err
 		return
 	}
 	defer func() {
@@ -107,16 +992,143 @@ func (c *LibvirtCollector) Collect(ch chan<- prometheus.Metric) {
 		}
 	}()
 
+	// Resolve domain label values before any collector emits metrics
+	collisions := c.sanitizer.Update(domains)
+	if c.exporterCollector != nil {
+		c.exporterCollector.SetLabelCollisions(collisions)
+	}
+
 	// Reset all collectors to prepare for a new scrape
-	for _, collector := range c.collectors {
+	for _, collector := range collectors {
 		collector.Reset()
 	}
+	c.metricsCollector.Reset()
 
-	// Collect domain metrics
-	for _, domain := range domains {
-		// Use individual collectors to gather metrics
-		for _, collector := range c.collectors {
-			collector.Collect(ch, c.conn, &domain)
+	// Forget inventory entries of domains that no longer exist
+	uuids := make(map[string]bool, len(domains))
+	for i := range domains {
+		if uuid, err := domains[i].GetUUIDString(); err == nil {
+			uuids[uuid] = true
+		}
+	}
+	c.inventory.retain(uuids)
+	if c.metadata != nil {
+		c.metadata.retain(uuids)
+	}
+	for _, collector := range c.collectors {
+		if retainer, ok := collector.(domainRetainer); ok {
+			retainer.retain(uuids)
+		}
+	}
+
+	// Restrict the scrape to the requested domains
+	selected := make([]*libvirt.Domain, 0, len(domains))
+	for i := range domains {
+		if scope.filtered() {
+			name, nameErr := domains[i].GetName()
+			uuid, uuidErr := domains[i].GetUUIDString()
+			if nameErr != nil || uuidErr != nil || !scope.includes(name, uuid) {
+				continue
+			}
+		}
+		selected = append(selected, &domains[i])
+	}
+
+	// Degrade instead of growing without bound on very large hosts
+	selected, collectors = c.applyLimits(selected, collectors)
+
+	// Fetch the statistics of all selected domains in one call
+	if c.batch != nil {
+		call := span.Call("virConnectGetAllDomainStats")
+		err := c.batch.Prefetch(conn, selected)
+		call.SetError(err)
+		call.End()
+		if err != nil {
+			slog.Warn("Failed to fetch domain statistics in batch, collecting per domain", "err", err)
+		}
+	}
+
+	// Collect domain metrics with a bounded pool of workers
+	workers := c.workerCount(len(selected))
+	span.SetAttribute("domains", strconv.Itoa(len(selected)))
+	span.SetAttribute("workers", strconv.Itoa(workers))
+	if c.exporterCollector != nil {
+		c.exporterCollector.SetWorkers(workers)
+	}
+
+	// Domain states decide whether counters are recorded or replayed
+	var states map[string]libvirt.DomainState
+	retain := c.retention != nil && hasCounterCollector(collectors)
+	if retain {
+		states = domainStates(domains)
+	}
+
+	// Host metrics are collected once, alongside the domains
+	hostCollectors, domainCollectors := splitHostCollectors(collectors)
+	var wg sync.WaitGroup
+	if len(hostCollectors) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.collectHost(ctx, ch, conn, hostCollectors, scope, span)
+			c.status.progressed()
+		}()
+	}
+
+	jobs := make(chan *libvirt.Domain)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for domain := range jobs {
+				c.collectDomainWithTimeout(ctx, ch, conn, domain, domainCollectors, states, span)
+				c.status.progressed()
+			}
+		}()
+	}
+	// Stop handing out domains once the scrape deadline is reached
+	queued := 0
+	for _, domain := range selected {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case jobs <- domain:
+			queued++
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	c.metricsCollector.forgetDomainXML()
+
+	partial := ctx.Err() != nil
+	if partial {
+		slog.Warn("Scrape deadline reached, returning partial results",
+			"domains", len(selected), "queued", queued, "err", ctx.Err())
+		span.SetError(ctx.Err())
+		scrapeErr = fmt.Errorf("partial scrape: %w", ctx.Err())
+	}
+	if c.exporterCollector != nil {
+		if partial {
+			c.exporterCollector.RecordPartialScrape()
+		}
+		ch <- c.exporterCollector.partialMetric(partial)
+	}
+
+	if retain {
+		c.retention.Finish(ch, states, scope)
+	}
+
+	// Forget counter state of devices and cached state of domains that
+	// disappeared. Filtered and partial scrapes do not see every device and
+	// domain, so only complete full scrapes prune.
+	if !scope.filtered() && !partial {
+		c.counters.Prune()
+		for _, collector := range collectors {
+			if pruner, ok := collector.(pruner); ok {
+				pruner.prune()
+			}
 		}
 	}
 
@@ -126,11 +1138,453 @@ func (c *LibvirtCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 }
 
+// collectDomainWithTimeout runs collectDomain within the per-domain timeout
+// and the scrape deadline of ctx. A domain exceeding either (e.g. with a hung
+// monitor socket) is abandoned, see collectDetached.
+func (c *LibvirtCollector) collectDomainWithTimeout(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+	collectors []Collector,
+	states map[string]libvirt.DomainState,
+	scrapeSpan *Span,
+) {
+	span := scrapeSpan.Child("domain")
+	defer span.End()
+	defer c.observeDomainDuration(ch, domain, time.Now())
+	if span != nil {
+		domainName, _ := domain.GetName()
+		domainUUID, _ := domain.GetUUIDString()
+		span.SetAttribute("domain", domainName)
+		span.SetAttribute("uuid", domainUUID)
+	}
+
+	if c.opts.DomainTimeout <= 0 && ctx.Done() == nil {
+		c.collectDomain(ctx, ch, conn, domain, collectors, states, span)
+		return
+	}
+
+	domainCtx := ctx
+	if c.opts.DomainTimeout > 0 {
+		var cancel context.CancelFunc
+		domainCtx, cancel = context.WithTimeout(ctx, c.opts.DomainTimeout)
+		defer cancel()
+	}
+
+	finished := c.collectDetached(domainCtx, ch, domainRef(domain), func(metrics chan<- prometheus.Metric) {
+		c.collectDomain(domainCtx, metrics, conn, domain, collectors, states, span)
+	})
+	if finished {
+		return
+	}
+	span.SetError(domainCtx.Err())
+	if ctx.Err() != nil {
+		// The scrape deadline was reached, reported for the whole scrape
+		return
+	}
+
+	domainName, _ := domain.GetName()
+	slog.Warn("Collection of domain timed out, skipping", "domain", domainName, "timeout", c.opts.DomainTimeout)
+	if c.exporterCollector != nil {
+		c.exporterCollector.RecordDomainTimeout(span.TraceID())
+	}
+}
+
+// observeDomainDuration sends the time taken to collect a domain since start,
+// including domains abandoned after a timeout
+func (c *LibvirtCollector) observeDomainDuration(
+	ch chan<- prometheus.Metric,
+	domain *libvirt.Domain,
+	start time.Time,
+) {
+	if c.exporterCollector == nil {
+		return
+	}
+	rawName, nameErr := domain.GetName()
+	uuid, uuidErr := domain.GetUUIDString()
+	if nameErr != nil || uuidErr != nil {
+		return
+	}
+	ch <- c.exporterCollector.domainDurationMetric(c.sanitizer.Label(rawName, uuid), uuid, time.Since(start))
+}
+
+// libvirtRef references the libvirt object used by a collection, returning
+// the function releasing it
+type libvirtRef func() (release func(), err error)
+
+// domainRef references a domain
+func domainRef(domain *libvirt.Domain) libvirtRef {
+	return func() (func(), error) {
+		if err := domain.Ref(); err != nil {
+			return nil, err
+		}
+		return func() { domain.Free() }, nil
+	}
+}
+
+// connRef references a connection
+func connRef(conn *libvirt.Connect) libvirtRef {
+	return func() (func(), error) {
+		if err := conn.Ref(); err != nil {
+			return nil, err
+		}
+		return func() { conn.Close() }, nil
+	}
+}
+
+// collectDetached runs collect in the background and forwards its samples to
+// ch until it finishes or ctx is done, and reports whether it finished.
+// libvirt calls cannot be cancelled, so an abandoned collection is left to
+// finish with the domain or connection still referenced and its late samples
+// are dropped.
+func (c *LibvirtCollector) collectDetached(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	ref libvirtRef,
+	collect func(chan<- prometheus.Metric),
+) bool {
+	// Keep the object referenced while its collection may outlive the scrape
+	release, err := ref()
+	if err != nil {
+		slog.Warn("Failed to reference libvirt object, collecting without timeout", "err", err)
+		collect(ch)
+		return true
+	}
+
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		defer release()
+		defer close(metrics)
+		collect(metrics)
+	}()
+
+	for {
+		select {
+		case metric, ok := <-metrics:
+			if !ok {
+				return true
+			}
+			ch <- metric
+		case <-ctx.Done():
+			// Discard whatever the abandoned collection still produces
+			go func() {
+				for range metrics {
+				}
+			}()
+			return false
+		}
+	}
+}
+
+// collectDomain runs every collector for a single domain
+func (c *LibvirtCollector) collectDomain(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+	collectors []Collector,
+	states map[string]libvirt.DomainState,
+	domainSpan *Span,
+) {
+	var name, uuid string
+	var state libvirt.DomainState
+	retain := false
+	if states != nil {
+		rawName, nameErr := domain.GetName()
+		domainUUID, uuidErr := domain.GetUUIDString()
+		if nameErr == nil && uuidErr == nil {
+			name = c.sanitizer.Label(rawName, domainUUID)
+			uuid = domainUUID
+			state, retain = states[uuid]
+		}
+	}
+
+	// Metadata labels are read before any collector emits metrics
+	if c.metadata != nil {
+		c.metadata.refresh(domain)
+	}
+
+	// Use individual collectors to gather metrics
+	for _, collector := range collectors {
+		// Skip the remaining collectors once the domain is out of time
+		if ctx.Err() != nil {
+			return
+		}
+
+		span := domainSpan.Child("collector")
+		if span != nil {
+			span.SetAttribute("collector", c.collectorName(collector))
+		}
+		collect := func(ctx context.Context, ch chan<- prometheus.Metric) {
+			c.runCollector(collector, func() {
+				if _, ok := collector.(counterCollector); ok && retain {
+					c.retention.Collect(ctx, ch, collector, conn, domain, name, uuid, state)
+					return
+				}
+				collector.Collect(ctx, ch, conn, domain)
+			})
+		}
+
+		timeout, ok := c.timeouts[collector]
+		if !ok {
+			collect(ctx, ch)
+			span.End()
+			continue
+		}
+
+		collectorCtx, cancel := context.WithTimeout(ctx, timeout)
+		finished := c.collectDetached(collectorCtx, ch, domainRef(domain), func(metrics chan<- prometheus.Metric) {
+			collect(collectorCtx, metrics)
+		})
+		if !finished {
+			span.SetError(collectorCtx.Err())
+		}
+		if !finished && ctx.Err() == nil {
+			collectorName := c.collectorName(collector)
+			domainName, _ := domain.GetName()
+			slog.Warn("Collector timed out, skipping",
+				"collector", collectorName, "domain", domainName, "timeout", timeout)
+			if c.exporterCollector != nil {
+				c.exporterCollector.RecordCollectorTimeout(collectorName)
+			}
+			c.status.collectorFailed(collectorName, fmt.Sprintf("timed out on domain %s after %s", domainName, timeout))
+		}
+		cancel()
+		span.End()
+	}
+}
+
+// splitHostCollectors separates the host collectors from the collectors run
+// for each domain
+func splitHostCollectors(collectors []Collector) (host []HostCollector, domain []Collector) {
+	for _, collector := range collectors {
+		if hostCollector, ok := collector.(HostCollector); ok {
+			host = append(host, hostCollector)
+			continue
+		}
+		domain = append(domain, collector)
+	}
+	return host, domain
+}
+
+// collectHost runs the host collectors once for the scrape, each within its
+// collector timeout if one is configured
+func (c *LibvirtCollector) collectHost(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	collectors []HostCollector,
+	scope scrapeScope,
+	scrapeSpan *Span,
+) {
+	span := scrapeSpan.Child("host")
+	defer span.End()
+
+	for _, collector := range collectors {
+		if ctx.Err() != nil {
+			return
+		}
+
+		collect := func(ctx context.Context, ch chan<- prometheus.Metric) {
+			c.runCollector(collector, func() {
+				collector.CollectHost(ctx, ch, conn, scope.includes)
+			})
+		}
+
+		timeout, ok := c.timeouts[collector]
+		if !ok && ctx.Done() == nil {
+			collect(ctx, ch)
+			continue
+		}
+
+		collectorCtx, cancel := ctx, context.CancelFunc(func() {})
+		if ok {
+			collectorCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		finished := c.collectDetached(collectorCtx, ch, connRef(conn), func(metrics chan<- prometheus.Metric) {
+			collect(collectorCtx, metrics)
+		})
+		cancel()
+		if finished || ctx.Err() != nil {
+			continue
+		}
+
+		collectorName := c.collectorName(collector)
+		slog.Warn("Host collector timed out, skipping", "collector", collectorName, "timeout", timeout)
+		span.SetError(collectorCtx.Err())
+		if c.exporterCollector != nil {
+			c.exporterCollector.RecordCollectorTimeout(collectorName)
+		}
+		c.status.collectorFailed(collectorName, fmt.Sprintf("timed out after %s", timeout))
+	}
+}
+
+// runCollector runs a sub-collector, recovering from panics so that one
+// failing collector does not abort the scrape for the remaining collectors
+func (c *LibvirtCollector) runCollector(collector Collector, collect func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			name := c.collectorName(collector)
+			slog.Error("Collector panicked", "collector", name, "panic", r, "stack", string(debug.Stack()))
+			if c.exporterCollector != nil {
+				c.exporterCollector.RecordCollectorPanic(name)
+			}
+			c.status.collectorFailed(name, fmt.Sprintf("panic: %v", r))
+		}
+	}()
+	collect()
+}
+
+// collectorName returns the name a sub-collector was registered under
+func (c *LibvirtCollector) collectorName(collector Collector) string {
+	for i, registered := range c.collectors {
+		if registered == collector {
+			return c.collectorNames[i]
+		}
+	}
+	return "unknown"
+}
+
+// hasCounterCollector reports whether any of the collectors has retained counters
+func hasCounterCollector(collectors []Collector) bool {
+	for _, collector := range collectors {
+		if _, ok := collector.(counterCollector); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// domainStates returns the current state of each domain keyed by UUID
+func domainStates(domains []libvirt.Domain) map[string]libvirt.DomainState {
+	states := make(map[string]libvirt.DomainState, len(domains))
+	for i := range domains {
+		uuid, err := domains[i].GetUUIDString()
+		if err != nil {
+			continue
+		}
+		state, _, err := domains[i].GetState()
+		if err != nil {
+			continue
+		}
+		states[uuid] = state
+	}
+	return states
+}
+
+// sortByUUID returns the domains sorted by UUID. libvirt lists domains in no
+// stable order, so this keeps the same domains below the max-domains cap from
+// one scrape to the next.
+func sortByUUID(domains []*libvirt.Domain) []*libvirt.Domain {
+	uuids := make(map[*libvirt.Domain]string, len(domains))
+	for _, domain := range domains {
+		uuids[domain], _ = domain.GetUUIDString()
+	}
+	sorted := append([]*libvirt.Domain(nil), domains...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return uuids[sorted[i]] < uuids[sorted[j]]
+	})
+	return sorted
+}
+
+// applyLimits enforces the max-domains cap and the memory guard, dropping
+// domains beyond the cap and optional collectors while over the memory limit
+func (c *LibvirtCollector) applyLimits(
+	domains []*libvirt.Domain,
+	collectors []Collector,
+) ([]*libvirt.Domain, []Collector) {
+	domainsSaturated := c.opts.MaxDomains > 0 && len(domains) > c.opts.MaxDomains
+	if domainsSaturated {
+		slog.Warn("Domains exceed the limit, collecting only the first ones by UUID",
+			"domains", len(domains), "limit", c.opts.MaxDomains)
+		domains = sortByUUID(domains)[:c.opts.MaxDomains]
+	}
+
+	memorySaturated := false
+	if c.opts.MemoryLimit > 0 {
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		memorySaturated = memStats.HeapAlloc > c.opts.MemoryLimit
+	}
+	if memorySaturated {
+		slog.Warn("Exporter memory usage exceeds the limit, skipping optional collectors",
+			"limit_bytes", c.opts.MemoryLimit)
+		essential := make([]Collector, 0, len(collectors))
+		for _, collector := range collectors {
+			if !optionalCollectors[c.collectorName(collector)] {
+				essential = append(essential, collector)
+			}
+		}
+		collectors = essential
+	}
+
+	if c.exporterCollector != nil {
+		c.exporterCollector.SetSaturated("domains", domainsSaturated)
+		c.exporterCollector.SetSaturated("memory", memorySaturated)
+	}
+
+	return domains, collectors
+}
+
+// workerCount returns the number of workers used to collect the given number of domains
+func (c *LibvirtCollector) workerCount(domains int) int {
+	workers := c.opts.MaxConcurrent
+	if c.opts.Autoscale && c.opts.DomainsPerWorker > 0 {
+		// One worker per DomainsPerWorker domains, rounded up
+		workers = (domains + c.opts.DomainsPerWorker - 1) / c.opts.DomainsPerWorker
+		if c.opts.MaxWorkers > 0 && workers > c.opts.MaxWorkers {
+			workers = c.opts.MaxWorkers
+		}
+	}
+	if workers > domains {
+		workers = domains
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+// Domains returns the summaries of the domains seen by the last scrapes
+func (c *LibvirtCollector) Domains() []DomainSummary {
+	return c.inventory.Domains()
+}
+
+// HostInfo returns a snapshot of the host, its storage pools and networks.
+// The host CPU usage is left out, its baseline belongs to the scrapes.
+func (c *LibvirtCollector) HostInfo(ctx context.Context) (*ConnectionMetrics, error) {
+	conn, err := c.connections.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return c.metricsCollector.CollectConnectionStats(ctx, conn)
+}
+
 // Close closes the libvirt connection
 func (c *LibvirtCollector) Close() {
-	if c.conn != nil {
-		log.Println("Closing libvirt connection...")
-		c.conn.Close()
-		log.Println("Libvirt connection closed")
+	if c.background != nil {
+		c.background.Stop()
+	}
+	if c.probe != nil {
+		c.probe.Stop()
+	}
+	if c.migrations != nil {
+		c.migrations.Stop()
+	}
+	if c.lifecycle != nil {
+		c.lifecycle.Stop()
+	}
+	if c.admin != nil {
+		c.admin.Close()
+	}
+	if c.elector != nil {
+		c.elector.Stop()
 	}
+	c.tracer.Stop()
+	c.stopDiscovery()
+	c.closeHosts()
+	c.connections.Close()
 }
@@ -1,8 +1,10 @@
 package collector
 
 import (
-	"log"
-	"sync/atomic"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"libvirt.org/go/libvirt"
@@ -18,12 +20,23 @@ type ConnectionCollector struct {
 	libvirtVersion           *prometheus.Desc
 	hypervisorVersion        *prometheus.Desc
 	driverType               *prometheus.Desc
+	hostInfo                 *prometheus.Desc
 
 	// Host resource metrics
 	hostCPUCount             *prometheus.Desc
 	hostCPUPercent           *prometheus.Desc
+	hostCPUTime              *prometheus.Desc
 	hostMemoryTotal          *prometheus.Desc
 	hostMemoryFree           *prometheus.Desc
+	hostCPUIowait            *prometheus.Desc
+	hostCPUSteal             *prometheus.Desc
+	hostPhysicalCores        *prometheus.Desc
+	hostVCPUsAllocated       *prometheus.Desc
+	hostVCPUToCoreRatio      *prometheus.Desc
+	hostVCPUOvercommit       *prometheus.Desc
+	hostMemoryAllocated      *prometheus.Desc
+	hostMemoryOvercommit     *prometheus.Desc
+	hostMemoryBalloonRatio   *prometheus.Desc
 
 	// Storage pool metrics
 	storagePoolInfo          *prometheus.Desc
@@ -35,6 +48,12 @@ type ConnectionCollector struct {
 	// Network pool metrics
 	networkPoolInfo          *prometheus.Desc
 	networkPoolBridge        *prometheus.Desc
+	networkPoolActive        *prometheus.Desc
+	networkPoolAutostart     *prometheus.Desc
+	networkPoolPersistent    *prometheus.Desc
+	networkPoolDHCPLeases    *prometheus.Desc
+	networkDHCPLease         *prometheus.Desc
+	networkDHCPLeaseExpiry   *prometheus.Desc
 
 	// Host interface metrics
 	hostInterfaceRxBytes     *prometheus.Desc
@@ -42,14 +61,21 @@ type ConnectionCollector struct {
 	hostInterfaceRxPackets   *prometheus.Desc
 	hostInterfaceTxPackets   *prometheus.Desc
 
-	metricsCollector MetricsCollector
+	// Host topology metrics
+	hostNUMANodes            *prometheus.Desc
+	hostNUMANodeCPUs         *prometheus.Desc
+	hostNUMANodeMemory       *prometheus.Desc
+	hostNUMADistance         *prometheus.Desc
+	hostIOMMUEnabled         *prometheus.Desc
+	hostIOMMUGroups          *prometheus.Desc
+	hostVFIODevices          *prometheus.Desc
 
-	// Used to ensure we only collect connection metrics once per scrape
-	collected uint32 // atomic flag
+	metricsCollector MetricsCollector
+	exporterVersion  string
 }
 
 // NewConnectionCollector creates a new ConnectionCollector
-func NewConnectionCollector() *ConnectionCollector {
+func NewConnectionCollector(metricsCollector MetricsCollector, exporterVersion string) *ConnectionCollector {
 	return &ConnectionCollector{
 		// Connection metrics
 		connectionAlive: prometheus.NewDesc(
@@ -94,6 +120,12 @@ func NewConnectionCollector() *ConnectionCollector {
 			[]string{"driver"},
 			nil,
 		),
+		hostInfo: prometheus.NewDesc(
+			"libvirt_host_info",
+			"Host information, value is always 1",
+			[]string{"hostname", "driver", "libvirt_version", "hypervisor_version", "exporter_version"},
+			nil,
+		),
 
 		// Host resource metrics
 		hostCPUCount: prometheus.NewDesc(
@@ -104,10 +136,16 @@ func NewConnectionCollector() *ConnectionCollector {
 		),
 		hostCPUPercent: prometheus.NewDesc(
 			"libvirt_host_cpu_usage_percent",
-			"Host CPU usage percentage",
+			"Host CPU usage percentage since the previous scrape",
 			[]string{},
 			nil,
 		),
+		hostCPUTime: prometheus.NewDesc(
+			"libvirt_host_cpu_time_seconds_total",
+			"Time host CPUs spent in each mode in seconds",
+			[]string{"mode"},
+			nil,
+		),
 		hostMemoryTotal: prometheus.NewDesc(
 			"libvirt_host_memory_total_bytes",
 			"Total memory on the host in bytes",
@@ -120,6 +158,60 @@ func NewConnectionCollector() *ConnectionCollector {
 			[]string{},
 			nil,
 		),
+		hostCPUIowait: prometheus.NewDesc(
+			"libvirt_host_cpu_iowait_seconds_total",
+			"Time host CPUs spent waiting for I/O in seconds (local connections only)",
+			[]string{},
+			nil,
+		),
+		hostCPUSteal: prometheus.NewDesc(
+			"libvirt_host_cpu_steal_seconds_total",
+			"Time stolen from host CPUs by an underlying hypervisor in seconds (local connections only)",
+			[]string{},
+			nil,
+		),
+		hostPhysicalCores: prometheus.NewDesc(
+			"libvirt_host_physical_cores",
+			"Number of physical CPU cores on the host",
+			[]string{},
+			nil,
+		),
+		hostVCPUsAllocated: prometheus.NewDesc(
+			"libvirt_host_vcpus_allocated",
+			"Number of vCPUs allocated to running domains",
+			[]string{},
+			nil,
+		),
+		hostVCPUToCoreRatio: prometheus.NewDesc(
+			"libvirt_host_vcpu_to_core_ratio",
+			"Ratio of vCPUs allocated to running domains to physical CPU cores",
+			[]string{},
+			nil,
+		),
+		hostVCPUOvercommit: prometheus.NewDesc(
+			"libvirt_host_vcpu_overcommit_ratio",
+			"Ratio of vCPUs allocated to running domains to online host CPUs",
+			[]string{},
+			nil,
+		),
+		hostMemoryAllocated: prometheus.NewDesc(
+			"libvirt_host_memory_allocated_bytes",
+			"Configured maximum memory of running domains in bytes",
+			[]string{},
+			nil,
+		),
+		hostMemoryOvercommit: prometheus.NewDesc(
+			"libvirt_host_memory_overcommit_ratio",
+			"Ratio of the configured maximum memory of running domains to host physical memory",
+			[]string{},
+			nil,
+		),
+		hostMemoryBalloonRatio: prometheus.NewDesc(
+			"libvirt_host_memory_balloon_overcommit_ratio",
+			"Ratio of the actual (ballooned) memory of running domains to host physical memory",
+			[]string{},
+			nil,
+		),
 
 		// Storage pool metrics
 		storagePoolInfo: prometheus.NewDesc(
@@ -166,6 +258,42 @@ func NewConnectionCollector() *ConnectionCollector {
 			[]string{"name", "bridge"},
 			nil,
 		),
+		networkPoolActive: prometheus.NewDesc(
+			"libvirt_network_pool_active",
+			"Whether the virtual network is active",
+			[]string{"name"},
+			nil,
+		),
+		networkPoolAutostart: prometheus.NewDesc(
+			"libvirt_network_pool_autostart",
+			"Whether the virtual network is set to autostart",
+			[]string{"name"},
+			nil,
+		),
+		networkPoolPersistent: prometheus.NewDesc(
+			"libvirt_network_pool_persistent",
+			"Whether the virtual network is persistent",
+			[]string{"name"},
+			nil,
+		),
+		networkPoolDHCPLeases: prometheus.NewDesc(
+			"libvirt_network_pool_dhcp_leases",
+			"Number of DHCP leases handed out by the virtual network",
+			[]string{"name"},
+			nil,
+		),
+		networkDHCPLease: prometheus.NewDesc(
+			"libvirt_network_dhcp_leases",
+			"DHCP lease handed out by the virtual network, value is always 1",
+			[]string{"network", "mac", "ip", "hostname"},
+			nil,
+		),
+		networkDHCPLeaseExpiry: prometheus.NewDesc(
+			"libvirt_network_dhcp_lease_expiry_timestamp_seconds",
+			"Unix timestamp at which the DHCP lease expires",
+			[]string{"network", "mac", "ip", "hostname"},
+			nil,
+		),
 
 		// Host interface metrics
 		hostInterfaceRxBytes: prometheus.NewDesc(
@@ -193,7 +321,53 @@ func NewConnectionCollector() *ConnectionCollector {
 			nil,
 		),
 
-		metricsCollector: NewLibvirtMetricsCollector(),
+
+		// Host topology metrics
+		hostNUMANodes: prometheus.NewDesc(
+			"libvirt_host_numa_nodes",
+			"Number of NUMA nodes on the host",
+			[]string{},
+			nil,
+		),
+		hostNUMANodeCPUs: prometheus.NewDesc(
+			"libvirt_host_numa_node_cpus",
+			"Number of host CPUs in the NUMA node",
+			[]string{"node"},
+			nil,
+		),
+		hostNUMANodeMemory: prometheus.NewDesc(
+			"libvirt_host_numa_node_memory_bytes",
+			"Memory of the NUMA node in bytes",
+			[]string{"node"},
+			nil,
+		),
+		hostNUMADistance: prometheus.NewDesc(
+			"libvirt_host_numa_distance",
+			"Relative distance between two NUMA nodes as reported by the firmware (10 = local)",
+			[]string{"node", "sibling"},
+			nil,
+		),
+		hostIOMMUEnabled: prometheus.NewDesc(
+			"libvirt_host_iommu_enabled",
+			"Whether the IOMMU is enabled on the host (1=enabled, 0=disabled)",
+			[]string{},
+			nil,
+		),
+		hostIOMMUGroups: prometheus.NewDesc(
+			"libvirt_host_iommu_groups",
+			"Number of IOMMU groups of the host PCI devices",
+			[]string{},
+			nil,
+		),
+		hostVFIODevices: prometheus.NewDesc(
+			"libvirt_host_vfio_devices",
+			"Number of host PCI devices bound to the vfio-pci driver",
+			[]string{},
+			nil,
+		),
+
+		metricsCollector: metricsCollector,
+		exporterVersion:  exporterVersion,
 	}
 }
 
@@ -207,12 +381,23 @@ func (c *ConnectionCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.libvirtVersion
 	ch <- c.hypervisorVersion
 	ch <- c.driverType
+	ch <- c.hostInfo
 
 	// Host resource metrics
 	ch <- c.hostCPUCount
 	ch <- c.hostCPUPercent
+	ch <- c.hostCPUTime
 	ch <- c.hostMemoryTotal
 	ch <- c.hostMemoryFree
+	ch <- c.hostCPUIowait
+	ch <- c.hostCPUSteal
+	ch <- c.hostPhysicalCores
+	ch <- c.hostVCPUsAllocated
+	ch <- c.hostVCPUToCoreRatio
+	ch <- c.hostVCPUOvercommit
+	ch <- c.hostMemoryAllocated
+	ch <- c.hostMemoryOvercommit
+	ch <- c.hostMemoryBalloonRatio
 
 	// Storage pool metrics
 	ch <- c.storagePoolInfo
@@ -224,46 +409,73 @@ func (c *ConnectionCollector) Describe(ch chan<- *prometheus.Desc) {
 	// Network pool metrics
 	ch <- c.networkPoolInfo
 	ch <- c.networkPoolBridge
+	ch <- c.networkPoolActive
+	ch <- c.networkPoolAutostart
+	ch <- c.networkPoolPersistent
+	ch <- c.networkPoolDHCPLeases
+	ch <- c.networkDHCPLease
+	ch <- c.networkDHCPLeaseExpiry
 
 	// Host interface metrics
 	ch <- c.hostInterfaceRxBytes
 	ch <- c.hostInterfaceTxBytes
 	ch <- c.hostInterfaceRxPackets
 	ch <- c.hostInterfaceTxPackets
+
+	// Host topology metrics
+	ch <- c.hostNUMANodes
+	ch <- c.hostNUMANodeCPUs
+	ch <- c.hostNUMANodeMemory
+	ch <- c.hostNUMADistance
+	ch <- c.hostIOMMUEnabled
+	ch <- c.hostIOMMUGroups
+	ch <- c.hostVFIODevices
 }
 
 // Reset implements the Collector interface for ConnectionCollector
 func (c *ConnectionCollector) Reset() {
-	atomic.StoreUint32(&c.collected, 0)
+	// No internal state to reset
 }
 
-// Collect implements the Collector interface for ConnectionCollector
+// Collect implements the Collector interface for ConnectionCollector; its
+// metrics are host-wide and sent by CollectHost
 func (c *ConnectionCollector) Collect(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) {
-	// Use atomic operation to ensure we only collect connection metrics once per scrape
-	if atomic.CompareAndSwapUint32(&c.collected, 0, 1) {
-		c.collectConnectionMetrics(ch, conn)
-		c.collectHostMetrics(ch, conn)
-		c.collectStoragePoolMetrics(ch, conn)
-		c.collectNetworkPoolMetrics(ch, conn)
-		c.collectHostInterfaceMetrics(ch, conn)
-	}
 }
 
-// collectConnectionMetrics collects connection-level metrics
-func (c *ConnectionCollector) collectConnectionMetrics(
+// CollectHost implements the HostCollector interface for ConnectionCollector
+func (c *ConnectionCollector) CollectHost(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
+	includes func(name, uuid string) bool,
 ) {
-	metrics, err := c.metricsCollector.CollectConnectionStats(conn)
+	// Fetched once so that all series describe the same sample and host CPU
+	// usage is computed over the interval between scrapes
+	metrics, err := c.metricsCollector.CollectConnectionStats(ctx, conn)
 	if err != nil {
-		log.Printf("Warning: Failed to collect connection metrics: %v", err)
-		return
+		slog.Warn("Failed to collect connection metrics", "err", err)
+	} else {
+		c.metricsCollector.CollectHostCPUUsage(ctx, conn, metrics)
+		c.collectConnectionMetrics(ch, metrics)
+		c.collectHostMetrics(ch, metrics)
+		c.collectStoragePoolMetrics(ch, metrics)
+		c.collectNetworkPoolMetrics(ch, metrics)
+		c.collectHostInterfaceMetrics(ch, metrics)
 	}
+	c.collectHostResourceMetrics(ctx, ch, conn)
+	c.collectHostTopologyMetrics(ctx, ch, conn)
+}
 
+// collectConnectionMetrics collects connection-level metrics
+func (c *ConnectionCollector) collectConnectionMetrics(
+	ch chan<- prometheus.Metric,
+	metrics *ConnectionMetrics,
+) {
 	// Connection metrics
 	var aliveValue float64
 	if metrics.IsAlive {
@@ -313,19 +525,30 @@ func (c *ConnectionCollector) collectConnectionMetrics(
 		1.0,
 		metrics.DriverType,
 	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostInfo,
+		prometheus.GaugeValue,
+		1.0,
+		metrics.Hostname,
+		metrics.DriverType,
+		FormatVersion(metrics.LibvirtVersion),
+		FormatVersion(metrics.HypervisorVersion),
+		c.exporterVersion,
+	)
+}
+
+// FormatVersion converts a libvirt encoded version (major * 1,000,000 +
+// minor * 1,000 + release) into its dotted form
+func FormatVersion(version uint64) string {
+	return fmt.Sprintf("%d.%d.%d", version/1000000, (version/1000)%1000, version%1000)
 }
 
 // collectHostMetrics collects host-level metrics
 func (c *ConnectionCollector) collectHostMetrics(
 	ch chan<- prometheus.Metric,
-	conn *libvirt.Connect,
+	metrics *ConnectionMetrics,
 ) {
-	metrics, err := c.metricsCollector.CollectConnectionStats(conn)
-	if err != nil {
-		log.Printf("Warning: Failed to collect host metrics: %v", err)
-		return
-	}
-
 	// Host resource metrics
 	ch <- prometheus.MustNewConstMetric(
 		c.hostCPUCount,
@@ -333,11 +556,23 @@ func (c *ConnectionCollector) collectHostMetrics(
 		float64(metrics.TotalCPUs),
 	)
 
-	ch <- prometheus.MustNewConstMetric(
-		c.hostCPUPercent,
-		prometheus.GaugeValue,
-		metrics.HostCPUUsagePercent,
-	)
+	// Usage needs the CPU times of a previous scrape
+	if metrics.HasHostCPUUsage {
+		ch <- prometheus.MustNewConstMetric(
+			c.hostCPUPercent,
+			prometheus.GaugeValue,
+			metrics.HostCPUUsagePercent,
+		)
+	}
+
+	for mode, seconds := range metrics.HostCPUTimes {
+		ch <- prometheus.MustNewConstMetric(
+			c.hostCPUTime,
+			prometheus.CounterValue,
+			seconds,
+			mode,
+		)
+	}
 
 	ch <- prometheus.MustNewConstMetric(
 		c.hostMemoryTotal,
@@ -352,17 +587,86 @@ func (c *ConnectionCollector) collectHostMetrics(
 	)
 }
 
-// collectStoragePoolMetrics collects storage pool metrics
-func (c *ConnectionCollector) collectStoragePoolMetrics(
+// collectHostResourceMetrics collects host CPU contention and overcommit metrics
+func (c *ConnectionCollector) collectHostResourceMetrics(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
 ) {
-	metrics, err := c.metricsCollector.CollectConnectionStats(conn)
+	metrics, err := c.metricsCollector.CollectHostResourceStats(ctx, conn)
 	if err != nil {
-		log.Printf("Warning: Failed to collect storage pool metrics: %v", err)
+		slog.Warn("Failed to collect host CPU metrics", "err", err)
 		return
 	}
 
+	if metrics.HasProcStat {
+		ch <- prometheus.MustNewConstMetric(
+			c.hostCPUIowait,
+			prometheus.CounterValue,
+			metrics.IowaitSeconds,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.hostCPUSteal,
+			prometheus.CounterValue,
+			metrics.StealSeconds,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostPhysicalCores,
+		prometheus.GaugeValue,
+		float64(metrics.PhysicalCores),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostVCPUsAllocated,
+		prometheus.GaugeValue,
+		float64(metrics.AllocatedVCPUs),
+	)
+
+	if metrics.PhysicalCores > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.hostVCPUToCoreRatio,
+			prometheus.GaugeValue,
+			float64(metrics.AllocatedVCPUs)/float64(metrics.PhysicalCores),
+		)
+	}
+
+	if metrics.OnlineCPUs > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.hostVCPUOvercommit,
+			prometheus.GaugeValue,
+			float64(metrics.AllocatedVCPUs)/float64(metrics.OnlineCPUs),
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostMemoryAllocated,
+		prometheus.GaugeValue,
+		float64(metrics.AllocatedMemoryBytes),
+	)
+
+	if metrics.PhysicalMemoryBytes > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.hostMemoryOvercommit,
+			prometheus.GaugeValue,
+			float64(metrics.AllocatedMemoryBytes)/float64(metrics.PhysicalMemoryBytes),
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.hostMemoryBalloonRatio,
+			prometheus.GaugeValue,
+			float64(metrics.BalloonMemoryBytes)/float64(metrics.PhysicalMemoryBytes),
+		)
+	}
+}
+
+// collectStoragePoolMetrics collects storage pool metrics
+func (c *ConnectionCollector) collectStoragePoolMetrics(
+	ch chan<- prometheus.Metric,
+	metrics *ConnectionMetrics,
+) {
 	for _, pool := range metrics.StoragePools {
 		ch <- prometheus.MustNewConstMetric(
 			c.storagePoolInfo,
@@ -404,14 +708,8 @@ func (c *ConnectionCollector) collectStoragePoolMetrics(
 // collectNetworkPoolMetrics collects virtual network pool metrics
 func (c *ConnectionCollector) collectNetworkPoolMetrics(
 	ch chan<- prometheus.Metric,
-	conn *libvirt.Connect,
+	metrics *ConnectionMetrics,
 ) {
-	metrics, err := c.metricsCollector.CollectConnectionStats(conn)
-	if err != nil {
-		log.Printf("Warning: Failed to collect network pool metrics: %v", err)
-		return
-	}
-
 	for _, network := range metrics.Networks {
 		ch <- prometheus.MustNewConstMetric(
 			c.networkPoolInfo,
@@ -431,20 +729,71 @@ func (c *ConnectionCollector) collectNetworkPoolMetrics(
 			activeValue,
 			network.Name, network.Bridge,
 		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.networkPoolActive,
+			prometheus.GaugeValue,
+			activeValue,
+			network.Name,
+		)
+
+		var autostartValue float64
+		if network.Autostart {
+			autostartValue = 1.0
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.networkPoolAutostart,
+			prometheus.GaugeValue,
+			autostartValue,
+			network.Name,
+		)
+
+		var persistentValue float64
+		if network.Persistent {
+			persistentValue = 1.0
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.networkPoolPersistent,
+			prometheus.GaugeValue,
+			persistentValue,
+			network.Name,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.networkPoolDHCPLeases,
+			prometheus.GaugeValue,
+			float64(network.DHCPLeases),
+			network.Name,
+		)
+
+		for _, lease := range network.Leases {
+			ch <- prometheus.MustNewConstMetric(
+				c.networkDHCPLease,
+				prometheus.GaugeValue,
+				1.0,
+				network.Name, lease.MAC, lease.IP, lease.Hostname,
+			)
+
+			// Leases without expiry have no timestamp
+			if !lease.ExpiryTime.IsZero() {
+				ch <- prometheus.MustNewConstMetric(
+					c.networkDHCPLeaseExpiry,
+					prometheus.GaugeValue,
+					float64(lease.ExpiryTime.Unix()),
+					network.Name, lease.MAC, lease.IP, lease.Hostname,
+				)
+			}
+		}
 	}
 }
 
 // collectHostInterfaceMetrics collects host interface metrics
 func (c *ConnectionCollector) collectHostInterfaceMetrics(
 	ch chan<- prometheus.Metric,
-	conn *libvirt.Connect,
+	metrics *ConnectionMetrics,
 ) {
-	metrics, err := c.metricsCollector.CollectConnectionStats(conn)
-	if err != nil {
-		log.Printf("Warning: Failed to collect host interface metrics: %v", err)
-		return
-	}
-
 	for _, iface := range metrics.Interfaces {
 		ch <- prometheus.MustNewConstMetric(
 			c.hostInterfaceRxBytes,
@@ -474,4 +823,73 @@ func (c *ConnectionCollector) collectHostInterfaceMetrics(
 			iface.Name,
 		)
 	}
-}
\ No newline at end of file
+}
+
+// collectHostTopologyMetrics collects host NUMA topology and IOMMU metrics
+func (c *ConnectionCollector) collectHostTopologyMetrics(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+) {
+	metrics, err := c.metricsCollector.CollectHostTopology(ctx, conn)
+	if err != nil {
+		slog.Warn("Failed to collect host topology metrics", "err", err)
+		return
+	}
+
+	var iommuValue float64
+	if metrics.IOMMUEnabled {
+		iommuValue = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.hostIOMMUEnabled,
+		prometheus.GaugeValue,
+		iommuValue,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostIOMMUGroups,
+		prometheus.GaugeValue,
+		float64(metrics.IOMMUGroups),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostVFIODevices,
+		prometheus.GaugeValue,
+		float64(metrics.VFIODevices),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostNUMANodes,
+		prometheus.GaugeValue,
+		float64(len(metrics.NUMANodes)),
+	)
+
+	for _, node := range metrics.NUMANodes {
+		nodeID := strconv.Itoa(node.ID)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.hostNUMANodeCPUs,
+			prometheus.GaugeValue,
+			float64(node.CPUs),
+			nodeID,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.hostNUMANodeMemory,
+			prometheus.GaugeValue,
+			float64(node.MemoryBytes),
+			nodeID,
+		)
+
+		for _, distance := range node.Distances {
+			ch <- prometheus.MustNewConstMetric(
+				c.hostNUMADistance,
+				prometheus.GaugeValue,
+				float64(distance.Value),
+				nodeID,
+				strconv.Itoa(distance.Sibling),
+			)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package collector
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+const (
+	// defaultReconnectInterval is used when no reconnect interval is configured
+	defaultReconnectInterval = 10 * time.Second
+	// maxReconnectBackoff caps the delay between failed reconnection attempts
+	maxReconnectBackoff = 5 * time.Minute
+)
+
+// errNotConnected is returned while no connection to libvirt is established
+var errNotConnected = errors.New("not connected to libvirt")
+
+// ConnectionManager maintains the libvirt connection used by scrapes in the
+// background. The connection is health checked every reconnect interval; once
+// lost, reconnection is attempted with an exponential backoff starting at the
+// reconnect interval, so scrapes never wait for a reconnect.
+type ConnectionManager struct {
+	reconnects        *prometheus.Desc
+	reconnectFailures *prometheus.Desc
+	lastConnect       *prometheus.Desc
+
+	uris      []string
+	auth      *libvirt.ConnectAuth
+	interval  time.Duration
+	onConnect func(active int) // called with the index of the connected URI
+	broken    chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+
+	mutex          sync.RWMutex
+	conn           *libvirt.Connect // nil while disconnected
+	lastErr        error
+	lastAttempt    time.Time
+	connectedAt    time.Time
+	reconnectCount uint64
+	failureCount   uint64
+}
+
+// NewConnectionManager connects to the first reachable URI and starts
+// maintaining the connection. onConnect is called with the index of the
+// connected URI on every successful connection. Unless required, a failed
+// first connection is not an error: the manager starts disconnected and
+// connects in the background.
+func NewConnectionManager(
+	uris []string,
+	auth *libvirt.ConnectAuth,
+	interval time.Duration,
+	required bool,
+	onConnect func(active int),
+) (*ConnectionManager, error) {
+	if interval <= 0 {
+		interval = defaultReconnectInterval
+	}
+
+	now := time.Now()
+	conn, active, err := connectAny(uris, auth)
+	if err != nil {
+		if required {
+			return nil, err
+		}
+		slog.Warn("Failed to connect to libvirt, retrying in the background", "err", err)
+	} else {
+		onConnect(active)
+	}
+
+	m := &ConnectionManager{
+		reconnects: prometheus.NewDesc(
+			"libvirt_connection_reconnects_total",
+			"Total number of successful reconnections to libvirt",
+			[]string{},
+			nil,
+		),
+		reconnectFailures: prometheus.NewDesc(
+			"libvirt_connection_reconnect_failures_total",
+			"Total number of failed attempts to reconnect to libvirt",
+			[]string{},
+			nil,
+		),
+		lastConnect: prometheus.NewDesc(
+			"libvirt_connection_last_connect_timestamp_seconds",
+			"Unix timestamp of the last successful connection to libvirt",
+			[]string{},
+			nil,
+		),
+		uris:        uris,
+		auth:        auth,
+		interval:    interval,
+		onConnect:   onConnect,
+		broken:      make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		conn:        conn,
+		lastErr:     err,
+		lastAttempt: now,
+	}
+	if conn != nil {
+		m.connectedAt = now
+	}
+
+	go m.run()
+	return m, nil
+}
+
+// Conn returns a reference to the current connection, which the caller must
+// release with Close, or an error while disconnected. The reference keeps the
+// connection usable even if the manager replaces it meanwhile.
+func (m *ConnectionManager) Conn() (*libvirt.Connect, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.conn == nil {
+		if m.lastErr != nil {
+			return nil, m.lastErr
+		}
+		return nil, errNotConnected
+	}
+	if err := m.conn.Ref(); err != nil {
+		return nil, err
+	}
+	return m.conn, nil
+}
+
+// Connected reports whether a connection is established
+func (m *ConnectionManager) Connected() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.conn != nil
+}
+
+// Broken reports a connection found dead by a scrape, so that it is checked
+// without waiting for the next health check
+func (m *ConnectionManager) Broken() {
+	select {
+	case m.broken <- struct{}{}:
+	default:
+	}
+}
+
+// run checks the connection and reconnects until Close is called
+func (m *ConnectionManager) run() {
+	defer close(m.done)
+
+	backoff := m.interval
+	// A failed first connection counts as the first attempt
+	first := !m.Connected()
+	for {
+		wait := m.interval
+		broken := m.broken
+		if !m.check() {
+			if !first && m.reconnect() {
+				backoff = m.interval
+			} else {
+				// Only the backoff decides when to try again
+				wait = backoff
+				backoff *= 2
+				if backoff > maxReconnectBackoff {
+					backoff = max(maxReconnectBackoff, m.interval)
+				}
+				broken = nil
+			}
+		}
+		first = false
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-broken:
+			timer.Stop()
+			// Attempts are at least the reconnect interval apart
+			m.mutex.RLock()
+			elapsed := time.Since(m.lastAttempt)
+			m.mutex.RUnlock()
+			if elapsed < m.interval {
+				timer = time.NewTimer(m.interval - elapsed)
+				select {
+				case <-timer.C:
+				case <-m.stop:
+					timer.Stop()
+					return
+				}
+			}
+		case <-m.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// check reports whether the connection is alive, dropping it if not
+func (m *ConnectionManager) check() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.conn == nil {
+		return false
+	}
+	alive, err := m.conn.IsAlive()
+	if err == nil && alive {
+		return true
+	}
+
+	slog.Warn("Connection to libvirt lost, reconnecting")
+	// Scrapes still holding a reference keep the connection object valid
+	m.conn.Close()
+	m.conn = nil
+	m.lastErr = errNotConnected
+	return false
+}
+
+// reconnect tries to connect to the first reachable URI
+func (m *ConnectionManager) reconnect() bool {
+	conn, active, err := connectAny(m.uris, m.auth)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lastAttempt = time.Now()
+	if err != nil {
+		slog.Error("Failed to reconnect to libvirt", "err", err)
+		m.lastErr = err
+		m.failureCount++
+		return false
+	}
+
+	m.conn = conn
+	m.lastErr = nil
+	m.connectedAt = m.lastAttempt
+	m.reconnectCount++
+	m.onConnect(active)
+	return true
+}
+
+// Describe sends the descriptors of the connection metrics
+func (m *ConnectionManager) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.reconnects
+	ch <- m.reconnectFailures
+	ch <- m.lastConnect
+}
+
+// Collect sends the connection metrics
+func (m *ConnectionManager) Collect(ch chan<- prometheus.Metric) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	ch <- prometheus.MustNewConstMetric(
+		m.reconnects,
+		prometheus.CounterValue,
+		float64(m.reconnectCount),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		m.reconnectFailures,
+		prometheus.CounterValue,
+		float64(m.failureCount),
+	)
+	// Not sent before the first successful connection
+	if !m.connectedAt.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			m.lastConnect,
+			prometheus.GaugeValue,
+			float64(m.connectedAt.Unix()),
+		)
+	}
+}
+
+// Close stops maintaining the connection and closes it
+func (m *ConnectionManager) Close() {
+	close(m.stop)
+	<-m.done
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.conn != nil {
+		slog.Info("Closing libvirt connection")
+		m.conn.Close()
+		m.conn = nil
+		slog.Info("Libvirt connection closed")
+	}
+}
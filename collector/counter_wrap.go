@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Counter wrap handling modes
+const (
+	// CounterWrapDetect only counts suspected 32-bit wraps
+	CounterWrapDetect = "detect"
+	// CounterWrapCorrect counts wraps and adds 2^32 to the reported value
+	CounterWrapCorrect = "correct"
+)
+
+const (
+	counterWrap32 = uint64(1) << 32
+	// A value must have been in the upper half of the 32-bit range before
+	// dropping for the drop to be treated as a wrap rather than a reset
+	counterWrapThreshold = uint64(1) << 31
+)
+
+// CounterTracker detects 32-bit wraps of counters returned by drivers that do
+// not report 64-bit InterfaceStats/BlockStats values
+type CounterTracker struct {
+	correct    bool
+	onWrap     func(subsystem string)
+	mutex      sync.Mutex
+	series     map[string]*trackedCounter
+	generation uint64
+}
+
+// trackedCounter holds the state of a single counter series
+type trackedCounter struct {
+	last       uint64
+	offset     uint64
+	generation uint64
+}
+
+// NewCounterTracker creates a new CounterTracker. onWrap is called for every
+// detected wrap and may be nil.
+func NewCounterTracker(mode string, onWrap func(subsystem string)) (*CounterTracker, error) {
+	switch mode {
+	case "", CounterWrapDetect, CounterWrapCorrect:
+	default:
+		return nil, fmt.Errorf("unknown counter wrap mode %q", mode)
+	}
+
+	return &CounterTracker{
+		correct: mode == CounterWrapCorrect,
+		onWrap:  onWrap,
+		series:  make(map[string]*trackedCounter),
+	}, nil
+}
+
+// Observe records a raw counter value and returns the value to report
+func (t *CounterTracker) Observe(subsystem, key string, value uint64) uint64 {
+	if t == nil {
+		return value
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	counter, ok := t.series[key]
+	if !ok {
+		t.series[key] = &trackedCounter{last: value, generation: t.generation}
+		return value
+	}
+
+	if value < counter.last {
+		if counter.last < counterWrap32 && counter.last >= counterWrapThreshold && value < counterWrap32 {
+			// Looks like a 32-bit counter wrapped around
+			if t.correct {
+				counter.offset += counterWrap32
+			}
+			if t.onWrap != nil {
+				t.onWrap(subsystem)
+			}
+		} else {
+			// Genuine reset (e.g. domain restarted)
+			counter.offset = 0
+		}
+	}
+	counter.last = value
+	counter.generation = t.generation
+
+	return value + counter.offset
+}
+
+// Prune forgets series that were not observed since the previous call
+func (t *CounterTracker) Prune() {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for key, counter := range t.series {
+		if counter.generation < t.generation {
+			delete(t.series, key)
+		}
+	}
+	t.generation++
+}
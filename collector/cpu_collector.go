@@ -1,7 +1,9 @@
 package collector
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"libvirt.org/go/libvirt"
@@ -15,11 +17,20 @@ type CPUCollector struct {
 	vmUserTime       *prometheus.Desc
 	vmSystemTime     *prometheus.Desc
 	vmStealTime      *prometheus.Desc
+	vmWaitTime       *prometheus.Desc
+	vmVcpuQuota      *prometheus.Desc
+	vmVcpuPeriod     *prometheus.Desc
+	vmCPUShares      *prometheus.Desc
+	vmEmulatorQuota  *prometheus.Desc
+	vmVcpuPinned     *prometheus.Desc
+	vmVcpuAffinity   *prometheus.Desc
+	vmVcpuTime       *prometheus.Desc
+	vmVcpuState      *prometheus.Desc
 	metricsCollector MetricsCollector
 }
 
 // NewCPUCollector creates a new CPUCollector
-func NewCPUCollector() *CPUCollector {
+func NewCPUCollector(metricsCollector MetricsCollector) *CPUCollector {
 	return &CPUCollector{
 		vmVcpuMax: prometheus.NewDesc(
 			"libvirt_vm_vcpu_max",
@@ -57,7 +68,61 @@ func NewCPUCollector() *CPUCollector {
 			[]string{"domain", "uuid"},
 			nil,
 		),
-		metricsCollector: NewLibvirtMetricsCollector(),
+		vmWaitTime: prometheus.NewDesc(
+			"libvirt_vm_cpu_wait_time_nanoseconds",
+			"vCPU time spent waiting for host I/O in nanoseconds",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmVcpuQuota: prometheus.NewDesc(
+			"libvirt_vm_cpu_vcpu_quota_microseconds",
+			"CPU time each vCPU may use per period in microseconds (-1 = unlimited)",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmVcpuPeriod: prometheus.NewDesc(
+			"libvirt_vm_cpu_vcpu_period_microseconds",
+			"Enforcement period of the vCPU quota in microseconds",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmCPUShares: prometheus.NewDesc(
+			"libvirt_vm_cpu_shares",
+			"Relative CPU weight of the virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmEmulatorQuota: prometheus.NewDesc(
+			"libvirt_vm_cpu_emulator_quota_microseconds",
+			"CPU time the emulator threads may use per period in microseconds (-1 = unlimited)",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmVcpuTime: prometheus.NewDesc(
+			"libvirt_vm_vcpu_time_seconds_total",
+			"CPU time used by a single vCPU in seconds",
+			[]string{"domain", "uuid", "vcpu"},
+			nil,
+		),
+		vmVcpuState: prometheus.NewDesc(
+			"libvirt_vm_vcpu_state",
+			"Current state of a vCPU (1 for the state in the state label)",
+			[]string{"domain", "uuid", "vcpu", "state"},
+			nil,
+		),
+		vmVcpuPinned: prometheus.NewDesc(
+			"libvirt_vm_vcpu_pinned",
+			"Host CPU a pinned vCPU may run on, value is always 1. Unpinned vCPUs are omitted",
+			[]string{"domain", "uuid", "vcpu", "cpu"},
+			nil,
+		),
+		vmVcpuAffinity: prometheus.NewDesc(
+			"libvirt_vm_vcpu_affinity_cpus",
+			"Number of host CPUs a vCPU may run on",
+			[]string{"domain", "uuid", "vcpu"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
 	}
 }
 
@@ -69,10 +134,20 @@ func (c *CPUCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.vmUserTime
 	ch <- c.vmSystemTime
 	ch <- c.vmStealTime
+	ch <- c.vmWaitTime
+	ch <- c.vmVcpuQuota
+	ch <- c.vmVcpuPeriod
+	ch <- c.vmCPUShares
+	ch <- c.vmEmulatorQuota
+	ch <- c.vmVcpuTime
+	ch <- c.vmVcpuState
+	ch <- c.vmVcpuPinned
+	ch <- c.vmVcpuAffinity
 }
 
 // Collect implements the Collector interface for CPUCollector
 func (c *CPUCollector) Collect(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
@@ -80,7 +155,7 @@ func (c *CPUCollector) Collect(
 	// Get domain info first to check if it's running
 	domainInfo, err := domain.GetInfo()
 	if err != nil {
-		log.Printf("Warning: Failed to get domain info for CPU metrics: %v", err)
+		slog.Warn("Failed to get domain info for CPU metrics", "err", err)
 		return
 	}
 
@@ -90,7 +165,7 @@ func (c *CPUCollector) Collect(
 		return
 	}
 
-	metrics, err := c.metricsCollector.CollectCPUStats(conn, domain)
+	metrics, err := c.metricsCollector.CollectCPUStats(ctx, conn, domain)
 	if err != nil {
 		// Check if this is because domain is not running (expected for some operations)
 		if lverr, ok := err.(libvirt.Error); ok && lverr.Code == libvirt.ERR_OPERATION_INVALID {
@@ -99,7 +174,7 @@ func (c *CPUCollector) Collect(
 		}
 		// For other errors, log with more context
 		domainName, _ := domain.GetName()
-		log.Printf("Warning: Failed to collect CPU metrics for domain '%s': %v", domainName, err)
+		slog.Warn("Failed to collect CPU metrics", "domain", domainName, "err", err)
 		return
 	}
 
@@ -157,9 +232,113 @@ func (c *CPUCollector) Collect(
 			metrics.UUID,
 		)
 	}
+
+	if metrics.WaitTime > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmWaitTime,
+			prometheus.CounterValue,
+			float64(metrics.WaitTime),
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
+
+	// Scheduler settings depend on the driver
+	if metrics.HasQuota {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmVcpuQuota,
+			prometheus.GaugeValue,
+			float64(metrics.Quota),
+			metrics.Name,
+			metrics.UUID,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmVcpuPeriod,
+			prometheus.GaugeValue,
+			float64(metrics.Period),
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
+
+	if metrics.HasShares {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmCPUShares,
+			prometheus.GaugeValue,
+			float64(metrics.Shares),
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
+
+	if metrics.HasEmulator {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmEmulatorQuota,
+			prometheus.GaugeValue,
+			float64(metrics.EmulatorQuota),
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
+
+	for _, vcpu := range metrics.VCPUs {
+		vcpuNumber := strconv.FormatUint(uint64(vcpu.Number), 10)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmVcpuTime,
+			prometheus.CounterValue,
+			float64(vcpu.CPUTime)/1e9,
+			metrics.Name,
+			metrics.UUID,
+			vcpuNumber,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmVcpuState,
+			prometheus.GaugeValue,
+			1,
+			metrics.Name,
+			metrics.UUID,
+			vcpuNumber,
+			vcpu.State,
+		)
+
+		if vcpu.CPUs == nil {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmVcpuAffinity,
+			prometheus.GaugeValue,
+			float64(len(vcpu.CPUs)),
+			metrics.Name,
+			metrics.UUID,
+			vcpuNumber,
+		)
+
+		// Unpinned vCPUs would export one series per host CPU
+		if !vcpu.Pinned {
+			continue
+		}
+		for _, cpu := range vcpu.CPUs {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmVcpuPinned,
+				prometheus.GaugeValue,
+				1,
+				metrics.Name,
+				metrics.UUID,
+				vcpuNumber,
+				strconv.Itoa(cpu),
+			)
+		}
+	}
 }
 
 // Reset implements the Collector interface
 func (c *CPUCollector) Reset() {
 	// No internal state to reset
 }
+
+// retainsCounters marks the counters of CPUCollector as retained after the domain stops
+func (c *CPUCollector) retainsCounters() {}
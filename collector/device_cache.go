@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"libvirt.org/go/libvirtxml"
+)
+
+// BlockDeviceCache keeps the block devices discovered from the XML of each
+// domain so repeated scrapes skip parsing an unchanged definition. libvirt
+// has no generation number for domain definitions, so a hash of the XML
+// description serves as one; an entry is used while the generation matches
+// and its TTL has not expired.
+type BlockDeviceCache struct {
+	ttl     time.Duration
+	onHit   func()
+	onMiss  func()
+	mutex   sync.Mutex
+	entries map[string]*blockDeviceEntry // keyed by domain UUID
+}
+
+// blockDeviceEntry holds the block devices of one domain definition
+type blockDeviceEntry struct {
+	generation uint64
+	devices    []string
+	configs    map[string]*libvirtxml.DomainDisk
+	expires    time.Time
+}
+
+// NewBlockDeviceCache creates a new BlockDeviceCache. onHit and onMiss are
+// called on every lookup and may be nil.
+func NewBlockDeviceCache(ttl time.Duration, onHit, onMiss func()) *BlockDeviceCache {
+	return &BlockDeviceCache{
+		ttl:     ttl,
+		onHit:   onHit,
+		onMiss:  onMiss,
+		entries: make(map[string]*blockDeviceEntry),
+	}
+}
+
+// xmlGeneration returns the generation of a domain XML description
+func xmlGeneration(xmlDesc string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(xmlDesc))
+	return hash.Sum64()
+}
+
+// Get returns the cached block devices of a domain definition. The returned
+// values are shared and must not be modified.
+func (c *BlockDeviceCache) Get(
+	uuid string,
+	generation uint64,
+) ([]string, map[string]*libvirtxml.DomainDisk, bool) {
+	c.mutex.Lock()
+	entry, ok := c.entries[uuid]
+	if ok && (entry.generation != generation || time.Now().After(entry.expires)) {
+		delete(c.entries, uuid)
+		ok = false
+	}
+	c.mutex.Unlock()
+
+	if !ok {
+		if c.onMiss != nil {
+			c.onMiss()
+		}
+		return nil, nil, false
+	}
+
+	if c.onHit != nil {
+		c.onHit()
+	}
+	return entry.devices, entry.configs, true
+}
+
+// Put stores the block devices of a domain definition and drops expired
+// entries, such as those of undefined domains
+func (c *BlockDeviceCache) Put(
+	uuid string,
+	generation uint64,
+	devices []string,
+	configs map[string]*libvirtxml.DomainDisk,
+) {
+	now := time.Now()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+
+	c.entries[uuid] = &blockDeviceEntry{
+		generation: generation,
+		devices:    devices,
+		configs:    configs,
+		expires:    now.Add(c.ttl),
+	}
+}
@@ -1,7 +1,9 @@
 package collector
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"libvirt.org/go/libvirt"
@@ -11,12 +13,23 @@ import (
 type DeviceCollector struct {
 	vmHasTPM         *prometheus.Desc
 	vmHasRNG         *prometheus.Desc
-	vmSnapshotCount  *prometheus.Desc
+	vmHasVSock       *prometheus.Desc
+	vmVSockCID       *prometheus.Desc
+	vmShmemCount     *prometheus.Desc
+	vmShmemSize      *prometheus.Desc
+	vmPCIDevice      *prometheus.Desc
+	vmUSBDevice      *prometheus.Desc
+	vmVGPUDevice     *prometheus.Desc
+	vmWatchdogCount  *prometheus.Desc
+	vmRNGCount       *prometheus.Desc
+	vmChardevCount   *prometheus.Desc
+	vmVideoVRAM      *prometheus.Desc
+	vmInputCount     *prometheus.Desc
 	metricsCollector MetricsCollector
 }
 
 // NewDeviceCollector creates a new DeviceCollector
-func NewDeviceCollector() *DeviceCollector {
+func NewDeviceCollector(metricsCollector MetricsCollector) *DeviceCollector {
 	return &DeviceCollector{
 		vmHasTPM: prometheus.NewDesc(
 			"libvirt_vm_has_tpm",
@@ -30,13 +43,79 @@ func NewDeviceCollector() *DeviceCollector {
 			[]string{"domain", "uuid"},
 			nil,
 		),
-		vmSnapshotCount: prometheus.NewDesc(
-			"libvirt_vm_snapshot_count",
-			"Number of snapshots for the virtual machine",
+		vmHasVSock: prometheus.NewDesc(
+			"libvirt_vm_has_vsock",
+			"Whether the virtual machine has a vsock device",
 			[]string{"domain", "uuid"},
 			nil,
 		),
-		metricsCollector: NewLibvirtMetricsCollector(),
+		vmVSockCID: prometheus.NewDesc(
+			"libvirt_vm_vsock_cid",
+			"Guest context ID of the vsock device (0 if not assigned yet)",
+			[]string{"domain", "uuid", "auto"},
+			nil,
+		),
+		vmShmemCount: prometheus.NewDesc(
+			"libvirt_vm_shmem_devices",
+			"Number of shared memory (ivshmem) devices attached to the virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmShmemSize: prometheus.NewDesc(
+			"libvirt_vm_shmem_size_bytes",
+			"Size of a shared memory (ivshmem) device in bytes",
+			[]string{"domain", "uuid", "name", "model"},
+			nil,
+		),
+		vmPCIDevice: prometheus.NewDesc(
+			"libvirt_vm_pci_device_info",
+			"PCI passthrough device attached to the virtual machine",
+			[]string{"domain", "uuid", "address", "type", "driver"},
+			nil,
+		),
+		vmUSBDevice: prometheus.NewDesc(
+			"libvirt_vm_usb_device_info",
+			"USB passthrough device attached to the virtual machine",
+			[]string{"domain", "uuid", "bus", "device", "vendor", "product"},
+			nil,
+		),
+		vmVGPUDevice: prometheus.NewDesc(
+			"libvirt_vm_vgpu_device_info",
+			"Mediated device (vGPU) attached to the virtual machine",
+			[]string{"domain", "uuid", "mdev_uuid", "model"},
+			nil,
+		),
+		vmWatchdogCount: prometheus.NewDesc(
+			"libvirt_vm_watchdog_devices",
+			"Number of watchdog devices of the virtual machine by model and action",
+			[]string{"domain", "uuid", "model", "action"},
+			nil,
+		),
+		vmRNGCount: prometheus.NewDesc(
+			"libvirt_vm_rng_devices",
+			"Number of random number generator devices of the virtual machine by model and backend",
+			[]string{"domain", "uuid", "model", "backend"},
+			nil,
+		),
+		vmChardevCount: prometheus.NewDesc(
+			"libvirt_vm_chardev_devices",
+			"Number of serial ports, consoles and channels of the virtual machine by kind and source type",
+			[]string{"domain", "uuid", "kind", "type"},
+			nil,
+		),
+		vmVideoVRAM: prometheus.NewDesc(
+			"libvirt_vm_video_vram_bytes",
+			"Video memory of a video device of the virtual machine in bytes (0 if not configured)",
+			[]string{"domain", "uuid", "index", "model", "primary"},
+			nil,
+		),
+		vmInputCount: prometheus.NewDesc(
+			"libvirt_vm_input_devices",
+			"Number of input devices of the virtual machine by type and bus",
+			[]string{"domain", "uuid", "type", "bus"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
 	}
 }
 
@@ -44,19 +123,31 @@ func NewDeviceCollector() *DeviceCollector {
 func (c *DeviceCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.vmHasTPM
 	ch <- c.vmHasRNG
-	ch <- c.vmSnapshotCount
+	ch <- c.vmHasVSock
+	ch <- c.vmVSockCID
+	ch <- c.vmShmemCount
+	ch <- c.vmShmemSize
+	ch <- c.vmPCIDevice
+	ch <- c.vmUSBDevice
+	ch <- c.vmVGPUDevice
+	ch <- c.vmWatchdogCount
+	ch <- c.vmRNGCount
+	ch <- c.vmChardevCount
+	ch <- c.vmVideoVRAM
+	ch <- c.vmInputCount
 }
 
 // Collect implements the Collector interface for DeviceCollector
 func (c *DeviceCollector) Collect(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) {
 	// Collect device stats
-	deviceMetrics, err := c.metricsCollector.CollectDeviceStats(conn, domain)
+	deviceMetrics, err := c.metricsCollector.CollectDeviceStats(ctx, conn, domain)
 	if err != nil {
-		log.Printf("Failed to collect device metrics: %v", err)
+		slog.Warn("Failed to collect device metrics", "err", err)
 	} else {
 		var tpmValue float64
 		if deviceMetrics.HasTPM {
@@ -83,19 +174,155 @@ func (c *DeviceCollector) Collect(
 			deviceMetrics.Name,
 			deviceMetrics.UUID,
 		)
+
+		var vsockValue float64
+		if deviceMetrics.VSock != nil {
+			vsockValue = 1.0
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmHasVSock,
+			prometheus.GaugeValue,
+			vsockValue,
+			deviceMetrics.Name,
+			deviceMetrics.UUID,
+		)
+
+		if deviceMetrics.VSock != nil {
+			auto := "no"
+			if deviceMetrics.VSock.AutoCID {
+				auto = "yes"
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				c.vmVSockCID,
+				prometheus.GaugeValue,
+				float64(deviceMetrics.VSock.CID),
+				deviceMetrics.Name,
+				deviceMetrics.UUID,
+				auto,
+			)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmShmemCount,
+			prometheus.GaugeValue,
+			float64(len(deviceMetrics.Shmems)),
+			deviceMetrics.Name,
+			deviceMetrics.UUID,
+		)
+
+		for _, shmem := range deviceMetrics.Shmems {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmShmemSize,
+				prometheus.GaugeValue,
+				float64(shmem.SizeBytes),
+				deviceMetrics.Name,
+				deviceMetrics.UUID,
+				shmem.Name,
+				shmem.Model,
+			)
+		}
+
+		for _, pci := range deviceMetrics.PCIDevices {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmPCIDevice,
+				prometheus.GaugeValue,
+				1,
+				deviceMetrics.Name,
+				deviceMetrics.UUID,
+				pci.Address,
+				pci.Type,
+				pci.Driver,
+			)
+		}
+
+		for _, usb := range deviceMetrics.USBDevices {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmUSBDevice,
+				prometheus.GaugeValue,
+				1,
+				deviceMetrics.Name,
+				deviceMetrics.UUID,
+				strconv.Itoa(usb.Bus),
+				strconv.Itoa(usb.Device),
+				usb.Vendor,
+				usb.Product,
+			)
+		}
+
+		for _, vgpu := range deviceMetrics.VGPUDevices {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmVGPUDevice,
+				prometheus.GaugeValue,
+				1,
+				deviceMetrics.Name,
+				deviceMetrics.UUID,
+				vgpu.MdevUUID,
+				vgpu.Model,
+			)
+		}
+
+		watchdogs := make(map[[2]string]int)
+		for _, watchdog := range deviceMetrics.Watchdogs {
+			watchdogs[[2]string{watchdog.Model, watchdog.Action}]++
+		}
+		c.sendDeviceCounts(ch, c.vmWatchdogCount, deviceMetrics, watchdogs)
+
+		rngs := make(map[[2]string]int)
+		for _, rng := range deviceMetrics.RNGs {
+			rngs[[2]string{rng.Model, rng.Backend}]++
+		}
+		c.sendDeviceCounts(ch, c.vmRNGCount, deviceMetrics, rngs)
+
+		chardevs := make(map[[2]string]int)
+		for _, chardev := range deviceMetrics.CharDevices {
+			chardevs[[2]string{chardev.Kind, chardev.Type}]++
+		}
+		c.sendDeviceCounts(ch, c.vmChardevCount, deviceMetrics, chardevs)
+
+		for i, video := range deviceMetrics.Videos {
+			primary := "no"
+			if video.Primary {
+				primary = "yes"
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				c.vmVideoVRAM,
+				prometheus.GaugeValue,
+				float64(video.VRAMBytes),
+				deviceMetrics.Name,
+				deviceMetrics.UUID,
+				strconv.Itoa(i),
+				video.Model,
+				primary,
+			)
+		}
+
+		inputs := make(map[[2]string]int)
+		for _, input := range deviceMetrics.Inputs {
+			inputs[[2]string{input.Type, input.Bus}]++
+		}
+		c.sendDeviceCounts(ch, c.vmInputCount, deviceMetrics, inputs)
 	}
+}
 
-	// Collect snapshot stats
-	snapshotMetrics, err := c.metricsCollector.CollectSnapshotStats(conn, domain)
-	if err != nil {
-		log.Printf("Failed to collect snapshot metrics: %v", err)
-	} else {
+// sendDeviceCounts sends the number of devices of each pair of label values
+func (c *DeviceCollector) sendDeviceCounts(
+	ch chan<- prometheus.Metric,
+	desc *prometheus.Desc,
+	deviceMetrics *DeviceMetrics,
+	counts map[[2]string]int,
+) {
+	for labels, count := range counts {
 		ch <- prometheus.MustNewConstMetric(
-			c.vmSnapshotCount,
+			desc,
 			prometheus.GaugeValue,
-			float64(snapshotMetrics.Count),
-			snapshotMetrics.Name,
-			snapshotMetrics.UUID,
+			float64(count),
+			deviceMetrics.Name,
+			deviceMetrics.UUID,
+			labels[0],
+			labels[1],
 		)
 	}
 }
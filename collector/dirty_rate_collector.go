@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// DirtyRateCollector collects the memory dirty rate of running domains, the
+// rate at which the guest writes to its memory, which bounds how fast a live
+// migration can converge. The rate is only known after a calculation, which
+// the collector can start periodically.
+type DirtyRateCollector struct {
+	vmDirtyRate      *prometheus.Desc
+	metricsCollector MetricsCollector
+
+	// Calculations are started at most once per interval for each domain
+	interval   time.Duration
+	period     time.Duration
+	mutex      sync.Mutex
+	started    map[string]*dirtyRateCalc // keyed by domain UUID
+	generation uint64
+}
+
+// dirtyRateCalc records when a dirty rate calculation was last started
+type dirtyRateCalc struct {
+	startedAt  time.Time
+	generation uint64
+}
+
+// NewDirtyRateCollector creates a new DirtyRateCollector. A calculation
+// lasting period is started for each running domain every interval; an
+// interval of 0 only reports calculations started by others (e.g. virsh
+// domdirtyrate-calc).
+func NewDirtyRateCollector(
+	metricsCollector MetricsCollector,
+	interval, period time.Duration,
+) *DirtyRateCollector {
+	return &DirtyRateCollector{
+		vmDirtyRate: prometheus.NewDesc(
+			"libvirt_vm_memory_dirty_rate_bytes_per_second",
+			"Rate at which the virtual machine dirtied its memory during the last calculation in bytes per second",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+		interval:         interval,
+		period:           period,
+		started:          make(map[string]*dirtyRateCalc),
+	}
+}
+
+// Describe implements the prometheus.Collector interface for DirtyRateCollector
+func (c *DirtyRateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmDirtyRate
+}
+
+// Collect implements the Collector interface for DirtyRateCollector
+func (c *DirtyRateCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+	metrics, err := c.metricsCollector.CollectDirtyRateStats(ctx, conn, domain)
+	if err != nil {
+		slog.Warn("Failed to collect dirty rate metrics", "err", err)
+		return
+	}
+	if !metrics.Running {
+		return
+	}
+
+	if metrics.Measured {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmDirtyRate,
+			prometheus.GaugeValue,
+			float64(metrics.BytesPerSecond),
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
+
+	// The result of a calculation started now is reported by a later scrape
+	if c.interval > 0 && !metrics.Measuring && c.due(metrics.UUID) {
+		seconds := int(c.period / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		if err := domain.StartDirtyRateCalc(seconds, 0); err != nil {
+			slog.Warn("Failed to start dirty rate calculation", "domain", metrics.Name, "err", err)
+		}
+	}
+}
+
+// due reports whether a calculation should be started for a domain and, if
+// so, records it as started
+func (c *DirtyRateCollector) due(uuid string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	calc, ok := c.started[uuid]
+	if !ok {
+		calc = &dirtyRateCalc{}
+		c.started[uuid] = calc
+	}
+	calc.generation = c.generation
+	if ok && time.Since(calc.startedAt) < c.interval {
+		return false
+	}
+	calc.startedAt = time.Now()
+	return true
+}
+
+// Reset implements the Collector interface
+func (c *DirtyRateCollector) Reset() {
+	// Cached domains are pruned after full scrapes only
+}
+
+// prune implements the pruner interface
+func (c *DirtyRateCollector) prune() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Drop domains that were not seen during the scrape
+	for uuid, calc := range c.started {
+		if calc.generation < c.generation {
+			delete(c.started, uuid)
+		}
+	}
+	c.generation++
+}
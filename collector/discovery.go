@@ -0,0 +1,197 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Host discovery record types
+const (
+	DiscoverySRV = "srv"
+	DiscoveryA   = "a"
+)
+
+// discoveryTimeout bounds a single DNS resolution
+const discoveryTimeout = 10 * time.Second
+
+// HostDiscovery resolves a DNS name into the libvirt URIs of the hypervisors
+// of a cluster: the targets of an SRV record or the addresses of an A/AAAA
+// record, each substituted into a URI template
+type HostDiscovery struct {
+	name     string
+	kind     string
+	template string
+	resolver *net.Resolver
+}
+
+// NewHostDiscovery creates a HostDiscovery resolving name as a record of the
+// given kind ("srv" or "a"). In template, "{host}" is replaced by each
+// resolved host and "{port}" by the port of SRV records.
+func NewHostDiscovery(name, kind, template string) (*HostDiscovery, error) {
+	switch kind {
+	case DiscoverySRV, DiscoveryA:
+	default:
+		return nil, fmt.Errorf("unknown discovery record type %q", kind)
+	}
+	if !strings.Contains(template, "{host}") {
+		return nil, fmt.Errorf("discovery URI template %q lacks {host}", template)
+	}
+	return &HostDiscovery{
+		name:     name,
+		kind:     kind,
+		template: template,
+		resolver: net.DefaultResolver,
+	}, nil
+}
+
+// Resolve returns the sorted libvirt URIs of the currently published hosts
+func (d *HostDiscovery) Resolve(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	var uris []string
+	switch d.kind {
+	case DiscoverySRV:
+		_, records, err := d.resolver.LookupSRV(ctx, "", "", d.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			host := strings.TrimSuffix(record.Target, ".")
+			uris = append(uris, d.uri(host, strconv.Itoa(int(record.Port))))
+		}
+	case DiscoveryA:
+		addresses, err := d.resolver.LookupHost(ctx, d.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range addresses {
+			// IPv6 addresses are bracketed in URIs
+			if strings.Contains(address, ":") {
+				address = "[" + address + "]"
+			}
+			uris = append(uris, d.uri(address, ""))
+		}
+	}
+
+	sort.Strings(uris)
+	return uris, nil
+}
+
+// uri substitutes a host and port into the URI template
+func (d *HostDiscovery) uri(host, port string) string {
+	uri := strings.ReplaceAll(d.template, "{host}", host)
+	return strings.ReplaceAll(uri, "{port}", port)
+}
+
+// hostDiscoveryLoop re-resolves the discovered hosts of a collector
+type hostDiscoveryLoop struct {
+	discovery *HostDiscovery
+	interval  time.Duration
+	stop      chan struct{}
+	done      sync.WaitGroup
+}
+
+// startDiscovery resolves the discovered hosts once and then every interval
+// in the background, adding and removing peers as hosts come and go
+func (c *LibvirtCollector) startDiscovery(discovery *HostDiscovery, interval time.Duration) {
+	loop := &hostDiscoveryLoop{
+		discovery: discovery,
+		interval:  interval,
+		stop:      make(chan struct{}),
+	}
+	c.discovery = loop
+	c.discoverHosts()
+
+	loop.done.Add(1)
+	go func() {
+		defer loop.done.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.discoverHosts()
+			case <-loop.stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopDiscovery stops re-resolving the discovered hosts
+func (c *LibvirtCollector) stopDiscovery() {
+	if c.discovery == nil {
+		return
+	}
+	close(c.discovery.stop)
+	c.discovery.done.Wait()
+}
+
+// discoverHosts resolves the discovered hosts and reconciles the peers with
+// them. Statically configured hosts are kept; a failed resolution keeps the
+// current peers.
+func (c *LibvirtCollector) discoverHosts() {
+	uris, err := c.discovery.discovery.Resolve(context.Background())
+	if err != nil {
+		slog.Warn("Failed to resolve discovered libvirt hosts",
+			"name", c.discovery.discovery.name, "err", err)
+		return
+	}
+
+	c.hostsMutex.Lock()
+	defer c.hostsMutex.Unlock()
+
+	discovered := make(map[string]string, len(uris))
+	for _, uri := range uris {
+		discovered[hostLabel(uri)] = uri
+	}
+
+	// Drop discovered peers that are no longer published
+	peers := c.hosts[:0]
+	for _, peer := range c.hosts {
+		if peer.discovered && discovered[peer.host] == "" {
+			slog.Info("Removing libvirt host no longer discovered", "host", peer.host)
+			go closePeer(peer)
+			continue
+		}
+		peers = append(peers, peer)
+	}
+	c.hosts = peers
+
+	known := map[string]bool{c.host: true}
+	for _, peer := range c.hosts {
+		known[peer.host] = true
+	}
+	for _, uri := range uris {
+		host := hostLabel(uri)
+		if known[host] {
+			continue
+		}
+		known[host] = true
+
+		slog.Info("Adding discovered libvirt host", "host", host, "uri", uri)
+		peer, err := NewLibvirtCollector(uri, c.peerOptions())
+		if err != nil {
+			slog.Warn("Failed to add discovered libvirt host", "host", host, "err", err)
+			continue
+		}
+		peer.host = host
+		peer.discovered = true
+		c.hosts = append(c.hosts, peer)
+	}
+}
+
+// closePeer closes a removed peer once a scrape still running on it is done
+func closePeer(peer *LibvirtCollector) {
+	peer.mutex.Lock()
+	defer peer.mutex.Unlock()
+	peer.Close()
+}
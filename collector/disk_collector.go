@@ -1,7 +1,8 @@
 package collector
 
 import (
-	"log"
+	"context"
+	"log/slog"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"libvirt.org/go/libvirt"
@@ -15,11 +16,24 @@ type DiskCollector struct {
 	vmDiskWriteOps   *prometheus.Desc
 	vmDiskReadTime   *prometheus.Desc
 	vmDiskWriteTime  *prometheus.Desc
+	vmDiskFlushOps   *prometheus.Desc
+	vmDiskFlushTime  *prometheus.Desc
+	vmDiskCapacity   *prometheus.Desc
+	vmDiskAllocation *prometheus.Desc
+	vmDiskPhysical   *prometheus.Desc
+	vmDiskEncrypted  *prometheus.Desc
+	vmDiskInfo       *prometheus.Desc
+	vmBlockJobProg   *prometheus.Desc
+	vmBlockJobBW     *prometheus.Desc
+	vmBlockJobCursor *prometheus.Desc
+	vmBlockJobEnd    *prometheus.Desc
+	vmDiskThrottleBW *prometheus.Desc
+	vmDiskThrottleIO *prometheus.Desc
 	metricsCollector MetricsCollector
 }
 
 // NewDiskCollector creates a new DiskCollector
-func NewDiskCollector() *DiskCollector {
+func NewDiskCollector(metricsCollector MetricsCollector) *DiskCollector {
 	return &DiskCollector{
 		vmDiskReadBytes: prometheus.NewDesc(
 			"libvirt_vm_disk_read_bytes_total",
@@ -57,7 +71,85 @@ func NewDiskCollector() *DiskCollector {
 			[]string{"domain", "uuid", "device"},
 			nil,
 		),
-		metricsCollector: NewLibvirtMetricsCollector(),
+		vmDiskFlushOps: prometheus.NewDesc(
+			"libvirt_vm_disk_flush_ops_total",
+			"Total disk flush operations by the virtual machine",
+			[]string{"domain", "uuid", "device"},
+			nil,
+		),
+		vmDiskFlushTime: prometheus.NewDesc(
+			"libvirt_vm_disk_flush_time_seconds_total",
+			"Total time spent flushing disk caches by the virtual machine",
+			[]string{"domain", "uuid", "device"},
+			nil,
+		),
+		vmDiskCapacity: prometheus.NewDesc(
+			"libvirt_vm_disk_capacity_bytes",
+			"Virtual size of the virtual machine disk as seen by the guest",
+			[]string{"domain", "uuid", "device"},
+			nil,
+		),
+		vmDiskAllocation: prometheus.NewDesc(
+			"libvirt_vm_disk_allocation_bytes",
+			"Host storage allocated to the virtual machine disk, the highest written offset for block devices",
+			[]string{"domain", "uuid", "device"},
+			nil,
+		),
+		vmDiskPhysical: prometheus.NewDesc(
+			"libvirt_vm_disk_physical_bytes",
+			"Physical size of the host file or block device backing the virtual machine disk",
+			[]string{"domain", "uuid", "device"},
+			nil,
+		),
+		vmDiskEncrypted: prometheus.NewDesc(
+			"libvirt_vm_disk_encrypted",
+			"Whether the virtual machine disk has encryption configured (1=encrypted, 0=not encrypted)",
+			[]string{"domain", "uuid", "device", "format"},
+			nil,
+		),
+		vmDiskInfo: prometheus.NewDesc(
+			"libvirt_vm_disk_info",
+			"Configuration of the virtual machine disk, value is always 1",
+			[]string{"domain", "uuid", "device", "source", "format", "bus", "cache", "io", "discard"},
+			nil,
+		),
+		vmBlockJobProg: prometheus.NewDesc(
+			"libvirt_vm_disk_block_job_progress_ratio",
+			"Progress of the block job running on the virtual machine disk (0-1)",
+			[]string{"domain", "uuid", "device", "type"},
+			nil,
+		),
+		vmBlockJobBW: prometheus.NewDesc(
+			"libvirt_vm_disk_block_job_bandwidth_bytes_per_second",
+			"Bandwidth limit of the block job running on the virtual machine disk (0=unlimited)",
+			[]string{"domain", "uuid", "device", "type"},
+			nil,
+		),
+		vmBlockJobCursor: prometheus.NewDesc(
+			"libvirt_vm_disk_block_job_cursor",
+			"Current position of the block job running on the virtual machine disk",
+			[]string{"domain", "uuid", "device", "type"},
+			nil,
+		),
+		vmBlockJobEnd: prometheus.NewDesc(
+			"libvirt_vm_disk_block_job_end",
+			"Position the block job running on the virtual machine disk reaches when done",
+			[]string{"domain", "uuid", "device", "type"},
+			nil,
+		),
+		vmDiskThrottleBW: prometheus.NewDesc(
+			"libvirt_vm_disk_throttle_bytes_per_second",
+			"Configured throughput limit of the virtual machine disk, unlimited directions are omitted",
+			[]string{"domain", "uuid", "device", "direction"},
+			nil,
+		),
+		vmDiskThrottleIO: prometheus.NewDesc(
+			"libvirt_vm_disk_throttle_iops",
+			"Configured I/O operations per second limit of the virtual machine disk, unlimited directions are omitted",
+			[]string{"domain", "uuid", "device", "direction"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
 	}
 }
 
@@ -69,10 +161,24 @@ func (c *DiskCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.vmDiskWriteOps
 	ch <- c.vmDiskReadTime
 	ch <- c.vmDiskWriteTime
+	ch <- c.vmDiskFlushOps
+	ch <- c.vmDiskFlushTime
+	ch <- c.vmDiskCapacity
+	ch <- c.vmDiskAllocation
+	ch <- c.vmDiskPhysical
+	ch <- c.vmDiskEncrypted
+	ch <- c.vmDiskInfo
+	ch <- c.vmBlockJobProg
+	ch <- c.vmBlockJobBW
+	ch <- c.vmBlockJobCursor
+	ch <- c.vmBlockJobEnd
+	ch <- c.vmDiskThrottleBW
+	ch <- c.vmDiskThrottleIO
 }
 
 // Collect implements the Collector interface for DiskCollector
 func (c *DiskCollector) Collect(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
@@ -80,7 +186,7 @@ func (c *DiskCollector) Collect(
 	// Get domain info first to check if it's running
 	domainInfo, err := domain.GetInfo()
 	if err != nil {
-		log.Printf("Warning: Failed to get domain info for disk metrics: %v", err)
+		slog.Warn("Failed to get domain info for disk metrics", "err", err)
 		return
 	}
 
@@ -90,7 +196,7 @@ func (c *DiskCollector) Collect(
 		return
 	}
 
-	metricsList, err := c.metricsCollector.CollectDiskStats(conn, domain)
+	metricsList, err := c.metricsCollector.CollectDiskStats(ctx, conn, domain)
 	if err != nil {
 		// Check if this is because domain is not running (expected for some operations)
 		if lverr, ok := err.(libvirt.Error); ok && lverr.Code == libvirt.ERR_OPERATION_INVALID {
@@ -99,7 +205,7 @@ func (c *DiskCollector) Collect(
 		}
 		// For other errors, log with more context
 		domainName, _ := domain.GetName()
-		log.Printf("Warning: Failed to collect disk metrics for domain '%s': %v", domainName, err)
+		slog.Warn("Failed to collect disk metrics", "domain", domainName, "err", err)
 		return
 	}
 
@@ -160,10 +266,188 @@ func (c *DiskCollector) Collect(
 				metrics.Device,
 			)
 		}
+
+		// Not all drivers report flush statistics
+		if metrics.FlushOps > 0 || metrics.FlushTimeNs > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmDiskFlushOps,
+				prometheus.CounterValue,
+				float64(metrics.FlushOps),
+				metrics.Name,
+				metrics.UUID,
+				metrics.Device,
+			)
+
+			ch <- prometheus.MustNewConstMetric(
+				c.vmDiskFlushTime,
+				prometheus.CounterValue,
+				float64(metrics.FlushTimeNs)/1e9,
+				metrics.Name,
+				metrics.UUID,
+				metrics.Device,
+			)
+		}
+
+		// Only expose sizes if they are available
+		if metrics.Capacity > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmDiskCapacity,
+				prometheus.GaugeValue,
+				float64(metrics.Capacity),
+				metrics.Name,
+				metrics.UUID,
+				metrics.Device,
+			)
+
+			ch <- prometheus.MustNewConstMetric(
+				c.vmDiskAllocation,
+				prometheus.GaugeValue,
+				float64(metrics.Allocation),
+				metrics.Name,
+				metrics.UUID,
+				metrics.Device,
+			)
+
+			ch <- prometheus.MustNewConstMetric(
+				c.vmDiskPhysical,
+				prometheus.GaugeValue,
+				float64(metrics.Physical),
+				metrics.Name,
+				metrics.UUID,
+				metrics.Device,
+			)
+		}
+
+		var encryptedValue float64
+		if metrics.Encrypted {
+			encryptedValue = 1.0
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmDiskEncrypted,
+			prometheus.GaugeValue,
+			encryptedValue,
+			metrics.Name,
+			metrics.UUID,
+			metrics.Device,
+			metrics.EncryptionFormat,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmDiskInfo,
+			prometheus.GaugeValue,
+			1.0,
+			metrics.Name,
+			metrics.UUID,
+			metrics.Device,
+			metrics.Source,
+			metrics.Format,
+			metrics.Bus,
+			valueOrDefault(metrics.CacheMode),
+			valueOrDefault(metrics.IOMode),
+			valueOrDefault(metrics.Discard),
+		)
+
+		if metrics.BlockJob != nil {
+			c.collectBlockJob(ch, &metrics)
+		}
+
+		if metrics.IOTune != nil {
+			c.collectIOTune(ch, &metrics)
+		}
+	}
+}
+
+// collectBlockJob collects the metrics of the block job running on a disk
+func (c *DiskCollector) collectBlockJob(ch chan<- prometheus.Metric, metrics *DiskMetrics) {
+	job := metrics.BlockJob
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmBlockJobProg,
+		prometheus.GaugeValue,
+		job.Progress,
+		metrics.Name,
+		metrics.UUID,
+		metrics.Device,
+		job.Type,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmBlockJobBW,
+		prometheus.GaugeValue,
+		float64(job.Bandwidth),
+		metrics.Name,
+		metrics.UUID,
+		metrics.Device,
+		job.Type,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmBlockJobCursor,
+		prometheus.GaugeValue,
+		float64(job.Cursor),
+		metrics.Name,
+		metrics.UUID,
+		metrics.Device,
+		job.Type,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmBlockJobEnd,
+		prometheus.GaugeValue,
+		float64(job.End),
+		metrics.Name,
+		metrics.UUID,
+		metrics.Device,
+		job.Type,
+	)
+}
+
+// collectIOTune collects the throttle limits of a disk
+func (c *DiskCollector) collectIOTune(ch chan<- prometheus.Metric, metrics *DiskMetrics) {
+	iotune := metrics.IOTune
+
+	limits := []struct {
+		desc      *prometheus.Desc
+		direction string
+		value     uint64
+	}{
+		{c.vmDiskThrottleBW, "total", iotune.TotalBytesSec},
+		{c.vmDiskThrottleBW, "read", iotune.ReadBytesSec},
+		{c.vmDiskThrottleBW, "write", iotune.WriteBytesSec},
+		{c.vmDiskThrottleIO, "total", iotune.TotalIOPSSec},
+		{c.vmDiskThrottleIO, "read", iotune.ReadIOPSSec},
+		{c.vmDiskThrottleIO, "write", iotune.WriteIOPSSec},
 	}
+
+	for _, limit := range limits {
+		if limit.value == 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			limit.desc,
+			prometheus.GaugeValue,
+			float64(limit.value),
+			metrics.Name,
+			metrics.UUID,
+			metrics.Device,
+			limit.direction,
+		)
+	}
+}
+
+// valueOrDefault returns "default" for settings left to the hypervisor default
+func valueOrDefault(value string) string {
+	if value == "" {
+		return "default"
+	}
+	return value
 }
 
 // Reset implements the Collector interface
 func (c *DiskCollector) Reset() {
 	// No internal state to reset
 }
+
+// retainsCounters marks the counters of DiskCollector as retained after the domain stops
+func (c *DiskCollector) retainsCounters() {}
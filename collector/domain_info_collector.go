@@ -1,7 +1,9 @@
 package collector
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"libvirt.org/go/libvirt"
@@ -10,6 +12,8 @@ import (
 // DomainInfoCollector collects basic domain information
 type DomainInfoCollector struct {
 	vmStatus         *prometheus.Desc
+	vmState          *prometheus.Desc
+	vmStateReason    *prometheus.Desc
 	vmCPUTime        *prometheus.Desc
 	vmMemoryCurrent  *prometheus.Desc
 	vmMemoryMax      *prometheus.Desc
@@ -17,11 +21,26 @@ type DomainInfoCollector struct {
 	vmAutostart      *prometheus.Desc
 	vmPersistent     *prometheus.Desc
 	vmManagedSave    *prometheus.Desc
+	vmGuestHostname  *prometheus.Desc
+	vmGenID          *prometheus.Desc
+	vmID             *prometheus.Desc
+	vmCPUTopology    *prometheus.Desc
+	vmSecurity       *prometheus.Desc
 	metricsCollector MetricsCollector
+	inventory        *DomainInventory
+	status           bool // send the state and info metrics (vm_status)
+	uptime           bool // send the uptime (vm_uptime)
 }
 
-// NewDomainInfoCollector creates a new DomainInfoCollector
-func NewDomainInfoCollector() *DomainInfoCollector {
+// NewDomainInfoCollector creates a new DomainInfoCollector sending the state
+// and info metrics of domains if status is set and their uptime if uptime is
+// set. Collected domains are summarized in inventory, which may be nil.
+func NewDomainInfoCollector(
+	metricsCollector MetricsCollector,
+	inventory *DomainInventory,
+	status bool,
+	uptime bool,
+) *DomainInfoCollector {
 	return &DomainInfoCollector{
 		vmStatus: prometheus.NewDesc(
 			"libvirt_vm_status",
@@ -29,6 +48,18 @@ func NewDomainInfoCollector() *DomainInfoCollector {
 			[]string{"domain", "uuid"},
 			nil,
 		),
+		vmState: prometheus.NewDesc(
+			"libvirt_vm_state",
+			"State of the virtual machine, 1 for the current state and 0 for all others",
+			[]string{"domain", "uuid", "state"},
+			nil,
+		),
+		vmStateReason: prometheus.NewDesc(
+			"libvirt_vm_state_reason",
+			"Reason of the current state of the virtual machine, value is always 1",
+			[]string{"domain", "uuid", "state", "reason"},
+			nil,
+		),
 		vmCPUTime: prometheus.NewDesc(
 			"libvirt_vm_cpu_time_seconds_total",
 			"Total CPU time used by the virtual machine in seconds",
@@ -71,13 +102,48 @@ func NewDomainInfoCollector() *DomainInfoCollector {
 			[]string{"domain", "uuid"},
 			nil,
 		),
-		metricsCollector: NewLibvirtMetricsCollector(),
+		vmGuestHostname: prometheus.NewDesc(
+			"libvirt_vm_guest_hostname_info",
+			"Hostname reported by the guest agent, value is always 1",
+			[]string{"domain", "uuid", "hostname"},
+			nil,
+		),
+		vmGenID: prometheus.NewDesc(
+			"libvirt_vm_genid_info",
+			"VM Generation ID of the virtual machine, value is always 1",
+			[]string{"domain", "uuid", "genid"},
+			nil,
+		),
+		vmID: prometheus.NewDesc(
+			"libvirt_vm_id",
+			"Numeric ID of the running virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmCPUTopology: prometheus.NewDesc(
+			"libvirt_vm_cpu_topology",
+			"Configured vCPU topology of the virtual machine, value is always 1",
+			[]string{"domain", "uuid", "sockets", "cores", "threads"},
+			nil,
+		),
+		vmSecurity: prometheus.NewDesc(
+			"libvirt_vm_security_info",
+			"Security label of the virtual machine for a security driver, value is always 1",
+			[]string{"domain", "uuid", "model", "type", "label", "relabel"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+		inventory:        inventory,
+		status:           status,
+		uptime:           uptime,
 	}
 }
 
 // Describe implements the prometheus.Collector interface for DomainInfoCollector
 func (c *DomainInfoCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.vmStatus
+	ch <- c.vmState
+	ch <- c.vmStateReason
 	ch <- c.vmCPUTime
 	ch <- c.vmMemoryCurrent
 	ch <- c.vmMemoryMax
@@ -85,17 +151,48 @@ func (c *DomainInfoCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.vmAutostart
 	ch <- c.vmPersistent
 	ch <- c.vmManagedSave
+	ch <- c.vmGuestHostname
+	ch <- c.vmGenID
+	ch <- c.vmID
+	ch <- c.vmCPUTopology
+	ch <- c.vmSecurity
 }
 
 // Collect implements the Collector interface for DomainInfoCollector
 func (c *DomainInfoCollector) Collect(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) {
-	metrics, err := c.metricsCollector.CollectDomainInfo(conn, domain)
+	metrics, err := c.metricsCollector.CollectDomainInfo(ctx, conn, domain)
 	if err != nil {
-		log.Printf("Failed to collect domain info metrics: %v", err)
+		slog.Warn("Failed to collect domain info metrics", "err", err)
+		if c.inventory != nil {
+			name, _ := domain.GetName()
+			uuid, uuidErr := domain.GetUUIDString()
+			if uuidErr == nil {
+				c.inventory.recordError(name, uuid, err)
+			}
+		}
+		return
+	}
+	if c.inventory != nil {
+		c.inventory.record(metrics)
+	}
+
+	// Only collect uptime for running domains
+	if c.uptime && metrics.HasUptime {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmUptime,
+			prometheus.GaugeValue,
+			metrics.Uptime,
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
+
+	if !c.status {
 		return
 	}
 
@@ -108,6 +205,35 @@ func (c *DomainInfoCollector) Collect(
 		metrics.UUID,
 	)
 
+	// VM state metric, one series per state
+	for _, state := range domainStateNames {
+		var stateValue float64
+		if state == metrics.State {
+			stateValue = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.vmState,
+			prometheus.GaugeValue,
+			stateValue,
+			metrics.Name,
+			metrics.UUID,
+			state,
+		)
+	}
+
+	// Missing if the state could not be queried
+	if metrics.StateReason != "" {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmStateReason,
+			prometheus.GaugeValue,
+			1.0,
+			metrics.Name,
+			metrics.UUID,
+			metrics.State,
+			metrics.StateReason,
+		)
+	}
+
 	// CPU time metric
 	ch <- prometheus.MustNewConstMetric(
 		c.vmCPUTime,
@@ -173,14 +299,71 @@ func (c *DomainInfoCollector) Collect(
 		metrics.UUID,
 	)
 
-	// Only collect uptime for running domains
-	if metrics.HasUptime {
+	// Only available for running domains with the guest agent
+	if metrics.GuestHostname != "" {
 		ch <- prometheus.MustNewConstMetric(
-			c.vmUptime,
+			c.vmGuestHostname,
 			prometheus.GaugeValue,
-			metrics.Uptime,
+			1.0,
+			metrics.Name,
+			metrics.UUID,
+			metrics.GuestHostname,
+		)
+	}
+
+	// Only present when the domain defines a generation ID
+	if metrics.GenID != "" {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmGenID,
+			prometheus.GaugeValue,
+			1.0,
+			metrics.Name,
+			metrics.UUID,
+			metrics.GenID,
+		)
+	}
+
+	// Inactive domains have no ID
+	if metrics.HasID {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmID,
+			prometheus.GaugeValue,
+			float64(metrics.ID),
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
+
+	// Missing if the domain XML could not be read
+	if metrics.CPUSockets > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmCPUTopology,
+			prometheus.GaugeValue,
+			1.0,
+			metrics.Name,
+			metrics.UUID,
+			strconv.Itoa(metrics.CPUSockets),
+			strconv.Itoa(metrics.CPUCores),
+			strconv.Itoa(metrics.CPUThreads),
+		)
+	}
+
+	// One series per security driver confining the domain
+	for _, seclabel := range metrics.SecLabels {
+		relabel := "no"
+		if seclabel.Relabel {
+			relabel = "yes"
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.vmSecurity,
+			prometheus.GaugeValue,
+			1.0,
 			metrics.Name,
 			metrics.UUID,
+			seclabel.Model,
+			seclabel.Type,
+			seclabel.Label,
+			relabel,
 		)
 	}
 }
@@ -0,0 +1,32 @@
+package collector
+
+import (
+	"log/slog"
+	"sync"
+
+	"libvirt.org/go/libvirt"
+)
+
+var (
+	eventLoopOnce sync.Once
+	eventLoopErr  error
+)
+
+// startEventLoop registers the default libvirt event loop implementation and
+// runs it in the background. It must be called before the first connection is
+// opened, otherwise libvirt does not deliver events to that connection.
+func startEventLoop() error {
+	eventLoopOnce.Do(func() {
+		if eventLoopErr = libvirt.EventRegisterDefaultImpl(); eventLoopErr != nil {
+			return
+		}
+		go func() {
+			for {
+				if err := libvirt.EventRunDefaultImpl(); err != nil {
+					slog.Warn("libvirt event loop iteration failed", "err", err)
+				}
+			}
+		}()
+	})
+	return eventLoopErr
+}
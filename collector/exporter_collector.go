@@ -1,6 +1,11 @@
 package collector
 
 import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -17,6 +22,17 @@ type ExporterCollector struct {
 	domainsDiscovered *prometheus.Desc
 	cacheHits         *prometheus.Desc
 	cacheMisses       *prometheus.Desc
+	labelCollisions   *prometheus.Desc
+	workers           *prometheus.Desc
+	counterWraps      *prometheus.Desc
+	collectorPanics   *prometheus.Desc
+	collectorTimeouts *prometheus.Desc
+	domainTimeouts    *prometheus.Desc
+	partialScrapes    *prometheus.Desc
+	scrapePartial     *prometheus.Desc // sent by LibvirtCollector at the end of a scrape
+	domainDuration    *prometheus.Desc // sent by LibvirtCollector after each domain
+	saturated         *prometheus.Desc
+	uriActive         *prometheus.Desc
 	buildVersion      *prometheus.Desc
 	buildCommit       *prometheus.Desc
 
@@ -26,9 +42,24 @@ type ExporterCollector struct {
 	scrapeErrorsTotal uint64
 	cacheHitsTotal    uint64
 	cacheMissesTotal  uint64
+	collisions        uint64 // colliding label values of the last scrape
+	timeoutsTotal     uint64
+	partialTotal      uint64
 	domainsFound      int
-
-	collected uint32 // atomic flag
+	workerCount       int64
+	wrapsMutex        sync.Mutex
+	wrapsTotal        map[string]uint64 // keyed by subsystem
+	panicsMutex       sync.Mutex
+	panicsTotal       map[string]uint64 // keyed by collector
+	timeoutsMutex     sync.Mutex
+	collectorTimedOut map[string]uint64 // keyed by collector
+	saturationMutex   sync.Mutex
+	saturation        map[string]bool // keyed by reason
+	urisMutex         sync.Mutex
+	uris              []string
+	activeURI         int
+	timeoutMutex      sync.Mutex
+	timeoutTrace      string // trace ID of the last timed-out domain
 }
 
 // NewExporterCollector creates a new ExporterCollector
@@ -76,6 +107,72 @@ func NewExporterCollector() *ExporterCollector {
 			[]string{},
 			nil,
 		),
+		labelCollisions: prometheus.NewDesc(
+			"libvirt_exporter_label_collisions",
+			"Number of domain label values colliding after sanitization in the last scrape",
+			[]string{},
+			nil,
+		),
+		workers: prometheus.NewDesc(
+			"libvirt_exporter_collection_workers",
+			"Number of workers used to collect domains during the last scrape",
+			[]string{},
+			nil,
+		),
+		counterWraps: prometheus.NewDesc(
+			"libvirt_exporter_counter_wraps_total",
+			"Total number of detected 32-bit counter wraps",
+			[]string{"subsystem"},
+			nil,
+		),
+		collectorPanics: prometheus.NewDesc(
+			"libvirt_exporter_collector_panics_total",
+			"Total number of recovered panics in collectors",
+			[]string{"collector"},
+			nil,
+		),
+		collectorTimeouts: prometheus.NewDesc(
+			"libvirt_exporter_collector_timeouts_total",
+			"Total number of collector runs abandoned because they exceeded the collector timeout",
+			[]string{"collector"},
+			nil,
+		),
+		domainTimeouts: prometheus.NewDesc(
+			"libvirt_exporter_domain_timeouts_total",
+			"Total number of domains skipped because their collection timed out",
+			[]string{},
+			nil,
+		),
+		partialScrapes: prometheus.NewDesc(
+			"libvirt_exporter_partial_scrapes_total",
+			"Total number of scrapes cut off by the scrape deadline before collecting every domain",
+			[]string{},
+			nil,
+		),
+		scrapePartial: prometheus.NewDesc(
+			"libvirt_exporter_scrape_partial",
+			"Whether this scrape was cut off by the scrape deadline before collecting every domain (1=partial, 0=complete)",
+			[]string{},
+			nil,
+		),
+		domainDuration: prometheus.NewDesc(
+			"libvirt_exporter_domain_collection_duration_seconds",
+			"Duration of the collection of a domain during this scrape",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		saturated: prometheus.NewDesc(
+			"libvirt_exporter_saturated",
+			"Whether the exporter is degraded because a limit was reached (1=saturated, 0=ok)",
+			[]string{"reason"},
+			nil,
+		),
+		uriActive: prometheus.NewDesc(
+			"libvirt_exporter_uri_active",
+			"Whether a configured libvirt URI is the one currently connected (1=active, 0=standby)",
+			[]string{"uri", "transport", "priority"},
+			nil,
+		),
 		buildVersion: prometheus.NewDesc(
 			"libvirt_exporter_build_version",
 			"Exporter build version",
@@ -88,7 +185,11 @@ func NewExporterCollector() *ExporterCollector {
 			[]string{"commit"},
 			nil,
 		),
-		startTime: time.Now(),
+		startTime:         time.Now(),
+		wrapsTotal:        make(map[string]uint64),
+		panicsTotal:       make(map[string]uint64),
+		collectorTimedOut: make(map[string]uint64),
+		saturation:        make(map[string]bool),
 	}
 }
 
@@ -101,25 +202,42 @@ func (c *ExporterCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.domainsDiscovered
 	ch <- c.cacheHits
 	ch <- c.cacheMisses
+	ch <- c.labelCollisions
+	ch <- c.workers
+	ch <- c.counterWraps
+	ch <- c.collectorPanics
+	ch <- c.collectorTimeouts
+	ch <- c.domainTimeouts
+	ch <- c.partialScrapes
+	ch <- c.saturated
+	ch <- c.uriActive
 	ch <- c.buildVersion
 	ch <- c.buildCommit
 }
 
 // Reset implements the Collector interface for ExporterCollector
 func (c *ExporterCollector) Reset() {
-	atomic.StoreUint32(&c.collected, 0)
+	// No internal state to reset
 }
 
-// Collect implements the Collector interface for ExporterCollector
+// Collect implements the Collector interface for ExporterCollector; its
+// metrics are sent once per scrape by CollectHost
 func (c *ExporterCollector) Collect(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) {
-	// Use atomic operation to ensure we only collect exporter metrics once per scrape
-	if atomic.CompareAndSwapUint32(&c.collected, 0, 1) {
-		c.collectExporterMetrics(ch, conn)
-	}
+}
+
+// CollectHost implements the HostCollector interface for ExporterCollector
+func (c *ExporterCollector) CollectHost(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	includes func(name, uuid string) bool,
+) {
+	c.collectExporterMetrics(ch, conn)
 }
 
 // collectExporterMetrics collects exporter self-monitoring metrics
@@ -143,6 +261,10 @@ func (c *ExporterCollector) collectExporterMetrics(
 	scrapeErrors := atomic.LoadUint64(&c.scrapeErrorsTotal)
 	cacheHits := atomic.LoadUint64(&c.cacheHitsTotal)
 	cacheMisses := atomic.LoadUint64(&c.cacheMissesTotal)
+	collisions := atomic.LoadUint64(&c.collisions)
+	timeouts := atomic.LoadUint64(&c.timeoutsTotal)
+	partials := atomic.LoadUint64(&c.partialTotal)
+	workers := atomic.LoadInt64(&c.workerCount)
 	domainsFound := c.domainsFound
 
 	// Calculate uptime (not used in metrics, but kept for future use)
@@ -196,6 +318,106 @@ func (c *ExporterCollector) collectExporterMetrics(
 		float64(cacheMisses),
 	)
 
+	ch <- prometheus.MustNewConstMetric(
+		c.labelCollisions,
+		prometheus.GaugeValue,
+		float64(collisions),
+	)
+
+	// Link the counter to the trace of the last timeout, if it was traced
+	domainTimeouts := prometheus.MustNewConstMetric(
+		c.domainTimeouts,
+		prometheus.CounterValue,
+		float64(timeouts),
+	)
+	c.timeoutMutex.Lock()
+	timeoutTrace := c.timeoutTrace
+	c.timeoutMutex.Unlock()
+	if timeoutTrace != "" {
+		domainTimeouts = prometheus.MustNewMetricWithExemplars(domainTimeouts, prometheus.Exemplar{
+			Value:  1,
+			Labels: prometheus.Labels{"trace_id": timeoutTrace},
+		})
+	}
+	ch <- domainTimeouts
+
+	ch <- prometheus.MustNewConstMetric(
+		c.partialScrapes,
+		prometheus.CounterValue,
+		float64(partials),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.workers,
+		prometheus.GaugeValue,
+		float64(workers),
+	)
+
+	c.wrapsMutex.Lock()
+	for subsystem, wraps := range c.wrapsTotal {
+		ch <- prometheus.MustNewConstMetric(
+			c.counterWraps,
+			prometheus.CounterValue,
+			float64(wraps),
+			subsystem,
+		)
+	}
+	c.wrapsMutex.Unlock()
+
+	c.panicsMutex.Lock()
+	for collector, panics := range c.panicsTotal {
+		ch <- prometheus.MustNewConstMetric(
+			c.collectorPanics,
+			prometheus.CounterValue,
+			float64(panics),
+			collector,
+		)
+	}
+	c.panicsMutex.Unlock()
+
+	c.timeoutsMutex.Lock()
+	for collector, timeouts := range c.collectorTimedOut {
+		ch <- prometheus.MustNewConstMetric(
+			c.collectorTimeouts,
+			prometheus.CounterValue,
+			float64(timeouts),
+			collector,
+		)
+	}
+	c.timeoutsMutex.Unlock()
+
+	c.saturationMutex.Lock()
+	for reason, saturated := range c.saturation {
+		var saturatedValue float64
+		if saturated {
+			saturatedValue = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.saturated,
+			prometheus.GaugeValue,
+			saturatedValue,
+			reason,
+		)
+	}
+	c.saturationMutex.Unlock()
+
+	c.urisMutex.Lock()
+	for i, uri := range c.uris {
+		var activeValue float64
+		if i == c.activeURI {
+			activeValue = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.uriActive,
+			prometheus.GaugeValue,
+			activeValue,
+			uri,
+			uriTransport(uri),
+			strconv.Itoa(i),
+		)
+	}
+	c.urisMutex.Unlock()
+
 	// Build info (these would typically come from build-time variables)
 	buildVersion := "unknown"
 	buildCommit := "unknown"
@@ -233,6 +455,111 @@ func (c *ExporterCollector) RecordCacheMiss() {
 	atomic.AddUint64(&c.cacheMissesTotal, 1)
 }
 
+// SetLabelCollisions sets the number of domain label collisions resolved
+// during the last scrape
+func (c *ExporterCollector) SetLabelCollisions(count int) {
+	atomic.StoreUint64(&c.collisions, uint64(count))
+}
+
+// RecordDomainTimeout records a domain skipped because its collection timed
+// out, along with the ID of the scrape's trace (empty if not traced)
+func (c *ExporterCollector) RecordDomainTimeout(traceID string) {
+	atomic.AddUint64(&c.timeoutsTotal, 1)
+	if traceID != "" {
+		c.timeoutMutex.Lock()
+		c.timeoutTrace = traceID
+		c.timeoutMutex.Unlock()
+	}
+}
+
+// RecordPartialScrape records a scrape cut off by its deadline
+func (c *ExporterCollector) RecordPartialScrape() {
+	atomic.AddUint64(&c.partialTotal, 1)
+}
+
+// partialMetric returns the sample telling whether the current scrape was
+// cut off by its deadline
+func (c *ExporterCollector) partialMetric(partial bool) prometheus.Metric {
+	var value float64
+	if partial {
+		value = 1
+	}
+	return prometheus.MustNewConstMetric(c.scrapePartial, prometheus.GaugeValue, value)
+}
+
+// domainDurationMetric returns the sample of the time taken to collect a domain
+func (c *ExporterCollector) domainDurationMetric(name, uuid string, duration time.Duration) prometheus.Metric {
+	return prometheus.MustNewConstMetric(c.domainDuration, prometheus.GaugeValue, duration.Seconds(), name, uuid)
+}
+
+// RecordCounterWrap records a detected counter wrap for a subsystem
+func (c *ExporterCollector) RecordCounterWrap(subsystem string) {
+	c.wrapsMutex.Lock()
+	c.wrapsTotal[subsystem]++
+	c.wrapsMutex.Unlock()
+}
+
+// RecordCollectorTimeout records a collector run abandoned after its timeout
+func (c *ExporterCollector) RecordCollectorTimeout(collector string) {
+	c.timeoutsMutex.Lock()
+	c.collectorTimedOut[collector]++
+	c.timeoutsMutex.Unlock()
+}
+
+// RecordCollectorPanic records a recovered panic of a collector
+func (c *ExporterCollector) RecordCollectorPanic(collector string) {
+	c.panicsMutex.Lock()
+	c.panicsTotal[collector]++
+	c.panicsMutex.Unlock()
+}
+
+// SetSaturated records whether the exporter is degraded for a reason
+func (c *ExporterCollector) SetSaturated(reason string, saturated bool) {
+	c.saturationMutex.Lock()
+	c.saturation[reason] = saturated
+	c.saturationMutex.Unlock()
+}
+
+// SetActiveURI records the configured URIs and the index of the connected one
+func (c *ExporterCollector) SetActiveURI(uris []string, active int) {
+	c.urisMutex.Lock()
+	c.uris = uris
+	c.activeURI = active
+	c.urisMutex.Unlock()
+}
+
+// ActiveURI returns the URI of the current libvirt connection
+func (c *ExporterCollector) ActiveURI() string {
+	c.urisMutex.Lock()
+	defer c.urisMutex.Unlock()
+
+	if c.activeURI >= len(c.uris) {
+		return ""
+	}
+	return c.uris[c.activeURI]
+}
+
+// uriTransport returns the transport used by a libvirt URI
+func uriTransport(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "unknown"
+	}
+	if i := strings.Index(parsed.Scheme, "+"); i >= 0 {
+		return parsed.Scheme[i+1:]
+	}
+	// Without an explicit transport, remote hosts default to TLS
+	if parsed.Host != "" {
+		return "tls"
+	}
+	return "unix"
+}
+
+// SetWorkers sets the number of collection workers used for the current scrape
+func (c *ExporterCollector) SetWorkers(count int) {
+	atomic.StoreInt64(&c.workerCount, int64(count))
+}
+
 // SetDomainsFound sets the number of domains found
 func (c *ExporterCollector) SetDomainsFound(count int) {
 	c.domainsFound = count
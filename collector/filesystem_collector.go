@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// FilesystemCollector collects the usage of guest filesystems reported by the
+// QEMU guest agent. Domains without a responsive agent are skipped.
+type FilesystemCollector struct {
+	vmFSUsed         *prometheus.Desc
+	vmFSTotal        *prometheus.Desc
+	metricsCollector MetricsCollector
+}
+
+// NewFilesystemCollector creates a new FilesystemCollector
+func NewFilesystemCollector(metricsCollector MetricsCollector) *FilesystemCollector {
+	return &FilesystemCollector{
+		vmFSUsed: prometheus.NewDesc(
+			"libvirt_vm_fs_used_bytes",
+			"Used bytes of a guest filesystem as reported by the guest agent",
+			[]string{"domain", "uuid", "mountpoint", "device", "fstype"},
+			nil,
+		),
+		vmFSTotal: prometheus.NewDesc(
+			"libvirt_vm_fs_total_bytes",
+			"Total bytes of a guest filesystem as reported by the guest agent",
+			[]string{"domain", "uuid", "mountpoint", "device", "fstype"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+	}
+}
+
+// Describe implements the prometheus.Collector interface for FilesystemCollector
+func (c *FilesystemCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmFSUsed
+	ch <- c.vmFSTotal
+}
+
+// Collect implements the Collector interface for FilesystemCollector
+func (c *FilesystemCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+	// Get domain info first to check if it's running
+	domainInfo, err := domain.GetInfo()
+	if err != nil {
+		slog.Warn("Failed to get domain info for filesystem metrics", "err", err)
+		return
+	}
+
+	// Only running domains have a guest agent to ask
+	if domainInfo.State != libvirt.DOMAIN_RUNNING {
+		return
+	}
+
+	metricsList, err := c.metricsCollector.CollectFilesystemStats(ctx, conn, domain)
+	if err != nil {
+		domainName, _ := domain.GetName()
+		if agentUnavailable(err) {
+			// The guest agent is optional, not installed in every guest
+			slog.Debug("Guest agent unavailable for filesystem metrics", "domain", domainName, "err", err)
+			return
+		}
+		slog.Warn("Failed to collect filesystem metrics", "domain", domainName, "err", err)
+		return
+	}
+
+	for _, metrics := range metricsList {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmFSUsed,
+			prometheus.GaugeValue,
+			float64(metrics.UsedBytes),
+			metrics.Name,
+			metrics.UUID,
+			metrics.MountPoint,
+			metrics.Device,
+			metrics.FSType,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmFSTotal,
+			prometheus.GaugeValue,
+			float64(metrics.TotalBytes),
+			metrics.Name,
+			metrics.UUID,
+			metrics.MountPoint,
+			metrics.Device,
+			metrics.FSType,
+		)
+	}
+}
+
+// agentUnavailable reports whether an error means the domain has no usable
+// guest agent
+func agentUnavailable(err error) bool {
+	lverr, ok := err.(libvirt.Error)
+	if !ok {
+		return false
+	}
+	switch lverr.Code {
+	case libvirt.ERR_AGENT_UNRESPONSIVE,
+		libvirt.ERR_AGENT_UNSYNCED,
+		libvirt.ERR_AGENT_COMMAND_TIMEOUT,
+		libvirt.ERR_OPERATION_UNSUPPORTED,
+		libvirt.ERR_ARGUMENT_UNSUPPORTED:
+		return true
+	default:
+		return false
+	}
+}
+
+// Reset implements the Collector interface
+func (c *FilesystemCollector) Reset() {
+	// No internal state to reset
+}
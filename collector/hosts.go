@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// hostLabelName is the label identifying the libvirt host of every metric
+// when several hosts are collected
+const hostLabelName = "host"
+
+// hostLabel returns the host label value of a libvirt URI: its host name, or
+// "localhost" for local URIs such as qemu:///system
+func hostLabel(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Hostname() == "" {
+		return "localhost"
+	}
+	return parsed.Hostname()
+}
+
+// peerOptions returns the options of peer collectors: those of the primary
+// collector, except the ones tied to the local host (fallback URIs and the
+// admin interface) and further hosts. Peers start even when their host is
+// unreachable and connect once it is up.
+func (c *LibvirtCollector) peerOptions() Options {
+	opts := c.opts
+	opts.peer = true
+	opts.HostURIs = nil
+	opts.FallbackURIs = nil
+	opts.AdminURI = ""
+	opts.DiscoveryName = ""
+	return opts
+}
+
+// addHosts connects a peer collector to each additional host URI
+func (c *LibvirtCollector) addHosts(primary string, uris []string) error {
+	if c.opts.LeaderLock != "" {
+		return fmt.Errorf("leader election cannot be combined with several libvirt hosts")
+	}
+
+	opts := c.peerOptions()
+	c.host = hostLabel(primary)
+	seen := map[string]bool{c.host: true}
+	for _, uri := range uris {
+		host := hostLabel(uri)
+		if seen[host] {
+			c.closeHosts()
+			return fmt.Errorf("libvirt URI %q has the same host %q as another URI", uri, host)
+		}
+		seen[host] = true
+
+		peer, err := NewLibvirtCollector(uri, opts)
+		if err != nil {
+			c.closeHosts()
+			return fmt.Errorf("failed to create collector of host %q: %w", host, err)
+		}
+		peer.host = host
+		c.hosts = append(c.hosts, peer)
+	}
+	return nil
+}
+
+// closeHosts closes the connections of the peer collectors
+func (c *LibvirtCollector) closeHosts() {
+	c.hostsMutex.Lock()
+	defer c.hostsMutex.Unlock()
+
+	for _, peer := range c.hosts {
+		peer.Close()
+	}
+	c.hosts = nil
+}
+
+// multiHost reports whether metrics are labeled with their host, i.e. peer
+// hosts are configured or discovered
+func (c *LibvirtCollector) multiHost() bool {
+	return c.discovery != nil || len(c.peers()) > 0
+}
+
+// peers returns the current peer collectors
+func (c *LibvirtCollector) peers() []*LibvirtCollector {
+	c.hostsMutex.RLock()
+	defer c.hostsMutex.RUnlock()
+
+	return append([]*LibvirtCollector(nil), c.hosts...)
+}
+
+// hostScrape is a prometheus.Collector running a scrape of the primary host
+// and all peer hosts, labeling each metric with its host
+type hostScrape struct {
+	collectors []prometheus.Collector
+}
+
+// newHostScrape labels the scrape of each host with the scope of the same index
+func newHostScrape(hosts []*LibvirtCollector, scopes []scrapeScope) *hostScrape {
+	scrape := &hostScrape{}
+	for i, host := range hosts {
+		scrape.collectors = append(scrape.collectors, prometheus.WrapCollectorWith(
+			prometheus.Labels{hostLabelName: host.host},
+			&collectorSubset{parent: host, scope: scopes[i]},
+		))
+	}
+	return scrape
+}
+
+// Describe implements the prometheus.Collector interface
+func (s *hostScrape) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range s.collectors {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements the prometheus.Collector interface. Hosts are scraped
+// in parallel so that a slow host does not delay the others.
+func (s *hostScrape) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for _, collector := range s.collectors {
+		wg.Add(1)
+		go func(collector prometheus.Collector) {
+			defer wg.Done()
+			collector.Collect(ch)
+		}(collector)
+	}
+	wg.Wait()
+}
+
+// selectHosts returns the scrape of the named sub-collectors and domains on
+// every host. Peers skip the selected collectors they do not run, and are not
+// scraped at all if they run none of them.
+func (c *LibvirtCollector) selectHosts(ctx context.Context, names, domains []string) (prometheus.Collector, error) {
+	scope, err := c.selectScope(ctx, names, domains, false)
+	if err != nil {
+		return nil, err
+	}
+	hosts := []*LibvirtCollector{c}
+	scopes := []scrapeScope{scope}
+	for _, peer := range c.peers() {
+		scope, err := peer.selectScope(ctx, names, domains, true)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) > 0 && len(scope.collectors) == 0 {
+			continue
+		}
+		hosts = append(hosts, peer)
+		scopes = append(scopes, scope)
+	}
+	return newHostScrape(hosts, scopes), nil
+}
+
+// fullHostScrape returns the scrape of all sub-collectors on every host
+func (c *LibvirtCollector) fullHostScrape() *hostScrape {
+	hosts := append([]*LibvirtCollector{c}, c.peers()...)
+	scopes := make([]scrapeScope, len(hosts))
+	for i, host := range hosts {
+		scopes[i] = scrapeScope{collectors: host.collectors}
+	}
+	return newHostScrape(hosts, scopes)
+}
@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DomainSummary is the last collected overview of a domain
+type DomainSummary struct {
+	Name          string
+	UUID          string
+	State         string
+	VCPUs         uint
+	MemoryBytes   float64
+	Uptime        float64
+	HasUptime     bool
+	LastCollected time.Time
+	LastError     string
+}
+
+// DomainInventory keeps the last collected summary of every domain
+type DomainInventory struct {
+	mutex   sync.RWMutex
+	domains map[string]*DomainSummary // keyed by domain UUID
+}
+
+// NewDomainInventory creates a new DomainInventory
+func NewDomainInventory() *DomainInventory {
+	return &DomainInventory{
+		domains: make(map[string]*DomainSummary),
+	}
+}
+
+// record stores the summary of a successfully collected domain
+func (i *DomainInventory) record(metrics *DomainInfoMetrics) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.domains[metrics.UUID] = &DomainSummary{
+		Name:          metrics.Name,
+		UUID:          metrics.UUID,
+		State:         metrics.State,
+		VCPUs:         metrics.VCPUs,
+		MemoryBytes:   metrics.MemoryCurrent,
+		Uptime:        metrics.Uptime,
+		HasUptime:     metrics.HasUptime,
+		LastCollected: time.Now(),
+	}
+}
+
+// recordError marks the last collection of a domain as failed, keeping the
+// previously collected values
+func (i *DomainInventory) recordError(name, uuid string, err error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	summary, ok := i.domains[uuid]
+	if !ok {
+		summary = &DomainSummary{Name: name, UUID: uuid}
+		i.domains[uuid] = summary
+	}
+	summary.LastError = err.Error()
+}
+
+// retain forgets domains that are not in the given set of UUIDs
+func (i *DomainInventory) retain(uuids map[string]bool) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for uuid := range i.domains {
+		if !uuids[uuid] {
+			delete(i.domains, uuid)
+		}
+	}
+}
+
+// Domains returns the summaries of all known domains sorted by name
+func (i *DomainInventory) Domains() []DomainSummary {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	summaries := make([]DomainSummary, 0, len(i.domains))
+	for _, summary := range i.domains {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(a, b int) bool {
+		return summaries[a].Name < summaries[b].Name
+	})
+	return summaries
+}
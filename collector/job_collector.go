@@ -0,0 +1,173 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// JobCollector collects domain job (migration, block job) progress
+type JobCollector struct {
+	vmJobActive      *prometheus.Desc
+	vmJobProgress    *prometheus.Desc
+	vmJobRemaining   *prometheus.Desc
+	vmJobTransferred *prometheus.Desc
+	vmJobTotal       *prometheus.Desc
+	vmJobSpeed       *prometheus.Desc
+	metricsCollector MetricsCollector
+}
+
+// NewJobCollector creates a new JobCollector
+func NewJobCollector(metricsCollector MetricsCollector) *JobCollector {
+	return &JobCollector{
+		vmJobActive: prometheus.NewDesc(
+			"libvirt_vm_job_active",
+			"Whether the virtual machine has an active job (1=active, 0=none)",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmJobProgress: prometheus.NewDesc(
+			"libvirt_vm_job_progress_ratio",
+			"Progress of the active job (0.0 - 1.0)",
+			[]string{"domain", "uuid", "type", "operation"},
+			nil,
+		),
+		vmJobRemaining: prometheus.NewDesc(
+			"libvirt_vm_job_bytes_remaining",
+			"Bytes remaining to be processed by the active job",
+			[]string{"domain", "uuid", "type", "operation"},
+			nil,
+		),
+		vmJobTransferred: prometheus.NewDesc(
+			"libvirt_vm_job_bytes_transferred",
+			"Bytes already processed by the active job",
+			[]string{"domain", "uuid", "type", "operation"},
+			nil,
+		),
+		vmJobTotal: prometheus.NewDesc(
+			"libvirt_vm_job_bytes_total",
+			"Total bytes to be processed by the active job",
+			[]string{"domain", "uuid", "type", "operation"},
+			nil,
+		),
+		vmJobSpeed: prometheus.NewDesc(
+			"libvirt_vm_job_speed_bps",
+			"Current transfer speed of the active job in bytes per second",
+			[]string{"domain", "uuid", "type", "operation"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+	}
+}
+
+// Describe implements the prometheus.Collector interface for JobCollector
+func (c *JobCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmJobActive
+	ch <- c.vmJobProgress
+	ch <- c.vmJobRemaining
+	ch <- c.vmJobTransferred
+	ch <- c.vmJobTotal
+	ch <- c.vmJobSpeed
+}
+
+// Collect implements the Collector interface for JobCollector
+func (c *JobCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+	// Get domain info first to check if it's running
+	domainInfo, err := domain.GetInfo()
+	if err != nil {
+		slog.Warn("Failed to get domain info for job metrics", "err", err)
+		return
+	}
+
+	// Jobs only exist for running domains
+	if domainInfo.State != libvirt.DOMAIN_RUNNING {
+		return
+	}
+
+	metrics, err := c.metricsCollector.CollectJobStats(ctx, conn, domain)
+	if err != nil {
+		domainName, _ := domain.GetName()
+		slog.Warn("Failed to collect job metrics", "domain", domainName, "err", err)
+		return
+	}
+
+	var activeValue float64
+	if metrics.Type != "" {
+		activeValue = 1.0
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmJobActive,
+		prometheus.GaugeValue,
+		activeValue,
+		metrics.Name,
+		metrics.UUID,
+	)
+
+	// Only expose progress metrics while a job is running
+	if metrics.Type == "" {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmJobProgress,
+		prometheus.GaugeValue,
+		metrics.Progress,
+		metrics.Name,
+		metrics.UUID,
+		metrics.Type,
+		metrics.Operation,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmJobRemaining,
+		prometheus.GaugeValue,
+		float64(metrics.Remaining),
+		metrics.Name,
+		metrics.UUID,
+		metrics.Type,
+		metrics.Operation,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmJobTransferred,
+		prometheus.GaugeValue,
+		float64(metrics.Transferred),
+		metrics.Name,
+		metrics.UUID,
+		metrics.Type,
+		metrics.Operation,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmJobTotal,
+		prometheus.GaugeValue,
+		float64(metrics.Total),
+		metrics.Name,
+		metrics.UUID,
+		metrics.Type,
+		metrics.Operation,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmJobSpeed,
+		prometheus.GaugeValue,
+		float64(metrics.SpeedBps),
+		metrics.Name,
+		metrics.UUID,
+		metrics.Type,
+		metrics.Operation,
+	)
+}
+
+// Reset implements the Collector interface
+func (c *JobCollector) Reset() {
+	// No internal state to reset
+}
@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// KSMCollector collects the kernel samepage merging counters of the host,
+// which libvirt reads from /sys/kernel/mm/ksm
+type KSMCollector struct {
+	ksmPagesShared   *prometheus.Desc
+	ksmPagesSharing  *prometheus.Desc
+	ksmPagesUnshared *prometheus.Desc
+	ksmPagesVolatile *prometheus.Desc
+	ksmFullScans     *prometheus.Desc
+	metricsCollector MetricsCollector
+}
+
+// NewKSMCollector creates a new KSMCollector
+func NewKSMCollector(metricsCollector MetricsCollector) *KSMCollector {
+	return &KSMCollector{
+		ksmPagesShared: prometheus.NewDesc(
+			"libvirt_host_ksm_pages_shared",
+			"Number of shared pages KSM keeps in use",
+			nil,
+			nil,
+		),
+		ksmPagesSharing: prometheus.NewDesc(
+			"libvirt_host_ksm_pages_sharing",
+			"Number of additional page references sharing the KSM shared pages, i.e. pages saved",
+			nil,
+			nil,
+		),
+		ksmPagesUnshared: prometheus.NewDesc(
+			"libvirt_host_ksm_pages_unshared",
+			"Number of pages KSM checked repeatedly that are unique",
+			nil,
+			nil,
+		),
+		ksmPagesVolatile: prometheus.NewDesc(
+			"libvirt_host_ksm_pages_volatile",
+			"Number of pages changing too fast to be merged by KSM",
+			nil,
+			nil,
+		),
+		ksmFullScans: prometheus.NewDesc(
+			"libvirt_host_ksm_full_scans_total",
+			"Number of times KSM scanned all mergeable memory areas",
+			nil,
+			nil,
+		),
+		metricsCollector: metricsCollector,
+	}
+}
+
+// Describe implements the prometheus.Collector interface for KSMCollector
+func (c *KSMCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.ksmPagesShared
+	ch <- c.ksmPagesSharing
+	ch <- c.ksmPagesUnshared
+	ch <- c.ksmPagesVolatile
+	ch <- c.ksmFullScans
+}
+
+// Reset implements the Collector interface for KSMCollector
+func (c *KSMCollector) Reset() {
+	// No internal state to reset
+}
+
+// Collect implements the Collector interface for KSMCollector; its metrics are
+// host-wide and sent by CollectHost
+func (c *KSMCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+}
+
+// CollectHost implements the HostCollector interface for KSMCollector
+func (c *KSMCollector) CollectHost(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	includes func(name, uuid string) bool,
+) {
+	metrics, err := c.metricsCollector.CollectKSMStats(ctx, conn)
+	if err != nil {
+		// Hosts without KSM support are expected
+		if lverr, ok := err.(libvirt.Error); ok &&
+			(lverr.Code == libvirt.ERR_NO_SUPPORT || lverr.Code == libvirt.ERR_OPERATION_UNSUPPORTED) {
+			return
+		}
+		slog.Warn("Failed to collect KSM metrics", "err", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.ksmPagesShared,
+		prometheus.GaugeValue,
+		float64(metrics.PagesShared),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.ksmPagesSharing,
+		prometheus.GaugeValue,
+		float64(metrics.PagesSharing),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.ksmPagesUnshared,
+		prometheus.GaugeValue,
+		float64(metrics.PagesUnshared),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.ksmPagesVolatile,
+		prometheus.GaugeValue,
+		float64(metrics.PagesVolatile),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.ksmFullScans,
+		prometheus.CounterValue,
+		float64(metrics.FullScans),
+	)
+}
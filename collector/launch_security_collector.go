@@ -0,0 +1,255 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// LaunchSecurityCollector collects which confidential computing technology
+// (AMD SEV, SEV-ES, SEV-SNP or Intel TDX) protects each domain
+type LaunchSecurityCollector struct {
+	vmLaunchSecurity *prometheus.Desc
+	vmPolicy         *prometheus.Desc
+	vmMeasured       *prometheus.Desc
+	vmSEVFirmware    *prometheus.Desc
+	metricsCollector MetricsCollector
+}
+
+// launchSecurityHostCollector collects the AMD SEV capabilities of the host
+type launchSecurityHostCollector struct {
+	hostSEVSupported *prometheus.Desc
+	hostSEVMaxGuests *prometheus.Desc
+	hostSEVMaxES     *prometheus.Desc
+	hostSEVCBitPos   *prometheus.Desc
+	hostSEVPhysBits  *prometheus.Desc
+	metricsCollector MetricsCollector
+}
+
+// NewLaunchSecurityCollector creates a new LaunchSecurityCollector
+func NewLaunchSecurityCollector(metricsCollector MetricsCollector) *LaunchSecurityCollector {
+	return &LaunchSecurityCollector{
+		vmLaunchSecurity: prometheus.NewDesc(
+			"libvirt_vm_launch_security_info",
+			"Launch security of the virtual machine (sev, sev-es, sev-snp, tdx, s390-pv or none), value is always 1",
+			[]string{"domain", "uuid", "type"},
+			nil,
+		),
+		vmPolicy: prometheus.NewDesc(
+			"libvirt_vm_launch_security_policy",
+			"Guest policy of the launch security of the virtual machine",
+			[]string{"domain", "uuid", "type"},
+			nil,
+		),
+		vmMeasured: prometheus.NewDesc(
+			"libvirt_vm_launch_security_measured",
+			"Whether the launch measurement of the running SEV virtual machine is available for attestation",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmSEVFirmware: prometheus.NewDesc(
+			"libvirt_vm_sev_firmware_info",
+			"SEV firmware version the running virtual machine was launched with, value is always 1",
+			[]string{"domain", "uuid", "api_version", "build_id"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+	}
+}
+
+// newLaunchSecurityHostCollector creates a new launchSecurityHostCollector
+func newLaunchSecurityHostCollector(metricsCollector MetricsCollector) *launchSecurityHostCollector {
+	return &launchSecurityHostCollector{
+		hostSEVSupported: prometheus.NewDesc(
+			"libvirt_host_sev_supported",
+			"Whether the host supports AMD SEV guests",
+			nil,
+			nil,
+		),
+		hostSEVMaxGuests: prometheus.NewDesc(
+			"libvirt_host_sev_max_guests",
+			"Maximum number of SEV guests the host can run",
+			nil,
+			nil,
+		),
+		hostSEVMaxES: prometheus.NewDesc(
+			"libvirt_host_sev_max_es_guests",
+			"Maximum number of SEV-ES guests the host can run",
+			nil,
+			nil,
+		),
+		hostSEVCBitPos: prometheus.NewDesc(
+			"libvirt_host_sev_cbitpos",
+			"Position of the SEV encryption bit in page table entries",
+			nil,
+			nil,
+		),
+		hostSEVPhysBits: prometheus.NewDesc(
+			"libvirt_host_sev_reduced_phys_bits",
+			"Number of physical address bits lost to SEV memory encryption",
+			nil,
+			nil,
+		),
+		metricsCollector: metricsCollector,
+	}
+}
+
+// Describe implements the prometheus.Collector interface for LaunchSecurityCollector
+func (c *LaunchSecurityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmLaunchSecurity
+	ch <- c.vmPolicy
+	ch <- c.vmMeasured
+	ch <- c.vmSEVFirmware
+}
+
+// Reset implements the Collector interface for LaunchSecurityCollector
+func (c *LaunchSecurityCollector) Reset() {
+	// No internal state to reset
+}
+
+// Collect implements the Collector interface for LaunchSecurityCollector
+func (c *LaunchSecurityCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+	metrics, err := c.metricsCollector.CollectLaunchSecurityStats(ctx, conn, domain)
+	if err != nil {
+		slog.Warn("Failed to collect launch security metrics", "err", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmLaunchSecurity,
+		prometheus.GaugeValue,
+		1.0,
+		metrics.Name,
+		metrics.UUID,
+		metrics.Type,
+	)
+
+	if metrics.HasPolicy {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmPolicy,
+			prometheus.GaugeValue,
+			float64(metrics.Policy),
+			metrics.Name,
+			metrics.UUID,
+			metrics.Type,
+		)
+	}
+
+	// Only running SEV domains report their launch state
+	if metrics.HasFirmware || metrics.Measured {
+		var measured float64
+		if metrics.Measured {
+			measured = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.vmMeasured,
+			prometheus.GaugeValue,
+			measured,
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
+
+	if metrics.HasFirmware {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmSEVFirmware,
+			prometheus.GaugeValue,
+			1.0,
+			metrics.Name,
+			metrics.UUID,
+			strconv.FormatUint(uint64(metrics.APIMajor), 10)+"."+strconv.FormatUint(uint64(metrics.APIMinor), 10),
+			strconv.FormatUint(uint64(metrics.BuildID), 10),
+		)
+	}
+}
+
+// Describe implements the prometheus.Collector interface for
+// launchSecurityHostCollector
+func (c *launchSecurityHostCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hostSEVSupported
+	ch <- c.hostSEVMaxGuests
+	ch <- c.hostSEVMaxES
+	ch <- c.hostSEVCBitPos
+	ch <- c.hostSEVPhysBits
+}
+
+// Reset implements the Collector interface for launchSecurityHostCollector
+func (c *launchSecurityHostCollector) Reset() {
+	// No internal state to reset
+}
+
+// Collect implements the Collector interface for
+// launchSecurityHostCollector; its metrics are sent by CollectHost
+func (c *launchSecurityHostCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+}
+
+// CollectHost sends the AMD SEV capabilities of the host
+func (c *launchSecurityHostCollector) CollectHost(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	includes func(name, uuid string) bool,
+) {
+	metrics, err := c.metricsCollector.CollectHostSEVStats(ctx, conn)
+	if err != nil {
+		// Hosts without SEV support are expected
+		if lverr, ok := err.(libvirt.Error); ok &&
+			(lverr.Code == libvirt.ERR_NO_SUPPORT || lverr.Code == libvirt.ERR_OPERATION_UNSUPPORTED) {
+			ch <- prometheus.MustNewConstMetric(
+				c.hostSEVSupported,
+				prometheus.GaugeValue,
+				0.0,
+			)
+			return
+		}
+		slog.Warn("Failed to collect host SEV metrics", "err", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostSEVSupported,
+		prometheus.GaugeValue,
+		1.0,
+	)
+
+	if metrics.HasMaxGuests {
+		ch <- prometheus.MustNewConstMetric(
+			c.hostSEVMaxGuests,
+			prometheus.GaugeValue,
+			float64(metrics.MaxGuests),
+		)
+	}
+
+	if metrics.HasMaxESGuests {
+		ch <- prometheus.MustNewConstMetric(
+			c.hostSEVMaxES,
+			prometheus.GaugeValue,
+			float64(metrics.MaxESGuests),
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostSEVCBitPos,
+		prometheus.GaugeValue,
+		float64(metrics.CBitPos),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.hostSEVPhysBits,
+		prometheus.GaugeValue,
+		float64(metrics.ReducedPhysBits),
+	)
+}
@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LeaderElector elects one active exporter among redundant replicas by
+// holding an exclusive lock on a shared file. The lock is released when the
+// leader exits, letting a standby take over on its next attempt.
+type LeaderElector struct {
+	path     string
+	interval time.Duration
+	leader   int32 // atomic flag
+	file     *os.File
+	stop     chan struct{}
+	desc     *prometheus.Desc
+}
+
+// NewLeaderElector creates a LeaderElector and starts trying to acquire the
+// lock every interval
+func NewLeaderElector(path string, interval time.Duration) *LeaderElector {
+	e := &LeaderElector{
+		path:     path,
+		interval: interval,
+		stop:     make(chan struct{}),
+		desc: prometheus.NewDesc(
+			"libvirt_exporter_leader",
+			"Whether this exporter replica is the active leader (1=leader, 0=standby serving stale data)",
+			[]string{},
+			nil,
+		),
+	}
+
+	go e.run()
+	return e
+}
+
+// run tries to acquire the lock until it is held or Stop is called
+func (e *LeaderElector) run() {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if e.file == nil && e.tryAcquire() {
+			slog.Info("Acquired leader lock, this replica is now active", "path", e.path)
+			atomic.StoreInt32(&e.leader, 1)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-e.stop:
+			if e.file != nil {
+				atomic.StoreInt32(&e.leader, 0)
+				syscall.Flock(int(e.file.Fd()), syscall.LOCK_UN)
+				e.file.Close()
+				e.file = nil
+			}
+			return
+		}
+	}
+}
+
+// tryAcquire takes the lock without blocking
+func (e *LeaderElector) tryAcquire() bool {
+	file, err := os.OpenFile(e.path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		slog.Warn("Failed to open leader lock", "path", e.path, "err", err)
+		return false
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		return false
+	}
+
+	e.file = file
+	return true
+}
+
+// IsLeader reports whether this replica holds the lock
+func (e *LeaderElector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+// Stop releases the lock and stops the election
+func (e *LeaderElector) Stop() {
+	close(e.stop)
+}
+
+// Describe sends the descriptor of the leader metric
+func (e *LeaderElector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.desc
+}
+
+// metric returns the leader metric
+func (e *LeaderElector) metric() prometheus.Metric {
+	var leaderValue float64
+	if e.IsLeader() {
+		leaderValue = 1.0
+	}
+	return prometheus.MustNewConstMetric(e.desc, prometheus.GaugeValue, leaderValue)
+}
@@ -1,25 +1,157 @@
 package collector
 
 import (
+	"context"
 	"encoding/xml"
-	"log"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"libvirt.org/go/libvirt"
 	"libvirt.org/go/libvirtxml"
 )
 
+// Host interface sources
+const (
+	// HostInterfacesLibvirt collects the interfaces managed by libvirt
+	HostInterfacesLibvirt = "libvirt"
+	// HostInterfacesProc collects every interface of /proc/net/dev
+	HostInterfacesProc = "proc"
+	// HostInterfacesNone disables host interface statistics
+	HostInterfacesNone = "none"
+)
+
 // LibvirtMetricsCollector implements MetricsCollector to fetch raw metrics from libvirt
-type LibvirtMetricsCollector struct{}
+type LibvirtMetricsCollector struct {
+	sanitizer *LabelSanitizer
+	counters  *CounterTracker
+	pidDir    string
+	vhostUser VHostUserBackend
+	sriov     *SRIOVStats
+	devices   *BlockDeviceCache
+	hostIfs   string
+	hostname  bool // read guest hostnames from the guest agent
+	blockJobs bool // read the block job of each disk
+	throttle  bool // read the I/O throttle limits of each disk
+
+	// Host CPU times of the previous call, used to compute CPU usage
+	cpuMutex sync.Mutex
+	lastCPU  *libvirt.NodeCPUStats
+
+	// vhost-user and SR-IOV counters are read on the exporter's host, so
+	// they are only collected when the connection targets it
+	localOnce sync.Once
+	local     bool
+
+	// Domain definitions fetched during the current scrape, keyed by UUID
+	xmlMutex sync.Mutex
+	xmls     map[string]*domainXMLEntry
+}
+
+// domainXMLEntry is a domain definition shared by the collectors of a
+// scrape, parsed when first needed
+type domainXMLEntry struct {
+	fetchOnce sync.Once
+	desc      string
+	fetchErr  error
+
+	parseOnce sync.Once
+	domainXML *libvirtxml.Domain
+	parseErr  error
+}
+
+// NewLibvirtMetricsCollector creates a new LibvirtMetricsCollector. pidDir is
+// the directory holding the QEMU PID files written by libvirtd; vhostUser
+// provides the counters of vhost-user interfaces, sriov those of passed
+// through SR-IOV interfaces and devices caches the block devices of domain
+// definitions; all may be nil. hostInterfaces selects the host interfaces
+// whose counters are collected. guestHostname enables reading the hostname
+// of running domains from their guest agent; blockJobs and throttle enable
+// reading the block job and the throttle limits of each disk.
+func NewLibvirtMetricsCollector(
+	sanitizer *LabelSanitizer,
+	counters *CounterTracker,
+	pidDir string,
+	vhostUser VHostUserBackend,
+	sriov *SRIOVStats,
+	devices *BlockDeviceCache,
+	hostInterfaces string,
+	guestHostname bool,
+	blockJobs bool,
+	throttle bool,
+) (*LibvirtMetricsCollector, error) {
+	switch hostInterfaces {
+	case "", HostInterfacesLibvirt:
+		hostInterfaces = HostInterfacesLibvirt
+	case HostInterfacesProc, HostInterfacesNone:
+	default:
+		return nil, fmt.Errorf("unknown host interface source %q", hostInterfaces)
+	}
+
+	return &LibvirtMetricsCollector{
+		sanitizer: sanitizer,
+		counters:  counters,
+		pidDir:    pidDir,
+		vhostUser: vhostUser,
+		sriov:     sriov,
+		devices:   devices,
+		hostIfs:   hostInterfaces,
+		hostname:  guestHostname,
+		blockJobs: blockJobs,
+		throttle:  throttle,
+	}, nil
+}
+
+// Reset drops the state kept for a single scrape
+func (mc *LibvirtMetricsCollector) Reset() {
+	if mc.vhostUser != nil {
+		mc.vhostUser.Reset()
+	}
+	mc.forgetDomainXML()
+}
+
+// forgetDomainXML drops the domain definitions fetched during the scrape
+func (mc *LibvirtMetricsCollector) forgetDomainXML() {
+	mc.xmlMutex.Lock()
+	mc.xmls = nil
+	mc.xmlMutex.Unlock()
+}
+
+// isLocal reports whether conn targets the exporter's host, where vhost-user
+// and SR-IOV counters are read
+func (mc *LibvirtMetricsCollector) isLocal(conn *libvirt.Connect) bool {
+	mc.localOnce.Do(func() {
+		mc.local = isLocalConnection(conn)
+		if !mc.local {
+			slog.Warn("libvirt connection is not local, vhost-user and SR-IOV interface metrics are disabled")
+		}
+	})
+	return mc.local
+}
+
+// domainLabels returns the sanitized domain name and UUID used as metric labels
+func (mc *LibvirtMetricsCollector) domainLabels(domain *libvirt.Domain) (string, string, error) {
+	domainName, err := domain.GetName()
+	if err != nil {
+		return "", "", err
+	}
+
+	domainUUID, err := domain.GetUUIDString()
+	if err != nil {
+		return "", "", err
+	}
 
-// NewLibvirtMetricsCollector creates a new LibvirtMetricsCollector
-func NewLibvirtMetricsCollector() *LibvirtMetricsCollector {
-	return &LibvirtMetricsCollector{}
+	return mc.sanitizer.Label(domainName, domainUUID), domainUUID, nil
 }
 
 // CollectDomainInfo collects basic domain information from libvirt
 func (mc *LibvirtMetricsCollector) CollectDomainInfo(
+	ctx context.Context,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) (*DomainInfoMetrics, error) {
@@ -28,12 +160,7 @@ func (mc *LibvirtMetricsCollector) CollectDomainInfo(
 		return nil, err
 	}
 
-	domainName, err := domain.GetName()
-	if err != nil {
-		return nil, err
-	}
-
-	domainUUID, err := domain.GetUUIDString()
+	domainName, domainUUID, err := mc.domainLabels(domain)
 	if err != nil {
 		return nil, err
 	}
@@ -59,6 +186,8 @@ func (mc *LibvirtMetricsCollector) CollectDomainInfo(
 	metrics := &DomainInfoMetrics{
 		Name:          domainName,
 		UUID:          domainUUID,
+		State:         domainStateToString(domainInfo.State),
+		VCPUs:         domainInfo.NrVirtCpu,
 		MemoryCurrent: float64(domainInfo.Memory) * 1024,
 		MemoryMax:     float64(domainInfo.MaxMem) * 1024,
 		CPUTime:       float64(domainInfo.CpuTime) / 1e9,
@@ -74,6 +203,14 @@ func (mc *LibvirtMetricsCollector) CollectDomainInfo(
 		metrics.Status = 0.0
 	}
 
+	// The reason explains how the domain got into its state (e.g. shut off
+	// after a crash or on user request); it is dropped if the state changed
+	// since GetInfo so it always matches the state label
+	state, reason, err := domain.GetState()
+	if err == nil && state == domainInfo.State {
+		metrics.StateReason = domainStateReasonToString(state, reason)
+	}
+
 	// Only collect uptime for running domains
 	if domainInfo.State == libvirt.DOMAIN_RUNNING {
 		domainTime, _, err := domain.GetTime(0)
@@ -82,22 +219,93 @@ func (mc *LibvirtMetricsCollector) CollectDomainInfo(
 			metrics.Uptime = time.Since(metrics.BootTime).Seconds()
 			metrics.HasUptime = true
 		}
+
+		// Guest hostname requires the guest agent, which may not be installed
+		if mc.hostname {
+			guestHostname, err := domain.GetHostname(libvirt.DOMAIN_GET_HOSTNAME_AGENT)
+			if err == nil {
+				metrics.GuestHostname = guestLabel(guestHostname)
+			}
+		}
+	}
+
+	// Inactive domains have no ID
+	if id, err := domain.GetID(); err == nil {
+		metrics.ID = id
+		metrics.HasID = true
+	}
+
+	// An automatically generated genid only shows up in the live XML
+	domainXML, err := mc.getDomainXML(domain)
+	if err != nil {
+		slog.Warn("Failed to get domain XML for genid, CPU topology and security labels", "domain", domainName, "err", err)
+	} else {
+		if domainXML.GenID != nil {
+			metrics.GenID = strings.TrimSpace(domainXML.GenID.Value)
+		}
+		metrics.CPUSockets, metrics.CPUCores, metrics.CPUThreads = cpuTopology(domainXML)
+		metrics.SecLabels = secLabels(domain, domainXML, domainInfo.State == libvirt.DOMAIN_RUNNING)
 	}
 
 	return metrics, nil
 }
 
+// secLabels returns the security labels of a domain. Dynamic labels are only
+// in the live XML of running domains; labels missing there are taken from
+// the labels the security drivers report for the running process, which
+// libvirt lists in the order of the seclabel elements.
+func secLabels(domain *libvirt.Domain, domainXML *libvirtxml.Domain, running bool) []SecLabel {
+	var processLabels []libvirt.SecurityLabel
+	if running {
+		processLabels, _ = domain.GetSecurityLabelList()
+	}
+
+	labels := make([]SecLabel, 0, len(domainXML.SecLabel))
+	for i, seclabel := range domainXML.SecLabel {
+		label := SecLabel{
+			Model: seclabel.Model,
+			Type:  seclabel.Type,
+			Label: seclabel.Label,
+		}
+		if label.Type == "" {
+			label.Type = "dynamic"
+		}
+		if label.Label == "" && label.Type != "none" && i < len(processLabels) {
+			label.Label = processLabels[i].Label
+		}
+		switch seclabel.Relabel {
+		case "yes":
+			label.Relabel = true
+		case "":
+			// Dynamic labels are always applied, static ones are not by default
+			label.Relabel = label.Type == "dynamic"
+		}
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// cpuTopology returns the configured vCPU sockets, cores per socket and
+// threads per core of a domain. Without an explicit topology every vCPU is a
+// socket of its own, as QEMU lays them out.
+func cpuTopology(domainXML *libvirtxml.Domain) (int, int, int) {
+	if domainXML.CPU != nil && domainXML.CPU.Topology != nil && domainXML.CPU.Topology.Sockets > 0 {
+		topology := domainXML.CPU.Topology
+		return topology.Sockets, max(topology.Cores, 1), max(topology.Threads, 1)
+	}
+	if domainXML.VCPU != nil && domainXML.VCPU.Value > 0 {
+		return int(domainXML.VCPU.Value), 1, 1
+	}
+	return 0, 0, 0
+}
+
 // CollectCPUStats collects CPU statistics from libvirt
 func (mc *LibvirtMetricsCollector) CollectCPUStats(
+	ctx context.Context,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) (*CPUStatsMetrics, error) {
-	domainName, err := domain.GetName()
-	if err != nil {
-		return nil, err
-	}
-
-	domainUUID, err := domain.GetUUIDString()
+	domainName, domainUUID, err := mc.domainLabels(domain)
 	if err != nil {
 		return nil, err
 	}
@@ -127,21 +335,112 @@ func (mc *LibvirtMetricsCollector) CollectCPUStats(
 		VCPUsCurrent: uint(len(vcpuInfo)),
 		CPUTime:      domainInfo.CpuTime,
 	}
+	for _, vcpu := range vcpuInfo {
+		metrics.VCPUs = append(metrics.VCPUs, VCPUMetrics{
+			Number:  vcpu.Number,
+			State:   vcpuStateToString(libvirt.VcpuState(vcpu.State)),
+			CPUTime: vcpu.CpuTime,
+		})
+	}
+
+	mc.applySchedulerParams(domain, metrics)
+	applyVcpuPinning(domain, metrics)
+
+	// Guest user and system time come from the cgroup of the domain
+	if domainInfo.State == libvirt.DOMAIN_RUNNING {
+		cpuStats, err := domain.GetCPUStats(-1, 1, 0)
+		if err == nil && len(cpuStats) > 0 {
+			metrics.UserTime = cpuStats[0].UserTime
+			metrics.SystemTime = cpuStats[0].SystemTime
+		}
+
+		records, err := conn.GetAllDomainStats([]*libvirt.Domain{domain}, libvirt.DOMAIN_STATS_VCPU, 0)
+		if err == nil {
+			for i := range records {
+				records[i].Domain.Free()
+			}
+			if len(records) > 0 {
+				applyVcpuDelays(metrics, records[0].Vcpu)
+			}
+		}
+	}
 
 	return metrics, nil
 }
 
+// applySchedulerParams adds the CPU bandwidth and weight settings of the
+// domain scheduler; which ones are reported depends on the driver
+func (mc *LibvirtMetricsCollector) applySchedulerParams(domain *libvirt.Domain, metrics *CPUStatsMetrics) {
+	params, err := domain.GetSchedulerParameters()
+	if err != nil {
+		slog.Debug("Failed to get scheduler parameters", "domain", metrics.Name, "err", err)
+		return
+	}
+
+	metrics.Scheduler = params.Type
+	if params.VcpuQuotaSet && params.VcpuPeriodSet {
+		metrics.HasQuota = true
+		metrics.Quota = params.VcpuQuota
+		metrics.Period = int64(params.VcpuPeriod)
+	}
+	if params.CpuSharesSet {
+		metrics.HasShares = true
+		metrics.Shares = params.CpuShares
+	}
+	if params.EmulatorQuotaSet {
+		metrics.HasEmulator = true
+		metrics.EmulatorQuota = params.EmulatorQuota
+	}
+}
+
+// applyVcpuPinning adds the host CPUs each vCPU may run on
+func applyVcpuPinning(domain *libvirt.Domain, metrics *CPUStatsMetrics) {
+	if len(metrics.VCPUs) == 0 {
+		return
+	}
+
+	pinInfo, err := domain.GetVcpuPinInfo(libvirt.DOMAIN_AFFECT_CURRENT)
+	if err != nil {
+		slog.Debug("Failed to get vCPU pinning", "domain", metrics.Name, "err", err)
+		return
+	}
+
+	for i := range metrics.VCPUs {
+		vcpu := &metrics.VCPUs[i]
+		if int(vcpu.Number) >= len(pinInfo) {
+			continue
+		}
+		cpuMap := pinInfo[vcpu.Number]
+		vcpu.CPUs = []int{}
+		for cpu, allowed := range cpuMap {
+			if allowed {
+				vcpu.CPUs = append(vcpu.CPUs, cpu)
+			}
+		}
+		vcpu.Pinned = len(vcpu.CPUs) < len(cpuMap)
+	}
+}
+
+// applyVcpuDelays sums the steal (delay) and wait times of the vCPUs; both
+// are only reported by recent QEMU drivers
+func applyVcpuDelays(metrics *CPUStatsMetrics, vcpus []libvirt.DomainStatsVcpu) {
+	for _, vcpu := range vcpus {
+		if vcpu.DelaySet {
+			metrics.StealTime += vcpu.Delay
+		}
+		if vcpu.WaitSet {
+			metrics.WaitTime += vcpu.Wait
+		}
+	}
+}
+
 // CollectMemoryStats collects memory statistics from libvirt
 func (mc *LibvirtMetricsCollector) CollectMemoryStats(
+	ctx context.Context,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) (*MemoryStatsMetrics, error) {
-	domainName, err := domain.GetName()
-	if err != nil {
-		return nil, err
-	}
-
-	domainUUID, err := domain.GetUUIDString()
+	domainName, domainUUID, err := mc.domainLabels(domain)
 	if err != nil {
 		return nil, err
 	}
@@ -179,16 +478,84 @@ func (mc *LibvirtMetricsCollector) CollectMemoryStats(
 		}
 	}
 
-	// Calculate total memory
-	if metrics.Available > 0 && metrics.Unused > 0 {
-		metrics.Total = metrics.Available
-	}
+	mc.applyMemoryConfig(domain, metrics)
 
 	return metrics, nil
 }
 
+// applyMemoryConfig fills in the memory statistics derived from the domain
+// XML: the configured memory and the balloon device settings
+func (mc *LibvirtMetricsCollector) applyMemoryConfig(domain *libvirt.Domain, metrics *MemoryStatsMetrics) {
+	// Total assigned memory is the configured current memory of the domain,
+	// independent of whether the guest reports balloon statistics
+	domainXML, err := mc.getDomainXML(domain)
+	if err == nil && domainXML.CurrentMemory != nil {
+		metrics.Total = scaleToBytes(uint64(domainXML.CurrentMemory.Value), currentMemoryUnit(domainXML)) / 1024
+	} else if domainInfo, err := domain.GetInfo(); err == nil {
+		metrics.Total = domainInfo.Memory
+	}
+
+	if err != nil {
+		return
+	}
+
+	if domainXML.Devices != nil {
+		if balloon := domainXML.Devices.MemBalloon; balloon != nil && balloon.Model != "none" {
+			metrics.HasBalloon = true
+			metrics.FreePageReporting = balloon.FreePageReporting == "on"
+		}
+	}
+
+	// Memory returned to the host is the part of the maximum memory the
+	// balloon currently holds back from the guest
+	if domainXML.Memory != nil && metrics.BalloonSize > 0 {
+		maxKB := scaleToBytes(uint64(domainXML.Memory.Value), memoryUnit(domainXML.Memory.Unit)) / 1024
+		if maxKB > metrics.BalloonSize {
+			metrics.Reclaimed = maxKB - metrics.BalloonSize
+		}
+	}
+
+	mc.applyNUMAConfig(domain, domainXML, metrics)
+}
+
+// applyNUMAConfig fills the guest NUMA nodes from the domain XML and the host
+// nodes their memory is bound to from the NUMA tuning parameters
+func (mc *LibvirtMetricsCollector) applyNUMAConfig(
+	domain *libvirt.Domain,
+	domainXML *libvirtxml.Domain,
+	metrics *MemoryStatsMetrics,
+) {
+	if domainXML.CPU != nil && domainXML.CPU.Numa != nil {
+		for i, cell := range domainXML.CPU.Numa.Cell {
+			nodeID := i
+			if cell.ID != nil {
+				nodeID = int(*cell.ID)
+			}
+			metrics.NUMANodes = append(metrics.NUMANodes, NUMANodeMemory{
+				NodeID:  nodeID,
+				TotalKB: scaleToBytes(uint64(cell.Memory), memoryUnit(cell.Unit)) / 1024,
+			})
+		}
+	}
+
+	// The live parameters reflect automatic placement by numad, the XML is
+	// used for inactive domains
+	params, err := domain.GetNumaParameters(libvirt.DOMAIN_AFFECT_CURRENT)
+	if err == nil && params.ModeSet {
+		metrics.NUMAMode = numatuneModeToString(params.Mode)
+		metrics.NUMANodeset = params.Nodeset
+	} else if domainXML.NUMATune != nil && domainXML.NUMATune.Memory != nil {
+		metrics.NUMAMode = domainXML.NUMATune.Memory.Mode
+		if metrics.NUMAMode == "" {
+			metrics.NUMAMode = "strict"
+		}
+		metrics.NUMANodeset = domainXML.NUMATune.Memory.Nodeset
+	}
+}
+
 // CollectDiskStats collects disk I/O statistics from libvirt
 func (mc *LibvirtMetricsCollector) CollectDiskStats(
+	ctx context.Context,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) ([]DiskMetrics, error) {
@@ -202,12 +569,7 @@ func (mc *LibvirtMetricsCollector) CollectDiskStats(
 		return []DiskMetrics{}, nil
 	}
 
-	domainName, err := domain.GetName()
-	if err != nil {
-		return nil, err
-	}
-
-	domainUUID, err := domain.GetUUIDString()
+	domainName, domainUUID, err := mc.domainLabels(domain)
 	if err != nil {
 		return nil, err
 	}
@@ -215,9 +577,16 @@ func (mc *LibvirtMetricsCollector) CollectDiskStats(
 	var metrics []DiskMetrics
 
 	// Try to discover devices dynamically
-	devices := mc.discoverBlockDevices(domain)
+	devices, configs := mc.discoverBlockDevices(domain)
 
 	for _, device := range devices {
+		// Stop issuing libvirt calls once the collector is out of time
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var m DiskMetrics
+
 		// Get detailed block stats
 		stats, err := domain.BlockStatsFlags(device, 0)
 		if err != nil {
@@ -227,7 +596,7 @@ func (mc *LibvirtMetricsCollector) CollectDiskStats(
 				continue
 			}
 
-			m := DiskMetrics{
+			m = DiskMetrics{
 				Name:       domainName,
 				UUID:       domainUUID,
 				Device:     device,
@@ -237,9 +606,8 @@ func (mc *LibvirtMetricsCollector) CollectDiskStats(
 				ReadOps:    uint64(basicStats.RdReq),
 				WriteOps:   uint64(basicStats.WrReq),
 			}
-			metrics = append(metrics, m)
 		} else {
-			m := DiskMetrics{
+			m = DiskMetrics{
 				Name:        domainName,
 				UUID:        domainUUID,
 				Device:      device,
@@ -250,16 +618,118 @@ func (mc *LibvirtMetricsCollector) CollectDiskStats(
 				WriteOps:    uint64(stats.WrReq),
 				ReadTimeNs:  uint64(stats.RdTotalTimes),
 				WriteTimeNs: uint64(stats.WrTotalTimes),
+				FlushOps:    uint64(stats.FlushReq),
+				FlushTimeNs: uint64(stats.FlushTotalTimes),
 			}
-			metrics = append(metrics, m)
 		}
+
+		// Sizes are unavailable for e.g. empty CD-ROM drives
+		if blockInfo, err := domain.GetBlockInfo(device, 0); err == nil {
+			m.Capacity = blockInfo.Capacity
+			m.Allocation = blockInfo.Allocation
+			m.Physical = blockInfo.Physical
+		}
+
+		metrics = append(metrics, m)
 	}
 
+	mc.finishDiskMetrics(domain, metrics, configs)
+
 	return metrics, nil
 }
 
+// finishDiskMetrics adds the settings of the disk definitions and, if
+// enabled, the active block jobs and the throttle limits to the disk metrics
+// and passes their counters through the wrap tracker
+func (mc *LibvirtMetricsCollector) finishDiskMetrics(
+	domain *libvirt.Domain,
+	metrics []DiskMetrics,
+	configs map[string]*libvirtxml.DomainDisk,
+) {
+	for i := range metrics {
+		m := &metrics[i]
+		config := configs[m.Device]
+		m.Encrypted, m.EncryptionFormat = diskEncryption(config)
+		m.Source = diskSource(config)
+		if config != nil && config.Target != nil {
+			m.Bus = config.Target.Bus
+		}
+		if config != nil && config.Driver != nil {
+			m.Format = config.Driver.Type
+			m.CacheMode = config.Driver.Cache
+			m.IOMode = config.Driver.IO
+			m.Discard = config.Driver.Discard
+		}
+		if mc.blockJobs {
+			m.BlockJob = collectBlockJob(domain, m.Device)
+		}
+		if mc.throttle {
+			m.IOTune = collectBlockIOTune(domain, m.Device)
+		}
+	}
+
+	// Some drivers report 32-bit counters that wrap around
+	for i := range metrics {
+		m := &metrics[i]
+		m.ReadBytes = mc.observeCounter("disk", m.UUID, m.Device, "read_bytes", m.ReadBytes)
+		m.WriteBytes = mc.observeCounter("disk", m.UUID, m.Device, "write_bytes", m.WriteBytes)
+		m.ReadOps = mc.observeCounter("disk", m.UUID, m.Device, "read_ops", m.ReadOps)
+		m.WriteOps = mc.observeCounter("disk", m.UUID, m.Device, "write_ops", m.WriteOps)
+		m.FlushOps = mc.observeCounter("disk", m.UUID, m.Device, "flush_ops", m.FlushOps)
+	}
+}
+
+// collectBlockJob returns the block job running on a disk, or nil if there
+// is none
+func collectBlockJob(domain *libvirt.Domain, device string) *BlockJobMetrics {
+	info, err := domain.GetBlockJobInfo(device, libvirt.DOMAIN_BLOCK_JOB_INFO_BANDWIDTH_BYTES)
+	if err != nil {
+		slog.Debug("Failed to get block job info", "device", device, "err", err)
+		return nil
+	}
+	// libvirt reports no job as a zeroed job of unknown type
+	if info.Type == libvirt.DOMAIN_BLOCK_JOB_TYPE_UNKNOWN {
+		return nil
+	}
+
+	job := &BlockJobMetrics{
+		Type:      blockJobTypeToString(info.Type),
+		Bandwidth: info.Bandwidth,
+		Cursor:    info.Cur,
+		End:       info.End,
+	}
+	if info.End > 0 {
+		job.Progress = float64(info.Cur) / float64(info.End)
+	}
+	return job
+}
+
+// collectBlockIOTune returns the throttle limits of a disk, or nil if the
+// disk is not throttled
+func collectBlockIOTune(domain *libvirt.Domain, device string) *BlockIOTuneMetrics {
+	params, err := domain.GetBlockIoTune(device, libvirt.DOMAIN_AFFECT_LIVE)
+	if err != nil {
+		slog.Debug("Failed to get block I/O tune", "device", device, "err", err)
+		return nil
+	}
+
+	iotune := BlockIOTuneMetrics{
+		TotalBytesSec: params.TotalBytesSec,
+		ReadBytesSec:  params.ReadBytesSec,
+		WriteBytesSec: params.WriteBytesSec,
+		TotalIOPSSec:  params.TotalIopsSec,
+		ReadIOPSSec:   params.ReadIopsSec,
+		WriteIOPSSec:  params.WriteIopsSec,
+	}
+	if iotune == (BlockIOTuneMetrics{}) {
+		return nil
+	}
+	return &iotune
+}
+
 // CollectNetworkStats collects network I/O statistics from libvirt
 func (mc *LibvirtMetricsCollector) CollectNetworkStats(
+	ctx context.Context,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) ([]NetworkMetrics, error) {
@@ -273,12 +743,7 @@ func (mc *LibvirtMetricsCollector) CollectNetworkStats(
 		return []NetworkMetrics{}, nil
 	}
 
-	domainName, err := domain.GetName()
-	if err != nil {
-		return nil, err
-	}
-
-	domainUUID, err := domain.GetUUIDString()
+	domainName, domainUUID, err := mc.domainLabels(domain)
 	if err != nil {
 		return nil, err
 	}
@@ -286,19 +751,54 @@ func (mc *LibvirtMetricsCollector) CollectNetworkStats(
 	var metrics []NetworkMetrics
 
 	// Try to discover interfaces dynamically
-	interfaces := mc.discoverNetworkInterfaces(domain)
+	interfaces, configs := mc.discoverNetworkInterfaces(domain)
 
 	for _, ifaceName := range interfaces {
-		// Get interface stats
-		stats, err := domain.InterfaceStats(ifaceName)
-		if err != nil {
-			continue
+		// Stop issuing libvirt calls once the collector is out of time
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		ifaceType := ""
+		var stats *libvirt.DomainInterfaceStats
+		config := configs[ifaceName]
+		if port := vhostUserPort(config); port != "" {
+			// libvirt cannot see vhost-user traffic, ask the backend instead
+			if mc.vhostUser == nil || !mc.isLocal(conn) {
+				continue
+			}
+			stats, err = mc.vhostUser.InterfaceStats(port)
+			if err != nil {
+				slog.Warn("Failed to get vhost-user stats",
+					"domain", domainName, "interface", ifaceName, "err", err)
+				continue
+			}
+			ifaceType = "vhostuser"
+		} else if address := hostdevAddress(config); address != "" {
+			// InterfaceStats does not work for passthrough NICs
+			if mc.sriov == nil || !mc.isLocal(conn) {
+				continue
+			}
+			stats, err = mc.sriov.InterfaceStats(address)
+			if err != nil {
+				slog.Warn("Failed to get SR-IOV interface stats",
+					"domain", domainName, "interface", ifaceName, "err", err)
+				continue
+			}
+			ifaceType = "hostdev"
+		} else {
+			// Get interface stats
+			stats, err = domain.InterfaceStats(ifaceName)
+			if err != nil {
+				continue
+			}
 		}
 
 		m := NetworkMetrics{
 			Name:      domainName,
 			UUID:      domainUUID,
 			Interface: ifaceName,
+			Type:      ifaceType,
 			RxBytes:   uint64(stats.RxBytes),
 			TxBytes:   uint64(stats.TxBytes),
 			RxPackets: uint64(stats.RxPackets),
@@ -311,42 +811,221 @@ func (mc *LibvirtMetricsCollector) CollectNetworkStats(
 		metrics = append(metrics, m)
 	}
 
+	mc.finishNetworkMetrics(metrics, configs)
+
 	return metrics, nil
 }
 
-// discoverBlockDevices attempts to discover available block devices for a domain using XML parsing
-func (mc *LibvirtMetricsCollector) discoverBlockDevices(domain *libvirt.Domain) []string {
+// finishNetworkMetrics adds the settings of the interface definitions to the
+// network metrics and passes their counters through the wrap tracker
+func (mc *LibvirtMetricsCollector) finishNetworkMetrics(
+	metrics []NetworkMetrics,
+	configs map[string]*libvirtxml.DomainInterface,
+) {
+	for i := range metrics {
+		m := &metrics[i]
+		m.Queues = 1
+		config := configs[m.Interface]
+		if config == nil {
+			continue
+		}
+		if config.MAC != nil {
+			m.MACAddress = config.MAC.Address
+		}
+		if config.Model != nil {
+			m.Model = config.Model.Type
+		}
+		if m.Type == "" {
+			m.Type = interfaceType(config)
+		}
+		if source := config.Source; source != nil {
+			switch {
+			case source.Network != nil:
+				m.Network = source.Network.Network
+				m.Bridge = source.Network.Bridge
+			case source.Bridge != nil:
+				m.Bridge = source.Bridge.Bridge
+			}
+		}
+		if config.Driver != nil {
+			m.Driver = config.Driver.Name
+			if config.Driver.Queues > 1 {
+				m.Queues = config.Driver.Queues
+				m.Multiqueue = true
+			}
+		}
+	}
+
+	// Some drivers report 32-bit counters that wrap around
+	for i := range metrics {
+		m := &metrics[i]
+		m.RxBytes = mc.observeCounter("network", m.UUID, m.Interface, "rx_bytes", m.RxBytes)
+		m.TxBytes = mc.observeCounter("network", m.UUID, m.Interface, "tx_bytes", m.TxBytes)
+		m.RxPackets = mc.observeCounter("network", m.UUID, m.Interface, "rx_packets", m.RxPackets)
+		m.TxPackets = mc.observeCounter("network", m.UUID, m.Interface, "tx_packets", m.TxPackets)
+	}
+}
+
+// observeCounter passes a raw device counter through the wrap tracker
+func (mc *LibvirtMetricsCollector) observeCounter(
+	subsystem, uuid, device, counter string,
+	value uint64,
+) uint64 {
+	return mc.counters.Observe(subsystem, uuid+"/"+device+"/"+counter, value)
+}
+
+// getDomainXML returns the parsed XML description of a domain. It is
+// fetched and parsed once per scrape and shared by all collectors, which must
+// not modify it.
+func (mc *LibvirtMetricsCollector) getDomainXML(domain *libvirt.Domain) (*libvirtxml.Domain, error) {
+	entry, err := mc.domainDefinition(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.parseOnce.Do(func() {
+		var domainXML libvirtxml.Domain
+		if entry.parseErr = xml.Unmarshal([]byte(entry.desc), &domainXML); entry.parseErr == nil {
+			entry.domainXML = &domainXML
+		}
+	})
+
+	return entry.domainXML, entry.parseErr
+}
+
+// getDomainXMLDesc returns the XML description of a domain, fetched once per
+// scrape
+func (mc *LibvirtMetricsCollector) getDomainXMLDesc(domain *libvirt.Domain) (string, error) {
+	entry, err := mc.domainDefinition(domain)
+	if err != nil {
+		return "", err
+	}
+	return entry.desc, nil
+}
+
+// domainDefinition returns the definition of a domain in the current scrape,
+// fetching its XML description on first use
+func (mc *LibvirtMetricsCollector) domainDefinition(domain *libvirt.Domain) (*domainXMLEntry, error) {
+	domainUUID, err := domain.GetUUIDString()
+	if err != nil {
+		return nil, err
+	}
+
+	mc.xmlMutex.Lock()
+	if mc.xmls == nil {
+		mc.xmls = make(map[string]*domainXMLEntry)
+	}
+	entry, ok := mc.xmls[domainUUID]
+	if !ok {
+		entry = &domainXMLEntry{}
+		mc.xmls[domainUUID] = entry
+	}
+	mc.xmlMutex.Unlock()
+
+	entry.fetchOnce.Do(func() {
+		entry.desc, entry.fetchErr = domain.GetXMLDesc(0)
+	})
+	if entry.fetchErr != nil {
+		return nil, entry.fetchErr
+	}
+	return entry, nil
+}
+
+// discoverBlockDevices attempts to discover available block devices for a domain using XML parsing.
+// It also returns the XML definition of each discovered disk, keyed by target device.
+// Results of an unchanged definition are served from the block device cache.
+func (mc *LibvirtMetricsCollector) discoverBlockDevices(
+	domain *libvirt.Domain,
+) ([]string, map[string]*libvirtxml.DomainDisk) {
 	var devices []string
+	configs := make(map[string]*libvirtxml.DomainDisk)
 
 	// Get domain XML description
-	xmlDesc, err := domain.GetXMLDesc(0)
+	xmlDesc, err := mc.getDomainXMLDesc(domain)
 	if err != nil {
-		log.Printf("Warning: Failed to get domain XML: %v", err)
-		return mc.fallbackBlockDeviceDiscovery(domain)
+		slog.Warn("Failed to get domain XML", "err", err)
+		return mc.fallbackBlockDeviceDiscovery(domain), nil
+	}
+
+	var domainUUID string
+	generation := xmlGeneration(xmlDesc)
+	if mc.devices != nil {
+		domainUUID, err = domain.GetUUIDString()
+		if err == nil {
+			if devices, configs, ok := mc.devices.Get(domainUUID, generation); ok {
+				return devices, configs
+			}
+		}
 	}
 
 	// Parse the XML
-	var domainXML libvirtxml.Domain
-	if err := xml.Unmarshal([]byte(xmlDesc), &domainXML); err != nil {
-		log.Printf("Warning: Failed to parse domain XML: %v", err)
-		return mc.fallbackBlockDeviceDiscovery(domain)
+	domainXML, err := mc.getDomainXML(domain)
+	if err != nil {
+		slog.Warn("Failed to parse domain XML", "err", err)
+		return mc.fallbackBlockDeviceDiscovery(domain), nil
 	}
 
 	// Extract disk devices from XML
 	if domainXML.Devices != nil {
-		for _, disk := range domainXML.Devices.Disks {
+		for i := range domainXML.Devices.Disks {
+			disk := &domainXML.Devices.Disks[i]
 			if disk.Target != nil && disk.Target.Dev != "" {
 				devices = append(devices, disk.Target.Dev)
+				configs[disk.Target.Dev] = disk
 			}
 		}
 	}
 
 	// If XML parsing didn't find any devices, fall back to trial-and-error
 	if len(devices) == 0 {
-		return mc.fallbackBlockDeviceDiscovery(domain)
+		return mc.fallbackBlockDeviceDiscovery(domain), nil
 	}
 
-	return devices
+	if mc.devices != nil && domainUUID != "" {
+		mc.devices.Put(domainUUID, generation, devices, configs)
+	}
+
+	return devices, configs
+}
+
+// diskEncryption reports whether a disk has encryption configured and its
+// format (e.g. "luks"). Encryption may be defined on the disk or its source.
+func diskEncryption(disk *libvirtxml.DomainDisk) (bool, string) {
+	if disk == nil {
+		return false, ""
+	}
+	encryption := disk.Encryption
+	if encryption == nil && disk.Source != nil {
+		encryption = disk.Source.Encryption
+	}
+	if encryption == nil {
+		return false, ""
+	}
+	return true, encryption.Format
+}
+
+// diskSource returns the source of a disk definition: the backing file or
+// block device, "pool/volume" for storage volumes and "protocol://name" for
+// network disks
+func diskSource(disk *libvirtxml.DomainDisk) string {
+	if disk == nil || disk.Source == nil {
+		return ""
+	}
+	source := disk.Source
+	switch {
+	case source.File != nil:
+		return source.File.File
+	case source.Block != nil:
+		return source.Block.Dev
+	case source.Dir != nil:
+		return source.Dir.Dir
+	case source.Volume != nil:
+		return source.Volume.Pool + "/" + source.Volume.Volume
+	case source.Network != nil:
+		return source.Network.Protocol + "://" + source.Network.Name
+	default:
+		return ""
+	}
 }
 
 // fallbackBlockDeviceDiscovery uses trial-and-error method as fallback
@@ -385,39 +1064,93 @@ func (mc *LibvirtMetricsCollector) fallbackBlockDeviceDiscovery(domain *libvirt.
 	return devices
 }
 
-// discoverNetworkInterfaces attempts to discover available network interfaces for a domain using XML parsing
-func (mc *LibvirtMetricsCollector) discoverNetworkInterfaces(domain *libvirt.Domain) []string {
+// discoverNetworkInterfaces attempts to discover available network interfaces for a domain using XML parsing.
+// It also returns the XML definition of each discovered interface, keyed by interface name.
+func (mc *LibvirtMetricsCollector) discoverNetworkInterfaces(
+	domain *libvirt.Domain,
+) ([]string, map[string]*libvirtxml.DomainInterface) {
 	var interfaces []string
+	configs := make(map[string]*libvirtxml.DomainInterface)
 
 	// Get domain XML description
-	xmlDesc, err := domain.GetXMLDesc(0)
+	domainXML, err := mc.getDomainXML(domain)
 	if err != nil {
-		log.Printf("Warning: Failed to get domain XML for interfaces: %v", err)
-		return mc.fallbackNetworkInterfaceDiscovery(domain)
-	}
-
-	// Parse the XML
-	var domainXML libvirtxml.Domain
-	if err := xml.Unmarshal([]byte(xmlDesc), &domainXML); err != nil {
-		log.Printf("Warning: Failed to parse domain XML for interfaces: %v", err)
-		return mc.fallbackNetworkInterfaceDiscovery(domain)
+		slog.Warn("Failed to get domain XML for interfaces", "err", err)
+		return mc.fallbackNetworkInterfaceDiscovery(domain), nil
 	}
 
 	// Extract network interfaces from XML
 	if domainXML.Devices != nil {
-		for _, iface := range domainXML.Devices.Interfaces {
+		for i := range domainXML.Devices.Interfaces {
+			iface := &domainXML.Devices.Interfaces[i]
+			name := ""
 			if iface.Target != nil && iface.Target.Dev != "" {
-				interfaces = append(interfaces, iface.Target.Dev)
+				name = iface.Target.Dev
+			} else if name = vhostUserPort(iface); name == "" {
+				// vhost-user and hostdev interfaces have no target device,
+				// the latter are named after their host PCI address
+				name = hostdevAddress(iface)
+			}
+			if name == "" {
+				continue
 			}
+			interfaces = append(interfaces, name)
+			configs[name] = iface
 		}
 	}
 
 	// If XML parsing didn't find any interfaces, fall back to trial-and-error
 	if len(interfaces) == 0 {
-		return mc.fallbackNetworkInterfaceDiscovery(domain)
+		return mc.fallbackNetworkInterfaceDiscovery(domain), nil
 	}
 
-	return interfaces
+	return interfaces, configs
+}
+
+// interfaceType returns the type of an interface definition, as in the type
+// attribute of <interface>
+func interfaceType(iface *libvirtxml.DomainInterface) string {
+	if iface.Source == nil {
+		return ""
+	}
+	source := iface.Source
+	switch {
+	case source.Network != nil:
+		return "network"
+	case source.Bridge != nil:
+		return "bridge"
+	case source.Direct != nil:
+		return "direct"
+	case source.VHostUser != nil:
+		return "vhostuser"
+	case source.Ethernet != nil:
+		return "ethernet"
+	case source.User != nil:
+		return "user"
+	case source.Hostdev != nil:
+		return "hostdev"
+	case source.VDPA != nil:
+		return "vdpa"
+	default:
+		return ""
+	}
+}
+
+// vhostUserPort returns the backend port name of a vhost-user interface: the
+// target device when libvirt reports one, otherwise the socket file name, which
+// is the port name Open vSwitch uses. It returns "" for other interface types.
+func vhostUserPort(iface *libvirtxml.DomainInterface) string {
+	if iface == nil || iface.Source == nil || iface.Source.VHostUser == nil {
+		return ""
+	}
+	if iface.Target != nil && iface.Target.Dev != "" {
+		return iface.Target.Dev
+	}
+	chardev := iface.Source.VHostUser.Chardev
+	if chardev != nil && chardev.UNIX != nil && chardev.UNIX.Path != "" {
+		return filepath.Base(chardev.UNIX.Path)
+	}
+	return ""
 }
 
 // fallbackNetworkInterfaceDiscovery uses trial-and-error method as fallback
@@ -455,48 +1188,295 @@ func (mc *LibvirtMetricsCollector) fallbackNetworkInterfaceDiscovery(domain *lib
 
 // CollectDeviceStats collects device statistics from libvirt
 func (mc *LibvirtMetricsCollector) CollectDeviceStats(
+	ctx context.Context,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) (*DeviceMetrics, error) {
-	domainName, err := domain.GetName()
+	domainName, domainUUID, err := mc.domainLabels(domain)
 	if err != nil {
 		return nil, err
 	}
 
-	domainUUID, err := domain.GetUUIDString()
+	metrics := &DeviceMetrics{
+		Name: domainName,
+		UUID: domainUUID,
+	}
+
+	domainXML, err := mc.getDomainXML(domain)
 	if err != nil {
 		return nil, err
 	}
 
-	metrics := &DeviceMetrics{
-		Name: domainName,
-		UUID: domainUUID,
+	if domainXML.Devices == nil {
+		return metrics, nil
 	}
 
-	// Check for TPM
-	xmlDesc, err := domain.GetXMLDesc(0)
-	if err == nil {
-		// Simple check for TPM in XML
-		if len([]byte(xmlDesc)) > 0 {
-			metrics.HasTPM = false // Would need to parse XML to determine this accurately
-			metrics.HasRNG = false // Would need to parse XML to determine this accurately
+	metrics.HasTPM = len(domainXML.Devices.TPMs) > 0
+	metrics.HasRNG = len(domainXML.Devices.RNGs) > 0
+
+	// Passthrough and mediated devices
+	for _, hostdev := range domainXML.Devices.Hostdevs {
+		switch {
+		case hostdev.SubsysPCI != nil:
+			pci := hostdev.SubsysPCI
+			if pci.Source == nil || pci.Source.Address == nil {
+				continue
+			}
+			device := PCIDevice{
+				Address: pciAddressString(pci.Source.Address),
+				Type:    mc.pciDeviceType(conn, pci.Source.Address),
+			}
+			if pci.Driver != nil {
+				device.Driver = pci.Driver.Name
+			}
+			metrics.PCIDevices = append(metrics.PCIDevices, device)
+		case hostdev.SubsysUSB != nil:
+			source := hostdev.SubsysUSB.Source
+			if source == nil {
+				continue
+			}
+			var device USBDevice
+			if source.Address != nil {
+				if source.Address.Bus != nil {
+					device.Bus = int(*source.Address.Bus)
+				}
+				if source.Address.Device != nil {
+					device.Device = int(*source.Address.Device)
+				}
+			}
+			if source.Vendor != nil {
+				device.Vendor = source.Vendor.ID
+			}
+			if source.Product != nil {
+				device.Product = source.Product.ID
+			}
+			metrics.USBDevices = append(metrics.USBDevices, device)
+		case hostdev.SubsysMDev != nil:
+			mdev := hostdev.SubsysMDev
+			device := VGPUDevice{
+				Model: mdev.Model,
+			}
+			if mdev.Source != nil && mdev.Source.Address != nil {
+				device.MdevUUID = mdev.Source.Address.UUID
+			}
+			metrics.VGPUDevices = append(metrics.VGPUDevices, device)
+		}
+	}
+
+	// vsock device, at most one per domain
+	if vsock := domainXML.Devices.VSock; vsock != nil {
+		metrics.VSock = &VSockDevice{
+			Model: vsock.Model,
+		}
+		if vsock.CID != nil {
+			metrics.VSock.AutoCID = vsock.CID.Auto == "yes"
+			if cid, err := strconv.ParseUint(vsock.CID.Address, 10, 64); err == nil {
+				metrics.VSock.CID = cid
+			}
+		}
+	}
+
+	// Shared memory devices
+	for _, shmem := range domainXML.Devices.Shmems {
+		device := ShmemDevice{
+			Name: shmem.Name,
+		}
+		if shmem.Model != nil {
+			device.Model = shmem.Model.Type
+		}
+		if shmem.Size != nil {
+			// libvirt defaults to MiB when no unit is given
+			unit := shmem.Size.Unit
+			if unit == "" {
+				unit = "M"
+			}
+			device.SizeBytes = scaleToBytes(uint64(shmem.Size.Value), unit)
+		}
+		metrics.Shmems = append(metrics.Shmems, device)
+	}
+
+	for _, watchdog := range domainXML.Devices.Watchdogs {
+		action := watchdog.Action
+		if action == "" {
+			// libvirt resets the domain unless told otherwise
+			action = "reset"
+		}
+		metrics.Watchdogs = append(metrics.Watchdogs, WatchdogDevice{
+			Model:  watchdog.Model,
+			Action: action,
+		})
+	}
+
+	for _, rng := range domainXML.Devices.RNGs {
+		device := RNGDevice{Model: rng.Model}
+		if backend := rng.Backend; backend != nil {
+			switch {
+			case backend.Random != nil:
+				device.Backend = "random"
+			case backend.EGD != nil:
+				device.Backend = "egd"
+			case backend.BuiltIn != nil:
+				device.Backend = "builtin"
+			}
+		}
+		metrics.RNGs = append(metrics.RNGs, device)
+	}
+
+	// Serial ports, consoles and channels such as the guest agent channel
+	for _, serial := range domainXML.Devices.Serials {
+		metrics.CharDevices = append(metrics.CharDevices, CharDevice{
+			Kind: "serial",
+			Type: chardevSourceType(serial.Source),
+		})
+	}
+	for _, console := range domainXML.Devices.Consoles {
+		metrics.CharDevices = append(metrics.CharDevices, CharDevice{
+			Kind: "console",
+			Type: chardevSourceType(console.Source),
+		})
+	}
+	for _, channel := range domainXML.Devices.Channels {
+		metrics.CharDevices = append(metrics.CharDevices, CharDevice{
+			Kind: "channel",
+			Type: chardevSourceType(channel.Source),
+		})
+	}
+
+	for _, video := range domainXML.Devices.Videos {
+		// vram is given in KiB
+		vram := uint64(video.Model.VRam)
+		if video.Model.VRam64 > 0 {
+			vram = uint64(video.Model.VRam64)
 		}
+		metrics.Videos = append(metrics.Videos, VideoDevice{
+			Model:     video.Model.Type,
+			VRAMBytes: vram * 1024,
+			Primary:   video.Model.Primary == "yes",
+		})
+	}
+
+	for _, input := range domainXML.Devices.Inputs {
+		metrics.Inputs = append(metrics.Inputs, InputDevice{
+			Type: input.Type,
+			Bus:  input.Bus,
+		})
 	}
 
 	return metrics, nil
 }
 
+// chardevSourceType returns the source type of a character device, e.g.
+// "pty" or "unix", empty if it has no source
+func chardevSourceType(source *libvirtxml.DomainChardevSource) string {
+	if source == nil {
+		return ""
+	}
+	switch {
+	case source.Null != nil:
+		return "null"
+	case source.VC != nil:
+		return "vc"
+	case source.Pty != nil:
+		return "pty"
+	case source.Dev != nil:
+		return "dev"
+	case source.File != nil:
+		return "file"
+	case source.Pipe != nil:
+		return "pipe"
+	case source.StdIO != nil:
+		return "stdio"
+	case source.UDP != nil:
+		return "udp"
+	case source.TCP != nil:
+		return "tcp"
+	case source.UNIX != nil:
+		return "unix"
+	case source.SpiceVMC != nil:
+		return "spicevmc"
+	case source.SpicePort != nil:
+		return "spiceport"
+	case source.QEMUVDAgent != nil:
+		return "qemu-vdagent"
+	case source.DBus != nil:
+		return "dbus"
+	default:
+		return "other"
+	}
+}
+
+// pciAddressString formats a PCI address as domain:bus:slot.function
+func pciAddressString(address *libvirtxml.DomainAddressPCI) string {
+	var domain, bus, slot, function uint
+	if address.Domain != nil {
+		domain = *address.Domain
+	}
+	if address.Bus != nil {
+		bus = *address.Bus
+	}
+	if address.Slot != nil {
+		slot = *address.Slot
+	}
+	if address.Function != nil {
+		function = *address.Function
+	}
+	return fmt.Sprintf("%04x:%02x:%02x.%x", domain, bus, slot, function)
+}
+
+// pciDeviceType classifies a host PCI device by its PCI class code, looked up
+// from the node device of the given address. It returns "" if unknown.
+func (mc *LibvirtMetricsCollector) pciDeviceType(
+	conn *libvirt.Connect,
+	address *libvirtxml.DomainAddressPCI,
+) string {
+	name := "pci_" + strings.NewReplacer(":", "_", ".", "_").Replace(pciAddressString(address))
+	device, err := conn.LookupDeviceByName(name)
+	if err != nil {
+		return ""
+	}
+	defer device.Free()
+
+	deviceXML, err := device.GetXMLDesc(0)
+	if err != nil {
+		return ""
+	}
+
+	var nodeDevice libvirtxml.NodeDevice
+	if err := nodeDevice.Unmarshal(deviceXML); err != nil || nodeDevice.Capability.PCI == nil {
+		return ""
+	}
+
+	return pciClassType(nodeDevice.Capability.PCI.Class)
+}
+
+// pciClassType maps a PCI class code to a device type such as "GPU", empty if
+// the class is unknown
+func pciClassType(class string) string {
+	// The class code is reported as 0xCCSSPP (class, subclass, prog-if)
+	class = strings.TrimPrefix(class, "0x")
+	switch {
+	case strings.HasPrefix(class, "03"):
+		return "GPU"
+	case strings.HasPrefix(class, "02"):
+		return "NIC"
+	case strings.HasPrefix(class, "01"):
+		return "storage"
+	case strings.HasPrefix(class, "0c03"):
+		return "USB"
+	case class == "":
+		return ""
+	default:
+		return "other"
+	}
+}
+
 // CollectJobStats collects job statistics from libvirt
 func (mc *LibvirtMetricsCollector) CollectJobStats(
+	ctx context.Context,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) (*DomainJobMetrics, error) {
-	domainName, err := domain.GetName()
-	if err != nil {
-		return nil, err
-	}
-
-	domainUUID, err := domain.GetUUIDString()
+	domainName, domainUUID, err := mc.domainLabels(domain)
 	if err != nil {
 		return nil, err
 	}
@@ -506,18 +1486,30 @@ func (mc *LibvirtMetricsCollector) CollectJobStats(
 		UUID: domainUUID,
 	}
 
-	// Try to get job info
-	jobInfo, err := domain.GetJobInfo()
+	// Job statistics name the operation (e.g. an outgoing migration), older
+	// daemons only support the plain job info
+	jobInfo, err := domain.GetJobStats(0)
+	if err != nil {
+		jobInfo, err = domain.GetJobInfo()
+	}
 	if err == nil && jobInfo.Type != libvirt.DOMAIN_JOB_NONE {
 		metrics.Type = jobTypeToString(jobInfo.Type)
+		metrics.Operation = "unknown"
+		if jobInfo.OperationSet {
+			metrics.Operation = jobOperationToString(jobInfo.Operation)
+		}
 		if jobInfo.DataTotal > 0 {
 			metrics.Progress = float64(jobInfo.DataProcessed) / float64(jobInfo.DataTotal)
 		}
 		metrics.Remaining = jobInfo.DataRemaining
 		metrics.Transferred = jobInfo.DataProcessed
 		metrics.Total = jobInfo.DataTotal
+		// Migrations transfer memory, other jobs (e.g. backups) disks
+		if jobInfo.MemBpsSet {
+			metrics.SpeedBps += jobInfo.MemBps
+		}
 		if jobInfo.DiskBpsSet {
-			metrics.SpeedBps = jobInfo.DiskBps
+			metrics.SpeedBps += jobInfo.DiskBps
 		}
 	}
 
@@ -526,34 +1518,148 @@ func (mc *LibvirtMetricsCollector) CollectJobStats(
 
 // CollectSnapshotStats collects snapshot statistics from libvirt
 func (mc *LibvirtMetricsCollector) CollectSnapshotStats(
+	ctx context.Context,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
 ) (*SnapshotMetrics, error) {
-	domainName, err := domain.GetName()
+	domainName, domainUUID, err := mc.domainLabels(domain)
 	if err != nil {
 		return nil, err
 	}
 
-	domainUUID, err := domain.GetUUIDString()
+	// List snapshots to get count
+	snapshots, err := domain.ListAllSnapshots(0)
 	if err != nil {
 		return nil, err
 	}
 
-	// List snapshots to get count
-	snapshots, err := domain.ListAllSnapshots(0)
+	metrics := &SnapshotMetrics{
+		Name:  domainName,
+		UUID:  domainUUID,
+		Count: len(snapshots),
+	}
+
+	for i := range snapshots {
+		// Only release the remaining snapshots once out of time
+		if ctx.Err() != nil {
+			snapshots[i].Free()
+			continue
+		}
+		info, err := snapshotInfo(&snapshots[i])
+		snapshots[i].Free()
+		if err != nil {
+			slog.Warn("Failed to get snapshot XML", "domain", domainName, "err", err)
+			continue
+		}
+		metrics.Snapshots = append(metrics.Snapshots, *info)
+
+		if info.CreationTime.IsZero() {
+			continue
+		}
+		if metrics.Oldest.IsZero() || info.CreationTime.Before(metrics.Oldest) {
+			metrics.Oldest = info.CreationTime
+		}
+		if info.CreationTime.After(metrics.LastCreate) {
+			metrics.LastCreate = info.CreationTime
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	metrics.MaxDepth = snapshotTreeDepth(metrics.Snapshots)
+
+	return metrics, nil
+}
+
+// snapshotInfo parses the name, parent, state and creation time of a
+// snapshot from its XML description
+func snapshotInfo(snapshot *libvirt.DomainSnapshot) (*SnapshotInfo, error) {
+	xmlDesc, err := snapshot.GetXMLDesc(0)
 	if err != nil {
 		return nil, err
 	}
 
-	// Free snapshots
+	var snapshotXML libvirtxml.DomainSnapshot
+	if err := snapshotXML.Unmarshal(xmlDesc); err != nil {
+		return nil, err
+	}
+
+	info := &SnapshotInfo{
+		Name:  snapshotXML.Name,
+		State: snapshotXML.State,
+	}
+	if current, err := snapshot.IsCurrent(0); err == nil {
+		info.Current = current
+	}
+	if snapshotXML.Parent != nil {
+		info.Parent = snapshotXML.Parent.Name
+	}
+	// The creation time is in seconds since the epoch
+	if seconds, err := strconv.ParseInt(snapshotXML.CreationTime, 10, 64); err == nil {
+		info.CreationTime = time.Unix(seconds, 0)
+	}
+
+	return info, nil
+}
+
+// snapshotTreeDepth returns the length of the longest parent chain of the
+// snapshots, 1 for snapshots without parents
+func snapshotTreeDepth(snapshots []SnapshotInfo) int {
+	parents := make(map[string]string, len(snapshots))
+	for _, snapshot := range snapshots {
+		parents[snapshot.Name] = snapshot.Parent
+	}
+
+	maxDepth := 0
 	for _, snapshot := range snapshots {
-		snapshot.Free()
+		depth := 1
+		// Bound the walk in case the parent links form a cycle
+		for parent := snapshot.Parent; parent != "" && depth <= len(snapshots); parent = parents[parent] {
+			depth++
+		}
+		if depth > maxDepth {
+			maxDepth = depth
+		}
 	}
+	return maxDepth
+}
 
-	metrics := &SnapshotMetrics{
-		Name:  domainName,
-		UUID:  domainUUID,
-		Count: len(snapshots),
+// CollectProcessStats collects host-side statistics of the QEMU process of a
+// domain from /proc, resolving its PID from the libvirt PID file
+func (mc *LibvirtMetricsCollector) CollectProcessStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) (*ProcessMetrics, error) {
+	// PID files are named after the raw domain name
+	rawName, err := domain.GetName()
+	if err != nil {
+		return nil, err
+	}
+
+	domainName, domainUUID, err := mc.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	pid, err := readPIDFile(filepath.Join(mc.pidDir, rawName+".pid"))
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := readProcessStats(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &ProcessMetrics{
+		Name:          domainName,
+		UUID:          domainUUID,
+		PID:           pid,
+		ResidentBytes: stats.ResidentBytes,
+		CPUSeconds:    stats.CPUSeconds,
+		Threads:       stats.Threads,
+		OpenFDs:       stats.OpenFDs,
 	}
 
 	return metrics, nil
@@ -561,6 +1667,7 @@ func (mc *LibvirtMetricsCollector) CollectSnapshotStats(
 
 // CollectConnectionStats collects connection and host level statistics
 func (mc *LibvirtMetricsCollector) CollectConnectionStats(
+	ctx context.Context,
 	conn *libvirt.Connect,
 ) (*ConnectionMetrics, error) {
 	// Get connection URI
@@ -637,113 +1744,20 @@ func (mc *LibvirtMetricsCollector) CollectConnectionStats(
 		}
 	}
 
-	// Get storage pools
-	storagePools := []StoragePoolMetrics{}
-	pools, err := conn.ListAllStoragePools(0)
-	if err == nil {
-		for _, pool := range pools {
-			poolInfo, err := pool.GetInfo()
-			if err != nil {
-				pool.Free()
-				continue
-			}
-
-			poolName, err := pool.GetName()
-			if err != nil {
-				pool.Free()
-				continue
-			}
-
-			// Get volumes count by listing them
-			// Note: Simplified approach - not listing volumes for now
-			volumeCount := 0
-
-			state := "inactive"
-			active, err := pool.IsActive()
-			if err == nil && active {
-				state = "active"
-			}
-
-			// Get pool type from XML description
-			poolType := "unknown"
-			xmlDesc, err := pool.GetXMLDesc(0)
-			if err == nil {
-				var poolXML libvirtxml.StoragePool
-				if err := xml.Unmarshal([]byte(xmlDesc), &poolXML); err == nil {
-					poolType = poolXML.Type
-				}
-			}
-
-			storagePool := StoragePoolMetrics{
-				Name:       poolName,
-				Type:       poolType,
-				State:      state,
-				Capacity:   uint64(poolInfo.Capacity),
-				Allocation: uint64(poolInfo.Allocation),
-				Available:  uint64(poolInfo.Available),
-				Volumes:    volumeCount,
-			}
-			storagePools = append(storagePools, storagePool)
-			pool.Free()
-		}
+	// Storage pools and networks take a libvirt call each, skip them once
+	// out of time
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Get virtual networks
-	networks := []NetworkPoolMetrics{}
-	nets, err := conn.ListAllNetworks(0)
-	if err == nil {
-		for _, net := range nets {
-			netName, err := net.GetName()
-			if err != nil {
-				net.Free()
-				continue
-			}
-
-			bridge := ""
-			bridgeName, err := net.GetBridgeName()
-			if err == nil {
-				bridge = bridgeName
-			}
-
-			active, err := net.IsActive()
-			if err != nil {
-				active = false
-			}
+	// Get storage pools
+	storagePools := mc.collectStoragePools(conn)
 
-			network := NetworkPoolMetrics{
-				Name:   netName,
-				Active: active,
-				Bridge: bridge,
-			}
-			networks = append(networks, network)
-			net.Free()
-		}
-	}
+	// Get virtual networks
+	networks := mc.collectNetworks(conn)
 
 	// Get host interfaces
-	interfaces := []HostInterfaceMetrics{}
-	ifaces, err := conn.ListInterfaces()
-	if err == nil {
-		for _, ifaceName := range ifaces {
-			iface, err := conn.LookupInterfaceByName(ifaceName)
-			if err != nil {
-				continue
-			}
-
-			// Get interface statistics (simplified for now)
-			// Note: libvirt go bindings don't provide direct interface stats
-			// This would require parsing /proc/net/dev or using netlink
-			hostInterface := HostInterfaceMetrics{
-				Name:      ifaceName,
-				RxBytes:   0, // TODO: Implement interface statistics collection
-				TxBytes:   0,
-				RxPackets: 0,
-				TxPackets: 0,
-			}
-			interfaces = append(interfaces, hostInterface)
-			iface.Free()
-		}
-	}
+	interfaces := mc.collectHostInterfaces(conn)
 
 	metrics := &ConnectionMetrics{
 		Hostname:            hostname,
@@ -756,7 +1770,6 @@ func (mc *LibvirtMetricsCollector) CollectConnectionStats(
 		FreeMemoryBytes:     freeMemory,
 		TotalMemoryBytes:    uint64(nodeInfo.Memory) * 1024, // Convert from KB to bytes
 		TotalCPUs:           int(nodeInfo.Cpus),
-		HostCPUUsagePercent: 0.0, // TODO: Implement CPU usage calculation
 		StoragePools:        storagePools,
 		Networks:            networks,
 		Interfaces:          interfaces,
@@ -765,64 +1778,1238 @@ func (mc *LibvirtMetricsCollector) CollectConnectionStats(
 	return metrics, nil
 }
 
-// CollectHostStats collects host level statistics
-func (mc *LibvirtMetricsCollector) CollectHostStats(
+// CollectHostCPUUsage fills the host CPU times and the CPU usage since the
+// previous call from the node CPU statistics. Only scrapes call it, so the
+// usage covers the interval between two scrapes.
+func (mc *LibvirtMetricsCollector) CollectHostCPUUsage(
+	ctx context.Context,
 	conn *libvirt.Connect,
-) (*HostMetrics, error) {
-	// Get hostname
-	hostname, err := conn.GetHostname()
+	metrics *ConnectionMetrics,
+) {
+	stats, err := conn.GetCPUStats(int(libvirt.NODE_CPU_STATS_ALL_CPUS), 0)
 	if err != nil {
-		hostname = "unknown"
+		slog.Warn("Failed to get host CPU statistics", "err", err)
+		return
 	}
 
-	// Get node info
-	nodeInfo, err := conn.GetNodeInfo()
-	if err != nil {
-		return nil, err
+	metrics.HostCPUTimes = make(map[string]float64)
+	if stats.KernelSet {
+		metrics.HostCPUTimes["kernel"] = float64(stats.Kernel) / 1e9
+	}
+	if stats.UserSet {
+		metrics.HostCPUTimes["user"] = float64(stats.User) / 1e9
+	}
+	if stats.IdleSet {
+		metrics.HostCPUTimes["idle"] = float64(stats.Idle) / 1e9
+	}
+	if stats.IowaitSet {
+		metrics.HostCPUTimes["iowait"] = float64(stats.Iowait) / 1e9
 	}
 
-	// Get free memory
-	freeMemory, err := conn.GetFreeMemory()
-	if err != nil {
-		freeMemory = 0
+	mc.cpuMutex.Lock()
+	last := mc.lastCPU
+	mc.lastCPU = stats
+	mc.cpuMutex.Unlock()
+
+	if last == nil || !stats.KernelSet || !stats.UserSet || !stats.IdleSet {
+		return
 	}
 
-	// Get libvirt version
-	libvirtVersion, err := conn.GetLibVersion()
-	if err != nil {
-		libvirtVersion = 0
+	// Time waiting for I/O counts as idle
+	busy := float64(stats.Kernel+stats.User) - float64(last.Kernel+last.User)
+	idle := float64(stats.Idle+stats.Iowait) - float64(last.Idle+last.Iowait)
+	if busy < 0 || idle < 0 || busy+idle == 0 {
+		// Counters were reset, e.g. after connecting to another host
+		return
 	}
+	metrics.HostCPUUsagePercent = busy / (busy + idle) * 100
+	metrics.HasHostCPUUsage = true
+}
 
-	// Get hypervisor version
-	hypervisorVersion, err := conn.GetVersion()
+// collectStoragePools collects the capacity and volume count of all storage
+// pools; pools that cannot be queried are skipped
+func (mc *LibvirtMetricsCollector) collectStoragePools(conn *libvirt.Connect) []StoragePoolMetrics {
+	storagePools := []StoragePoolMetrics{}
+	pools, err := conn.ListAllStoragePools(0)
 	if err != nil {
-		hypervisorVersion = 0
+		slog.Warn("Failed to list storage pools", "err", err)
+		return storagePools
 	}
 
-	metrics := &HostMetrics{
-		Name:              hostname,
-		TotalCPUCount:     uint64(nodeInfo.Cpus),
-		OnlineCPUCount:    uint64(nodeInfo.Cpus), // Simplified, assuming all CPUs are online
-		TotalMemoryBytes:  uint64(nodeInfo.Memory) * 1024, // Convert from KB to bytes
-		FreeMemoryBytes:   freeMemory,
-		Hostname:          hostname,
-		LibvirtVersion:    uint64(libvirtVersion),
-		HypervisorVersion: uint64(hypervisorVersion),
+	for i := range pools {
+		pool := &pools[i]
+		metrics, err := mc.collectStoragePool(pool)
+		pool.Free()
+		if err != nil {
+			slog.Warn("Failed to collect storage pool", "err", err)
+			continue
+		}
+		storagePools = append(storagePools, *metrics)
 	}
 
-	return metrics, nil
+	return storagePools
 }
 
-// Helper function to convert job type to string
-func jobTypeToString(jobType libvirt.DomainJobType) string {
-	switch jobType {
-	case libvirt.DOMAIN_JOB_BOUNDED:
-		return "bounded"
-	case libvirt.DOMAIN_JOB_UNBOUNDED:
-		return "unbounded"
-	case libvirt.DOMAIN_JOB_COMPLETED:
+// collectStoragePool collects the statistics of one storage pool
+func (mc *LibvirtMetricsCollector) collectStoragePool(pool *libvirt.StoragePool) (*StoragePoolMetrics, error) {
+	poolName, err := pool.GetName()
+	if err != nil {
+		return nil, err
+	}
+
+	poolInfo, err := pool.GetInfo()
+	if err != nil {
+		return nil, fmt.Errorf("pool %s: %w", poolName, err)
+	}
+
+	metrics := &StoragePoolMetrics{
+		Name:       poolName,
+		Type:       "unknown",
+		State:      storagePoolStateToString(poolInfo.State),
+		Capacity:   poolInfo.Capacity,
+		Allocation: poolInfo.Allocation,
+		Available:  poolInfo.Available,
+	}
+
+	// Get pool type from XML description
+	xmlDesc, err := pool.GetXMLDesc(0)
+	if err == nil {
+		var poolXML libvirtxml.StoragePool
+		if err := xml.Unmarshal([]byte(xmlDesc), &poolXML); err == nil && poolXML.Type != "" {
+			metrics.Type = poolXML.Type
+		}
+	}
+
+	// Volumes can only be listed in active pools
+	if poolInfo.State != libvirt.STORAGE_POOL_INACTIVE {
+		volumes, err := pool.NumOfStorageVolumes()
+		if err == nil {
+			metrics.Volumes = volumes
+		}
+	}
+
+	return metrics, nil
+}
+
+// collectNetworks collects the state and DHCP lease count of all virtual
+// networks; networks that cannot be queried are skipped
+func (mc *LibvirtMetricsCollector) collectNetworks(conn *libvirt.Connect) []NetworkPoolMetrics {
+	networks := []NetworkPoolMetrics{}
+	nets, err := conn.ListAllNetworks(0)
+	if err != nil {
+		slog.Warn("Failed to list virtual networks", "err", err)
+		return networks
+	}
+
+	for i := range nets {
+		net := &nets[i]
+		metrics, err := mc.collectNetwork(net)
+		net.Free()
+		if err != nil {
+			slog.Warn("Failed to collect virtual network", "err", err)
+			continue
+		}
+		networks = append(networks, *metrics)
+	}
+
+	return networks
+}
+
+// collectNetwork collects the statistics of one virtual network
+func (mc *LibvirtMetricsCollector) collectNetwork(net *libvirt.Network) (*NetworkPoolMetrics, error) {
+	netName, err := net.GetName()
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &NetworkPoolMetrics{
+		Name: netName,
+	}
+
+	// Networks without a bridge (e.g. macvtap or hostdev forwarding) fail here
+	bridge, err := net.GetBridgeName()
+	if err == nil {
+		metrics.Bridge = bridge
+	}
+
+	metrics.Active, err = net.IsActive()
+	if err != nil {
+		return nil, fmt.Errorf("network %s: %w", netName, err)
+	}
+
+	metrics.Autostart, _ = net.GetAutostart()
+	metrics.Persistent, _ = net.IsPersistent()
+
+	// Leases are only handed out by the dnsmasq of active networks
+	if metrics.Active {
+		leases, err := net.GetDHCPLeases()
+		if err == nil {
+			metrics.DHCPLeases = len(leases)
+			for _, lease := range leases {
+				m := DHCPLeaseMetrics{
+					MAC:      lease.Mac,
+					IP:       lease.IPaddr,
+					Hostname: guestLabel(lease.Hostname),
+				}
+				// libvirt reports leases that never expire with expiry time 0
+				if lease.ExpiryTime.Unix() > 0 {
+					m.ExpiryTime = lease.ExpiryTime
+				}
+				metrics.Leases = append(metrics.Leases, m)
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+// collectHostInterfaces collects the counters of host network interfaces.
+// libvirt does not report interface statistics, so they are read from
+// /proc/net/dev, which only describes the hypervisor for local connections.
+func (mc *LibvirtMetricsCollector) collectHostInterfaces(conn *libvirt.Connect) []HostInterfaceMetrics {
+	interfaces := []HostInterfaceMetrics{}
+	if mc.hostIfs == HostInterfacesNone || !isLocalConnection(conn) {
+		return interfaces
+	}
+
+	devices, err := readProcNetDev()
+	if err != nil {
+		slog.Warn("Failed to read host interface statistics", "err", err)
+		return interfaces
+	}
+
+	var names []string
+	if mc.hostIfs == HostInterfacesLibvirt {
+		ifaces, err := conn.ListAllInterfaces(libvirt.CONNECT_LIST_INTERFACES_ACTIVE)
+		if err != nil {
+			slog.Warn("Failed to list host interfaces", "err", err)
+			return interfaces
+		}
+		for i := range ifaces {
+			if name, err := ifaces[i].GetName(); err == nil {
+				names = append(names, name)
+			}
+			ifaces[i].Free()
+		}
+	} else {
+		for name := range devices {
+			if name != "lo" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		stats, ok := devices[name]
+		if !ok {
+			continue
+		}
+		interfaces = append(interfaces, HostInterfaceMetrics{
+			Name:      name,
+			RxBytes:   stats.RxBytes,
+			TxBytes:   stats.TxBytes,
+			RxPackets: stats.RxPackets,
+			TxPackets: stats.TxPackets,
+		})
+	}
+
+	return interfaces
+}
+
+// CollectHostTopology collects the host topology from the capabilities XML
+func (mc *LibvirtMetricsCollector) CollectHostTopology(
+	ctx context.Context,
+	conn *libvirt.Connect,
+) (*HostTopologyMetrics, error) {
+	capsXML, err := conn.GetCapabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	var caps libvirtxml.Caps
+	if err := caps.Unmarshal(capsXML); err != nil {
+		return nil, err
+	}
+
+	metrics := &HostTopologyMetrics{}
+	var numaCells []libvirtxml.CapsHostNUMACell
+	if caps.Host.NUMA != nil && caps.Host.NUMA.Cells != nil {
+		numaCells = caps.Host.NUMA.Cells.Cells
+	}
+
+	for _, cell := range numaCells {
+		node := NUMANodeMetrics{ID: cell.ID}
+		if cell.CPUS != nil {
+			node.CPUs = len(cell.CPUS.CPUs)
+		}
+		if cell.Memory != nil {
+			node.MemoryBytes = scaleToBytes(cell.Memory.Size, cell.Memory.Unit)
+		}
+		if cell.Distances != nil {
+			for _, sibling := range cell.Distances.Siblings {
+				node.Distances = append(node.Distances, NUMADistance{
+					Sibling: sibling.ID,
+					Value:   sibling.Value,
+				})
+			}
+		}
+		metrics.NUMANodes = append(metrics.NUMANodes, node)
+	}
+
+	// IOMMU groups and VFIO bindings come from the host PCI devices
+	groups, vfioDevices, err := mc.pciPassthroughInfo(conn)
+	if err != nil {
+		slog.Warn("Failed to list host PCI devices", "err", err)
+	}
+	metrics.IOMMUGroups = groups
+	metrics.VFIODevices = vfioDevices
+
+	// Older libvirt versions do not report IOMMU support in capabilities
+	if caps.Host.IOMMU != nil {
+		metrics.IOMMUEnabled = caps.Host.IOMMU.Support == "yes"
+	} else {
+		metrics.IOMMUEnabled = groups > 0
+	}
+
+	return metrics, nil
+}
+
+// pciPassthroughInfo returns the number of distinct IOMMU groups and the
+// number of vfio-pci bound devices among the host PCI devices
+func (mc *LibvirtMetricsCollector) pciPassthroughInfo(conn *libvirt.Connect) (int, int, error) {
+	devices, err := conn.ListAllNodeDevices(libvirt.CONNECT_LIST_NODE_DEVICES_CAP_PCI_DEV)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		for _, device := range devices {
+			device.Free()
+		}
+	}()
+
+	groups := make(map[int]bool)
+	vfioDevices := 0
+	for _, device := range devices {
+		deviceXML, err := device.GetXMLDesc(0)
+		if err != nil {
+			continue
+		}
+
+		var nodeDevice libvirtxml.NodeDevice
+		if err := nodeDevice.Unmarshal(deviceXML); err != nil {
+			continue
+		}
+
+		if nodeDevice.Driver != nil && nodeDevice.Driver.Name == "vfio-pci" {
+			vfioDevices++
+		}
+		if pci := nodeDevice.Capability.PCI; pci != nil && pci.IOMMUGroup != nil {
+			groups[pci.IOMMUGroup.Number] = true
+		}
+	}
+
+	return len(groups), vfioDevices, nil
+}
+
+// CollectHostResourceStats collects host CPU contention and the resources
+// allocated to running domains
+func (mc *LibvirtMetricsCollector) CollectHostResourceStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+) (*HostResourceMetrics, error) {
+	nodeInfo, err := conn.GetNodeInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := conn.ListAllDomains(libvirt.CONNECT_LIST_DOMAINS_RUNNING)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &HostResourceMetrics{
+		PhysicalCores:       uint(nodeInfo.Nodes * nodeInfo.Sockets * nodeInfo.Cores),
+		OnlineCPUs:          nodeInfo.Cpus,
+		PhysicalMemoryBytes: nodeInfo.Memory * 1024,
+	}
+	for _, domain := range domains {
+		if domainInfo, err := domain.GetInfo(); err == nil {
+			metrics.AllocatedVCPUs += domainInfo.NrVirtCpu
+			metrics.AllocatedMemoryBytes += domainInfo.MaxMem * 1024
+			metrics.BalloonMemoryBytes += domainInfo.Memory * 1024
+		}
+		domain.Free()
+	}
+
+	// Steal time is not reported by libvirt, so it is read from /proc/stat
+	// when the exporter runs on the hypervisor itself
+	if isLocalConnection(conn) {
+		cpuTimes, err := readProcCPUTimes()
+		if err != nil {
+			slog.Warn("Failed to read host CPU times", "err", err)
+		} else {
+			metrics.HasProcStat = true
+			metrics.IowaitSeconds = cpuTimes.Iowait
+			metrics.StealSeconds = cpuTimes.Steal
+		}
+	}
+
+	return metrics, nil
+}
+
+// CollectDirtyRateStats collects the result of the last memory dirty rate
+// calculation of a domain
+func (mc *LibvirtMetricsCollector) CollectDirtyRateStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) (*DirtyRateMetrics, error) {
+	domainName, domainUUID, err := mc.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &DirtyRateMetrics{
+		Name: domainName,
+		UUID: domainUUID,
+	}
+
+	state, _, err := domain.GetState()
+	if err != nil {
+		return nil, err
+	}
+	if state != libvirt.DOMAIN_RUNNING {
+		return metrics, nil
+	}
+	metrics.Running = true
+
+	records, err := conn.GetAllDomainStats([]*libvirt.Domain{domain}, libvirt.DOMAIN_STATS_DIRTYRATE, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		records[i].Domain.Free()
+	}
+	if len(records) == 0 || records[0].DirtyRate == nil {
+		return metrics, nil
+	}
+
+	dirtyRate := records[0].DirtyRate
+	if dirtyRate.CalcStatusSet {
+		switch libvirt.DomainDirtyRateStatus(dirtyRate.CalcStatus) {
+		case libvirt.DOMAIN_DIRTYRATE_MEASURING:
+			metrics.Measuring = true
+		case libvirt.DOMAIN_DIRTYRATE_MEASURED:
+			metrics.Measured = dirtyRate.MegabytesPerSecondSet
+		}
+	}
+
+	// QEMU reports the rate in MiB/s
+	if metrics.Measured && dirtyRate.MegabytesPerSecond > 0 {
+		metrics.BytesPerSecond = uint64(dirtyRate.MegabytesPerSecond) << 20
+	}
+
+	return metrics, nil
+}
+
+// CollectPerfStats collects the perf event counters of a running domain
+func (mc *LibvirtMetricsCollector) CollectPerfStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) (*PerfMetrics, error) {
+	domainName, domainUUID, err := mc.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &PerfMetrics{
+		Name: domainName,
+		UUID: domainUUID,
+	}
+
+	state, _, err := domain.GetState()
+	if err != nil {
+		return nil, err
+	}
+	if state != libvirt.DOMAIN_RUNNING {
+		return metrics, nil
+	}
+	metrics.Running = true
+
+	records, err := conn.GetAllDomainStats([]*libvirt.Domain{domain}, libvirt.DOMAIN_STATS_PERF, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		records[i].Domain.Free()
+	}
+	if len(records) == 0 || records[0].Perf == nil {
+		return metrics, nil
+	}
+
+	perf := records[0].Perf
+	metrics.HasCPUCycles = perf.CpuCyclesSet
+	metrics.CPUCycles = perf.CpuCycles
+	metrics.HasInstructions = perf.InstructionsSet
+	metrics.Instructions = perf.Instructions
+	metrics.HasCacheMisses = perf.CacheMissesSet
+	metrics.CacheMisses = perf.CacheMisses
+	metrics.HasBranchMisses = perf.BranchMissesSet
+	metrics.BranchMisses = perf.BranchMisses
+
+	return metrics, nil
+}
+
+// CollectResctrlStats collects the cache occupancy and memory bandwidth
+// monitors of a running domain
+func (mc *LibvirtMetricsCollector) CollectResctrlStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) (*ResctrlMetrics, error) {
+	domainName, domainUUID, err := mc.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &ResctrlMetrics{
+		Name: domainName,
+		UUID: domainUUID,
+	}
+
+	state, _, err := domain.GetState()
+	if err != nil {
+		return nil, err
+	}
+	if state != libvirt.DOMAIN_RUNNING {
+		return metrics, nil
+	}
+
+	records, err := conn.GetAllDomainStats(
+		[]*libvirt.Domain{domain},
+		libvirt.DOMAIN_STATS_CPU_TOTAL|libvirt.DOMAIN_STATS_MEMORY,
+		0,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		records[i].Domain.Free()
+	}
+	if len(records) == 0 {
+		return metrics, nil
+	}
+
+	if cpu := records[0].Cpu; cpu != nil {
+		for _, monitor := range cpu.CacheMonitors {
+			for _, bank := range monitor.Banks {
+				if !bank.IDSet || !bank.BytesSet {
+					continue
+				}
+				metrics.CacheOccupancy = append(metrics.CacheOccupancy, ResctrlCacheMetrics{
+					Monitor: monitor.Name,
+					VCPUs:   monitor.Vcpus,
+					Bank:    bank.ID,
+					Bytes:   bank.Bytes,
+				})
+			}
+		}
+	}
+	if memory := records[0].Memory; memory != nil {
+		for _, monitor := range memory.BandwidthMonitor {
+			for _, node := range monitor.Nodes {
+				if !node.IDSet {
+					continue
+				}
+				metrics.MemoryBandwidth = append(metrics.MemoryBandwidth, ResctrlBandwidthMetrics{
+					Monitor:       monitor.Name,
+					VCPUs:         monitor.VCPUs,
+					Node:          node.ID,
+					HasLocalBytes: node.BytesLocalSet,
+					LocalBytes:    node.BytesLocal,
+					HasTotalBytes: node.BytesTotalSet,
+					TotalBytes:    node.BytesTotal,
+				})
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+// sevPolicyES is the guest policy bit of SEV domains requiring SEV-ES
+const sevPolicyES = 0x04
+
+// CollectLaunchSecurityStats collects the launch security (SEV, SEV-ES,
+// SEV-SNP or TDX) of a domain from its definition and, for running SEV
+// domains, the launch measurement state and firmware version
+func (mc *LibvirtMetricsCollector) CollectLaunchSecurityStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) (*LaunchSecurityMetrics, error) {
+	domainName, domainUUID, err := mc.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	domainXML, err := mc.getDomainXML(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &LaunchSecurityMetrics{
+		Name: domainName,
+		UUID: domainUUID,
+		Type: "none",
+	}
+
+	launchSecurity := domainXML.LaunchSecurity
+	if launchSecurity == nil {
+		return metrics, nil
+	}
+	switch {
+	case launchSecurity.SEV != nil:
+		metrics.Type = "sev"
+		if policy := launchSecurity.SEV.Policy; policy != nil {
+			metrics.Policy, metrics.HasPolicy = uint64(*policy), true
+		}
+	case launchSecurity.SEVSNP != nil:
+		metrics.Type = "sev-snp"
+		if policy := launchSecurity.SEVSNP.Policy; policy != nil {
+			metrics.Policy, metrics.HasPolicy = *policy, true
+		}
+	case launchSecurity.TDX != nil:
+		metrics.Type = "tdx"
+		if policy := launchSecurity.TDX.Policy; policy != nil {
+			metrics.Policy, metrics.HasPolicy = uint64(*policy), true
+		}
+	case launchSecurity.S390PV != nil:
+		metrics.Type = "s390-pv"
+	}
+
+	// Only running SEV domains report their launch state
+	active, err := domain.IsActive()
+	if err == nil && active && (metrics.Type == "sev" || metrics.Type == "sev-snp") {
+		info, err := domain.GetLaunchSecurityInfo(0)
+		if err != nil {
+			slog.Debug("Failed to get launch security info", "domain", domainName, "err", err)
+		} else {
+			metrics.Measured = info.SEVMeasurementSet
+			if info.SEVAPIMajorSet && info.SEVAPIMinorSet && info.SEVBuildIDSet {
+				metrics.HasFirmware = true
+				metrics.APIMajor = info.SEVAPIMajor
+				metrics.APIMinor = info.SEVAPIMinor
+				metrics.BuildID = info.SEVBuildID
+			}
+			if info.SEVPolicySet {
+				metrics.Policy, metrics.HasPolicy = uint64(info.SEVPolicy), true
+			} else if info.SEVSNPPolicySet {
+				metrics.Policy, metrics.HasPolicy = info.SEVSNPPolicy, true
+			}
+		}
+	}
+
+	// SEV-ES is plain SEV with the ES bit in the guest policy
+	if metrics.Type == "sev" && metrics.Policy&sevPolicyES != 0 {
+		metrics.Type = "sev-es"
+	}
+
+	return metrics, nil
+}
+
+// CollectHostSEVStats collects the AMD SEV capabilities of the host; hosts
+// without SEV support fail with an unsupported error
+func (mc *LibvirtMetricsCollector) CollectHostSEVStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+) (*HostSEVMetrics, error) {
+	params, err := conn.GetSEVInfo(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HostSEVMetrics{
+		CBitPos:         params.CBitPos,
+		ReducedPhysBits: params.ReducedPhysBits,
+		HasMaxGuests:    params.MaxGuestsSet,
+		MaxGuests:       params.MaxGuests,
+		HasMaxESGuests:  params.MaxEsGuestsSet,
+		MaxESGuests:     params.MaxEsGuests,
+	}, nil
+}
+
+// CollectNodeDeviceStats lists the PCI and USB devices of the host along
+// with the active domains they are assigned to
+func (mc *LibvirtMetricsCollector) CollectNodeDeviceStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+) ([]NodeDeviceMetrics, error) {
+	assigned, err := mc.assignedHostDevices(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := conn.ListAllNodeDevices(
+		libvirt.CONNECT_LIST_NODE_DEVICES_CAP_PCI_DEV | libvirt.CONNECT_LIST_NODE_DEVICES_CAP_USB_DEV,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, device := range devices {
+			device.Free()
+		}
+	}()
+
+	var metrics []NodeDeviceMetrics
+	for _, device := range devices {
+		if ctx.Err() != nil {
+			break
+		}
+		deviceXML, err := device.GetXMLDesc(0)
+		if err != nil {
+			continue
+		}
+		var nodeDevice libvirtxml.NodeDevice
+		if err := nodeDevice.Unmarshal(deviceXML); err != nil {
+			continue
+		}
+
+		m := NodeDeviceMetrics{Name: nodeDevice.Name}
+		if nodeDevice.Driver != nil {
+			m.Driver = nodeDevice.Driver.Name
+		}
+		var key string
+		switch {
+		case nodeDevice.Capability.PCI != nil:
+			pci := nodeDevice.Capability.PCI
+			m.Bus = "pci"
+			m.Class = pciClassType(pci.Class)
+			m.VendorID, m.Vendor = pci.Vendor.ID, pci.Vendor.Name
+			m.ProductID, m.Product = pci.Product.ID, pci.Product.Name
+			m.VFIO = strings.HasPrefix(m.Driver, "vfio")
+			key = "pci:" + pciAddressString(&libvirtxml.DomainAddressPCI{
+				Domain:   pci.Domain,
+				Bus:      pci.Bus,
+				Slot:     pci.Slot,
+				Function: pci.Function,
+			})
+		case nodeDevice.Capability.USBDevice != nil:
+			usb := nodeDevice.Capability.USBDevice
+			m.Bus = "usb"
+			m.VendorID, m.Vendor = usb.Vendor.ID, usb.Vendor.Name
+			m.ProductID, m.Product = usb.Product.ID, usb.Product.Name
+			key = fmt.Sprintf("usb:%d:%d", usb.Bus, usb.Device)
+			if _, ok := assigned[key]; !ok {
+				key = "usb:" + strings.ToLower(usb.Vendor.ID) + ":" + strings.ToLower(usb.Product.ID)
+			}
+		default:
+			continue
+		}
+		if domain, ok := assigned[key]; ok {
+			m.Domain = domain.Name
+			m.UUID = domain.UUID
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, nil
+}
+
+// assignedDomain is a domain a host device is assigned to
+type assignedDomain struct {
+	Name string
+	UUID string
+}
+
+// assignedHostDevices maps the host devices assigned to active domains to
+// the domain. PCI devices are keyed by "pci:" and their address, USB devices
+// by "usb:" and either their bus and device number or their vendor and
+// product ID, depending on how the domain selects them.
+func (mc *LibvirtMetricsCollector) assignedHostDevices(
+	ctx context.Context,
+	conn *libvirt.Connect,
+) (map[string]assignedDomain, error) {
+	domains, err := conn.ListAllDomains(libvirt.CONNECT_LIST_DOMAINS_ACTIVE)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, domain := range domains {
+			domain.Free()
+		}
+	}()
+
+	assigned := make(map[string]assignedDomain)
+	for i := range domains {
+		if ctx.Err() != nil {
+			break
+		}
+		domainName, domainUUID, err := mc.domainLabels(&domains[i])
+		if err != nil {
+			continue
+		}
+		domainXML, err := mc.getDomainXML(&domains[i])
+		if err != nil || domainXML.Devices == nil {
+			continue
+		}
+		owner := assignedDomain{Name: domainName, UUID: domainUUID}
+
+		addPCI := func(source *libvirtxml.DomainHostdevSubsysPCISource) {
+			if source != nil && source.Address != nil {
+				assigned["pci:"+pciAddressString(source.Address)] = owner
+			}
+		}
+		addUSB := func(source *libvirtxml.DomainHostdevSubsysUSBSource) {
+			if source == nil {
+				return
+			}
+			if address := source.Address; address != nil && address.Bus != nil && address.Device != nil {
+				assigned[fmt.Sprintf("usb:%d:%d", *address.Bus, *address.Device)] = owner
+			}
+			if source.Vendor != nil && source.Product != nil {
+				assigned["usb:"+strings.ToLower(source.Vendor.ID)+":"+strings.ToLower(source.Product.ID)] = owner
+			}
+		}
+		for _, hostdev := range domainXML.Devices.Hostdevs {
+			if hostdev.SubsysPCI != nil {
+				addPCI(hostdev.SubsysPCI.Source)
+			}
+			if hostdev.SubsysUSB != nil {
+				addUSB(hostdev.SubsysUSB.Source)
+			}
+		}
+		// SR-IOV virtual functions are often assigned as interfaces
+		for _, iface := range domainXML.Devices.Interfaces {
+			if iface.Source != nil && iface.Source.Hostdev != nil {
+				addPCI(iface.Source.Hostdev.PCI)
+				addUSB(iface.Source.Hostdev.USB)
+			}
+		}
+	}
+
+	return assigned, nil
+}
+
+// CollectFilesystemStats collects the usage of the guest filesystems from
+// the guest agent
+func (mc *LibvirtMetricsCollector) CollectFilesystemStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) ([]FilesystemMetrics, error) {
+	domainName, domainUUID, err := mc.domainLabels(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := domain.GetGuestInfo(libvirt.DOMAIN_GUEST_INFO_FILESYSTEM, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []FilesystemMetrics
+	for _, fs := range info.FileSystems {
+		// Pseudo filesystems come without sizes
+		if !fs.MountPointSet || !fs.TotalBytesSet {
+			continue
+		}
+		metrics = append(metrics, FilesystemMetrics{
+			Name:       domainName,
+			UUID:       domainUUID,
+			MountPoint: guestLabel(fs.MountPoint),
+			Device:     guestLabel(fs.Name),
+			FSType:     guestLabel(fs.FSType),
+			TotalBytes: fs.TotalBytes,
+			UsedBytes:  fs.UsedBytes,
+		})
+	}
+
+	return metrics, nil
+}
+
+// CollectKSMStats collects the kernel samepage merging counters of the host
+func (mc *LibvirtMetricsCollector) CollectKSMStats(ctx context.Context, conn *libvirt.Connect) (*KSMMetrics, error) {
+	params, err := conn.GetMemoryParameters(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KSMMetrics{
+		PagesShared:   params.ShmPagesShared,
+		PagesSharing:  params.ShmPagesSharing,
+		PagesUnshared: params.ShmPagesUnshared,
+		PagesVolatile: params.ShmPagesVolatile,
+		FullScans:     params.ShmFullScans,
+	}, nil
+}
+
+// CollectNodeMemoryStats collects the free memory of each host NUMA node
+func (mc *LibvirtMetricsCollector) CollectNodeMemoryStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+) ([]NodeMemoryMetrics, error) {
+	capsXML, err := conn.GetCapabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	var caps libvirtxml.Caps
+	if err := caps.Unmarshal(capsXML); err != nil {
+		return nil, err
+	}
+
+	// Cell IDs need not be contiguous; hosts without a NUMA topology have
+	// cell 0 only
+	cells := []int{0}
+	if caps.Host.NUMA != nil && caps.Host.NUMA.Cells != nil && len(caps.Host.NUMA.Cells.Cells) > 0 {
+		cells = cells[:0]
+		for _, cell := range caps.Host.NUMA.Cells.Cells {
+			cells = append(cells, cell.ID)
+		}
+	}
+
+	nodes := make([]NodeMemoryMetrics, 0, len(cells))
+	for _, cell := range cells {
+		free, err := conn.GetCellsFreeMemory(cell, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(free) == 0 {
+			continue
+		}
+		nodes = append(nodes, NodeMemoryMetrics{
+			Node:      cell,
+			FreeBytes: free[0],
+		})
+	}
+
+	return nodes, nil
+}
+
+// CollectHostStats collects host level statistics
+func (mc *LibvirtMetricsCollector) CollectHostStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+) (*HostMetrics, error) {
+	// Get hostname
+	hostname, err := conn.GetHostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	// Get node info
+	nodeInfo, err := conn.GetNodeInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get free memory
+	freeMemory, err := conn.GetFreeMemory()
+	if err != nil {
+		freeMemory = 0
+	}
+
+	// Get libvirt version
+	libvirtVersion, err := conn.GetLibVersion()
+	if err != nil {
+		libvirtVersion = 0
+	}
+
+	// Get hypervisor version
+	hypervisorVersion, err := conn.GetVersion()
+	if err != nil {
+		hypervisorVersion = 0
+	}
+
+	metrics := &HostMetrics{
+		Name:              hostname,
+		TotalCPUCount:     uint64(nodeInfo.Cpus),
+		OnlineCPUCount:    uint64(nodeInfo.Cpus), // Simplified, assuming all CPUs are online
+		TotalMemoryBytes:  uint64(nodeInfo.Memory) * 1024, // Convert from KB to bytes
+		FreeMemoryBytes:   freeMemory,
+		Hostname:          hostname,
+		LibvirtVersion:    uint64(libvirtVersion),
+		HypervisorVersion: uint64(hypervisorVersion),
+	}
+
+	return metrics, nil
+}
+
+// currentMemoryUnit returns the unit of the <currentMemory> element, which defaults to KiB
+func currentMemoryUnit(domainXML *libvirtxml.Domain) string {
+	if domainXML.CurrentMemory == nil {
+		return "KiB"
+	}
+	return memoryUnit(domainXML.CurrentMemory.Unit)
+}
+
+// memoryUnit returns the unit of a domain memory element, which defaults to KiB
+func memoryUnit(unit string) string {
+	if unit == "" {
+		return "KiB"
+	}
+	return unit
+}
+
+// scaleToBytes converts a value in a libvirt scaled unit to bytes
+func scaleToBytes(value uint64, unit string) uint64 {
+	switch strings.ToLower(unit) {
+	case "", "b", "bytes":
+		return value
+	case "kb":
+		return value * 1000
+	case "k", "kib":
+		return value << 10
+	case "mb":
+		return value * 1000 * 1000
+	case "m", "mib":
+		return value << 20
+	case "gb":
+		return value * 1000 * 1000 * 1000
+	case "g", "gib":
+		return value << 30
+	case "tb":
+		return value * 1000 * 1000 * 1000 * 1000
+	case "t", "tib":
+		return value << 40
+	default:
+		return value
+	}
+}
+
+// storagePoolStateToString converts a storage pool state to its libvirt name
+func storagePoolStateToString(state libvirt.StoragePoolState) string {
+	switch state {
+	case libvirt.STORAGE_POOL_INACTIVE:
+		return "inactive"
+	case libvirt.STORAGE_POOL_BUILDING:
+		return "building"
+	case libvirt.STORAGE_POOL_RUNNING:
+		return "running"
+	case libvirt.STORAGE_POOL_DEGRADED:
+		return "degraded"
+	case libvirt.STORAGE_POOL_INACCESSIBLE:
+		return "inaccessible"
+	default:
+		return "unknown"
+	}
+}
+
+// domainStateNames lists the names returned by domainStateToString
+var domainStateNames = []string{
+	"nostate",
+	"running",
+	"blocked",
+	"paused",
+	"shutdown",
+	"shutoff",
+	"crashed",
+	"pmsuspended",
+}
+
+// domainStateToString converts a domain state to its libvirt name
+func domainStateToString(state libvirt.DomainState) string {
+	switch state {
+	case libvirt.DOMAIN_RUNNING:
+		return "running"
+	case libvirt.DOMAIN_BLOCKED:
+		return "blocked"
+	case libvirt.DOMAIN_PAUSED:
+		return "paused"
+	case libvirt.DOMAIN_SHUTDOWN:
+		return "shutdown"
+	case libvirt.DOMAIN_SHUTOFF:
+		return "shutoff"
+	case libvirt.DOMAIN_CRASHED:
+		return "crashed"
+	case libvirt.DOMAIN_PMSUSPENDED:
+		return "pmsuspended"
+	default:
+		return "nostate"
+	}
+}
+
+// domainStateReasonToString converts the reason code of a domain state to
+// its libvirt name. Reason codes are defined per state.
+func domainStateReasonToString(state libvirt.DomainState, reason int) string {
+	switch state {
+	case libvirt.DOMAIN_RUNNING:
+		switch libvirt.DomainRunningReason(reason) {
+		case libvirt.DOMAIN_RUNNING_BOOTED:
+			return "booted"
+		case libvirt.DOMAIN_RUNNING_MIGRATED:
+			return "migrated"
+		case libvirt.DOMAIN_RUNNING_RESTORED:
+			return "restored"
+		case libvirt.DOMAIN_RUNNING_FROM_SNAPSHOT:
+			return "from_snapshot"
+		case libvirt.DOMAIN_RUNNING_UNPAUSED:
+			return "unpaused"
+		case libvirt.DOMAIN_RUNNING_MIGRATION_CANCELED:
+			return "migration_canceled"
+		case libvirt.DOMAIN_RUNNING_SAVE_CANCELED:
+			return "save_canceled"
+		case libvirt.DOMAIN_RUNNING_WAKEUP:
+			return "wakeup"
+		case libvirt.DOMAIN_RUNNING_CRASHED:
+			return "crashed"
+		case libvirt.DOMAIN_RUNNING_POSTCOPY:
+			return "postcopy"
+		case libvirt.DOMAIN_RUNNING_POSTCOPY_FAILED:
+			return "postcopy_failed"
+		}
+	case libvirt.DOMAIN_PAUSED:
+		switch libvirt.DomainPausedReason(reason) {
+		case libvirt.DOMAIN_PAUSED_USER:
+			return "user"
+		case libvirt.DOMAIN_PAUSED_MIGRATION:
+			return "migration"
+		case libvirt.DOMAIN_PAUSED_SAVE:
+			return "save"
+		case libvirt.DOMAIN_PAUSED_DUMP:
+			return "dump"
+		case libvirt.DOMAIN_PAUSED_IOERROR:
+			return "ioerror"
+		case libvirt.DOMAIN_PAUSED_WATCHDOG:
+			return "watchdog"
+		case libvirt.DOMAIN_PAUSED_FROM_SNAPSHOT:
+			return "from_snapshot"
+		case libvirt.DOMAIN_PAUSED_SHUTTING_DOWN:
+			return "shutting_down"
+		case libvirt.DOMAIN_PAUSED_SNAPSHOT:
+			return "snapshot"
+		case libvirt.DOMAIN_PAUSED_CRASHED:
+			return "crashed"
+		case libvirt.DOMAIN_PAUSED_STARTING_UP:
+			return "starting_up"
+		case libvirt.DOMAIN_PAUSED_POSTCOPY:
+			return "postcopy"
+		case libvirt.DOMAIN_PAUSED_POSTCOPY_FAILED:
+			return "postcopy_failed"
+		case libvirt.DOMAIN_PAUSED_API_ERROR:
+			return "api_error"
+		}
+	case libvirt.DOMAIN_SHUTDOWN:
+		switch libvirt.DomainShutdownReason(reason) {
+		case libvirt.DOMAIN_SHUTDOWN_USER:
+			return "user"
+		}
+	case libvirt.DOMAIN_SHUTOFF:
+		switch libvirt.DomainShutoffReason(reason) {
+		case libvirt.DOMAIN_SHUTOFF_SHUTDOWN:
+			return "shutdown"
+		case libvirt.DOMAIN_SHUTOFF_DESTROYED:
+			return "destroyed"
+		case libvirt.DOMAIN_SHUTOFF_CRASHED:
+			return "crashed"
+		case libvirt.DOMAIN_SHUTOFF_MIGRATED:
+			return "migrated"
+		case libvirt.DOMAIN_SHUTOFF_SAVED:
+			return "saved"
+		case libvirt.DOMAIN_SHUTOFF_FAILED:
+			return "failed"
+		case libvirt.DOMAIN_SHUTOFF_FROM_SNAPSHOT:
+			return "from_snapshot"
+		case libvirt.DOMAIN_SHUTOFF_DAEMON:
+			return "daemon"
+		}
+	case libvirt.DOMAIN_CRASHED:
+		switch libvirt.DomainCrashedReason(reason) {
+		case libvirt.DOMAIN_CRASHED_PANICKED:
+			return "panicked"
+		}
+	}
+	return "unknown"
+}
+
+// numatuneModeToString converts a NUMA memory mode to its libvirt name
+func numatuneModeToString(mode libvirt.DomainNumatuneMemMode) string {
+	switch mode {
+	case libvirt.DOMAIN_NUMATUNE_MEM_STRICT:
+		return "strict"
+	case libvirt.DOMAIN_NUMATUNE_MEM_PREFERRED:
+		return "preferred"
+	case libvirt.DOMAIN_NUMATUNE_MEM_INTERLEAVE:
+		return "interleave"
+	case libvirt.DOMAIN_NUMATUNE_MEM_RESTRICTIVE:
+		return "restrictive"
+	default:
+		return "unknown"
+	}
+}
+
+// vcpuStateToString converts a vCPU state to its libvirt name
+func vcpuStateToString(state libvirt.VcpuState) string {
+	switch state {
+	case libvirt.VCPU_RUNNING:
+		return "running"
+	case libvirt.VCPU_BLOCKED:
+		return "blocked"
+	default:
+		return "offline"
+	}
+}
+
+// Helper function to convert job type to string
+func jobTypeToString(jobType libvirt.DomainJobType) string {
+	switch jobType {
+	case libvirt.DOMAIN_JOB_BOUNDED:
+		return "bounded"
+	case libvirt.DOMAIN_JOB_UNBOUNDED:
+		return "unbounded"
+	case libvirt.DOMAIN_JOB_COMPLETED:
 		return "completed"
 	default:
 		return "none"
 	}
 }
+
+// blockJobTypeToString converts a block job type to a label value
+func blockJobTypeToString(jobType libvirt.DomainBlockJobType) string {
+	switch jobType {
+	case libvirt.DOMAIN_BLOCK_JOB_TYPE_PULL:
+		return "pull"
+	case libvirt.DOMAIN_BLOCK_JOB_TYPE_COPY:
+		return "copy"
+	case libvirt.DOMAIN_BLOCK_JOB_TYPE_COMMIT:
+		return "commit"
+	case libvirt.DOMAIN_BLOCK_JOB_TYPE_ACTIVE_COMMIT:
+		return "active-commit"
+	case libvirt.DOMAIN_BLOCK_JOB_TYPE_BACKUP:
+		return "backup"
+	default:
+		return "unknown"
+	}
+}
+
+// jobOperationToString converts a job operation to a label value
+func jobOperationToString(operation libvirt.DomainJobOperationType) string {
+	switch operation {
+	case libvirt.DOMAIN_JOB_OPERATION_START:
+		return "start"
+	case libvirt.DOMAIN_JOB_OPERATION_SAVE:
+		return "save"
+	case libvirt.DOMAIN_JOB_OPERATION_RESTORE:
+		return "restore"
+	case libvirt.DOMAIN_JOB_OPERATION_MIGRATION_IN:
+		return "migration_in"
+	case libvirt.DOMAIN_JOB_OPERATION_MIGRATION_OUT:
+		return "migration_out"
+	case libvirt.DOMAIN_JOB_OPERATION_SNAPSHOT:
+		return "snapshot"
+	case libvirt.DOMAIN_JOB_OPERATION_SNAPSHOT_REVERT:
+		return "snapshot_revert"
+	case libvirt.DOMAIN_JOB_OPERATION_DUMP:
+		return "dump"
+	case libvirt.DOMAIN_JOB_OPERATION_BACKUP:
+		return "backup"
+	case libvirt.DOMAIN_JOB_OPERATION_SNAPSHOT_DELETE:
+		return "snapshot_delete"
+	default:
+		return "unknown"
+	}
+}
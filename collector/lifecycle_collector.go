@@ -0,0 +1,423 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// lifecycleReconnectInterval is how often the event connection is checked
+// and re-established after it dropped
+const lifecycleReconnectInterval = 10 * time.Second
+
+// lifecycleEventKey identifies a lifecycle event counter of a domain
+type lifecycleEventKey struct {
+	event  string
+	detail string
+}
+
+// lifecycleHistory holds the lifecycle events received for a domain
+type lifecycleHistory struct {
+	domain string // name of the domain when its last event was received
+	name   string // label of the domain when its last event was received
+	counts map[lifecycleEventKey]uint64
+	last   time.Time
+	gone   time.Time // since when the domain is missing from libvirt
+}
+
+// LifecycleCollector counts domain lifecycle events (start, shutdown, crash,
+// ...) received from libvirt, so state changes between two scrapes are not
+// lost. Events are received on a dedicated connection and only cover the
+// time the exporter was running; events sent while the connection is down
+// are missed. The events of undefined domains are kept for a retention
+// period, so a crashed transient domain still shows up.
+type LifecycleCollector struct {
+	vmEvents    *prometheus.Desc
+	vmLastEvent *prometheus.Desc
+	sanitizer   *LabelSanitizer
+	notifier    *EventNotifier // nil if events are not forwarded
+
+	uris       []string
+	auth       *libvirt.ConnectAuth
+	stop       chan struct{}
+	conn       *libvirt.Connect // only used by the event goroutine
+	callbackID int
+
+	mutex     sync.Mutex
+	history   map[string]*lifecycleHistory // keyed by domain UUID
+	retention time.Duration
+}
+
+// NewLifecycleCollector creates a new LifecycleCollector and starts listening
+// for lifecycle events on the first reachable URI. Events are also passed to
+// notifier unless it is nil. The events of domains gone from libvirt are
+// dropped after retention. The libvirt event loop must have been started
+// before.
+func NewLifecycleCollector(
+	uris []string,
+	auth *libvirt.ConnectAuth,
+	sanitizer *LabelSanitizer,
+	notifier *EventNotifier,
+	retention time.Duration,
+) *LifecycleCollector {
+	c := &LifecycleCollector{
+		vmEvents: prometheus.NewDesc(
+			"libvirt_domain_events_total",
+			"Total number of lifecycle events received for the domain",
+			[]string{"domain", "uuid", "event", "detail"},
+			nil,
+		),
+		vmLastEvent: prometheus.NewDesc(
+			"libvirt_domain_last_event_timestamp_seconds",
+			"Unix timestamp of the last lifecycle event received for the domain",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		sanitizer: sanitizer,
+		notifier:  notifier,
+		uris:      uris,
+		auth:      auth,
+		stop:      make(chan struct{}),
+		history:   make(map[string]*lifecycleHistory),
+		retention: retention,
+	}
+
+	go c.run()
+	return c
+}
+
+// run keeps the event connection registered until Stop is called
+func (c *LifecycleCollector) run() {
+	ticker := time.NewTicker(lifecycleReconnectInterval)
+	defer ticker.Stop()
+
+	c.ensureRegistered()
+	for {
+		select {
+		case <-ticker.C:
+			c.ensureRegistered()
+		case <-c.stop:
+			c.closeConnection()
+			return
+		}
+	}
+}
+
+// ensureRegistered (re)connects and registers the lifecycle callback when the
+// event connection is missing or dead
+func (c *LifecycleCollector) ensureRegistered() {
+	if c.conn != nil {
+		if alive, err := c.conn.IsAlive(); err == nil && alive {
+			return
+		}
+		slog.Warn("Lifecycle event connection lost, reconnecting")
+		c.closeConnection()
+	}
+
+	conn, _, err := connectAny(c.uris, c.auth)
+	if err != nil {
+		slog.Warn("Failed to open lifecycle event connection", "err", err)
+		return
+	}
+
+	// Keepalives make a dead remote daemon show up in IsAlive
+	if err := conn.SetKeepAlive(5, 3); err != nil {
+		slog.Warn("Failed to enable keepalive on lifecycle event connection", "err", err)
+	}
+
+	callbackID, err := conn.DomainEventLifecycleRegister(nil, c.lifecycle)
+	if err != nil {
+		slog.Warn("Failed to register lifecycle events", "err", err)
+		conn.Close()
+		return
+	}
+
+	c.conn = conn
+	c.callbackID = callbackID
+}
+
+// closeConnection deregisters the callback and closes the event connection
+func (c *LifecycleCollector) closeConnection() {
+	if c.conn == nil {
+		return
+	}
+	c.conn.DomainEventDeregister(c.callbackID)
+	c.conn.Close()
+	c.conn = nil
+}
+
+// lifecycle records a lifecycle event; it runs on the event loop
+func (c *LifecycleCollector) lifecycle(
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+	event *libvirt.DomainEventLifecycle,
+) {
+	uuid, err := domain.GetUUIDString()
+	if err != nil {
+		slog.Warn("Failed to get UUID of domain for lifecycle event", "err", err)
+		return
+	}
+	name, err := domain.GetName()
+	if err != nil {
+		slog.Warn("Failed to get name of domain for lifecycle event", "uuid", uuid, "err", err)
+		return
+	}
+
+	key := lifecycleEventKey{
+		event:  lifecycleEventToString(event.Event),
+		detail: lifecycleDetailToString(event.Event, event.Detail),
+	}
+	slog.Debug("Domain lifecycle event", "domain", name, "event", key.event, "detail", key.detail)
+
+	now := time.Now()
+	if c.notifier != nil {
+		c.notifier.Notify(LifecycleEvent{
+			Timestamp: now,
+			Domain:    name,
+			UUID:      uuid,
+			Event:     key.event,
+			Detail:    key.detail,
+		})
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	history, ok := c.history[uuid]
+	if !ok {
+		history = &lifecycleHistory{counts: make(map[lifecycleEventKey]uint64)}
+		c.history[uuid] = history
+	}
+	history.domain = name
+	history.name = c.sanitizer.Label(name, uuid)
+	history.counts[key]++
+	history.last = now
+}
+
+// Stop stops listening for events and forwarding them, and closes the event
+// connection
+func (c *LifecycleCollector) Stop() {
+	close(c.stop)
+	if c.notifier != nil {
+		c.notifier.Stop()
+	}
+}
+
+// Describe implements the prometheus.Collector interface for LifecycleCollector
+func (c *LifecycleCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmEvents
+	ch <- c.vmLastEvent
+}
+
+// Collect implements the Collector interface for LifecycleCollector; its
+// metrics are host-wide and sent by CollectHost
+func (c *LifecycleCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+}
+
+// CollectHost implements the HostCollector interface for LifecycleCollector.
+// Events of the domains of the scrape are exported once per scrape, including
+// domains undefined within the retention period.
+func (c *LifecycleCollector) CollectHost(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	includes func(name, uuid string) bool,
+) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for uuid, history := range c.history {
+		if !includes(history.domain, uuid) {
+			continue
+		}
+		for key, count := range history.counts {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmEvents,
+				prometheus.CounterValue,
+				float64(count),
+				history.name,
+				uuid,
+				key.event,
+				key.detail,
+			)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmLastEvent,
+			prometheus.GaugeValue,
+			float64(history.last.Unix()),
+			history.name,
+			uuid,
+		)
+	}
+}
+
+// Reset implements the Collector interface
+func (c *LifecycleCollector) Reset() {
+	// History is kept across scrapes
+}
+
+// retain drops the events of domains gone for longer than the retention
+func (c *LifecycleCollector) retain(uuids map[string]bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for uuid, history := range c.history {
+		if expired(&history.gone, uuids[uuid], now, c.retention) {
+			delete(c.history, uuid)
+		}
+	}
+}
+
+// lifecycleEventToString converts a lifecycle event type to a label value
+func lifecycleEventToString(event libvirt.DomainEventType) string {
+	switch event {
+	case libvirt.DOMAIN_EVENT_DEFINED:
+		return "defined"
+	case libvirt.DOMAIN_EVENT_UNDEFINED:
+		return "undefined"
+	case libvirt.DOMAIN_EVENT_STARTED:
+		return "started"
+	case libvirt.DOMAIN_EVENT_SUSPENDED:
+		return "suspended"
+	case libvirt.DOMAIN_EVENT_RESUMED:
+		return "resumed"
+	case libvirt.DOMAIN_EVENT_STOPPED:
+		return "stopped"
+	case libvirt.DOMAIN_EVENT_SHUTDOWN:
+		return "shutdown"
+	case libvirt.DOMAIN_EVENT_PMSUSPENDED:
+		return "pmsuspended"
+	case libvirt.DOMAIN_EVENT_CRASHED:
+		return "crashed"
+	default:
+		return "unknown"
+	}
+}
+
+// lifecycleDetailToString converts the detail of a lifecycle event to a label
+// value
+func lifecycleDetailToString(event libvirt.DomainEventType, detail int) string {
+	switch event {
+	case libvirt.DOMAIN_EVENT_DEFINED:
+		switch libvirt.DomainEventDefinedDetailType(detail) {
+		case libvirt.DOMAIN_EVENT_DEFINED_ADDED:
+			return "added"
+		case libvirt.DOMAIN_EVENT_DEFINED_UPDATED:
+			return "updated"
+		case libvirt.DOMAIN_EVENT_DEFINED_RENAMED:
+			return "renamed"
+		case libvirt.DOMAIN_EVENT_DEFINED_FROM_SNAPSHOT:
+			return "from_snapshot"
+		}
+	case libvirt.DOMAIN_EVENT_UNDEFINED:
+		switch libvirt.DomainEventUndefinedDetailType(detail) {
+		case libvirt.DOMAIN_EVENT_UNDEFINED_REMOVED:
+			return "removed"
+		case libvirt.DOMAIN_EVENT_UNDEFINED_RENAMED:
+			return "renamed"
+		}
+	case libvirt.DOMAIN_EVENT_STARTED:
+		switch libvirt.DomainEventStartedDetailType(detail) {
+		case libvirt.DOMAIN_EVENT_STARTED_BOOTED:
+			return "booted"
+		case libvirt.DOMAIN_EVENT_STARTED_MIGRATED:
+			return "migrated"
+		case libvirt.DOMAIN_EVENT_STARTED_RESTORED:
+			return "restored"
+		case libvirt.DOMAIN_EVENT_STARTED_FROM_SNAPSHOT:
+			return "from_snapshot"
+		case libvirt.DOMAIN_EVENT_STARTED_WAKEUP:
+			return "wakeup"
+		case libvirt.DOMAIN_EVENT_STARTED_RECREATED:
+			return "recreated"
+		}
+	case libvirt.DOMAIN_EVENT_SUSPENDED:
+		switch libvirt.DomainEventSuspendedDetailType(detail) {
+		case libvirt.DOMAIN_EVENT_SUSPENDED_PAUSED:
+			return "paused"
+		case libvirt.DOMAIN_EVENT_SUSPENDED_MIGRATED:
+			return "migrated"
+		case libvirt.DOMAIN_EVENT_SUSPENDED_IOERROR:
+			return "ioerror"
+		case libvirt.DOMAIN_EVENT_SUSPENDED_WATCHDOG:
+			return "watchdog"
+		case libvirt.DOMAIN_EVENT_SUSPENDED_RESTORED:
+			return "restored"
+		case libvirt.DOMAIN_EVENT_SUSPENDED_FROM_SNAPSHOT:
+			return "from_snapshot"
+		case libvirt.DOMAIN_EVENT_SUSPENDED_API_ERROR:
+			return "api_error"
+		case libvirt.DOMAIN_EVENT_SUSPENDED_POSTCOPY:
+			return "postcopy"
+		case libvirt.DOMAIN_EVENT_SUSPENDED_POSTCOPY_FAILED:
+			return "postcopy_failed"
+		}
+	case libvirt.DOMAIN_EVENT_RESUMED:
+		switch libvirt.DomainEventResumedDetailType(detail) {
+		case libvirt.DOMAIN_EVENT_RESUMED_UNPAUSED:
+			return "unpaused"
+		case libvirt.DOMAIN_EVENT_RESUMED_MIGRATED:
+			return "migrated"
+		case libvirt.DOMAIN_EVENT_RESUMED_FROM_SNAPSHOT:
+			return "from_snapshot"
+		case libvirt.DOMAIN_EVENT_RESUMED_POSTCOPY:
+			return "postcopy"
+		case libvirt.DOMAIN_EVENT_RESUMED_POSTCOPY_FAILED:
+			return "postcopy_failed"
+		}
+	case libvirt.DOMAIN_EVENT_STOPPED:
+		switch libvirt.DomainEventStoppedDetailType(detail) {
+		case libvirt.DOMAIN_EVENT_STOPPED_SHUTDOWN:
+			return "shutdown"
+		case libvirt.DOMAIN_EVENT_STOPPED_DESTROYED:
+			return "destroyed"
+		case libvirt.DOMAIN_EVENT_STOPPED_CRASHED:
+			return "crashed"
+		case libvirt.DOMAIN_EVENT_STOPPED_MIGRATED:
+			return "migrated"
+		case libvirt.DOMAIN_EVENT_STOPPED_SAVED:
+			return "saved"
+		case libvirt.DOMAIN_EVENT_STOPPED_FAILED:
+			return "failed"
+		case libvirt.DOMAIN_EVENT_STOPPED_FROM_SNAPSHOT:
+			return "from_snapshot"
+		case libvirt.DOMAIN_EVENT_STOPPED_RECREATED:
+			return "recreated"
+		}
+	case libvirt.DOMAIN_EVENT_SHUTDOWN:
+		switch libvirt.DomainEventShutdownDetailType(detail) {
+		case libvirt.DOMAIN_EVENT_SHUTDOWN_FINISHED:
+			return "finished"
+		case libvirt.DOMAIN_EVENT_SHUTDOWN_GUEST:
+			return "guest"
+		case libvirt.DOMAIN_EVENT_SHUTDOWN_HOST:
+			return "host"
+		}
+	case libvirt.DOMAIN_EVENT_PMSUSPENDED:
+		switch libvirt.DomainEventPMSuspendedDetailType(detail) {
+		case libvirt.DOMAIN_EVENT_PMSUSPENDED_MEMORY:
+			return "memory"
+		case libvirt.DOMAIN_EVENT_PMSUSPENDED_DISK:
+			return "disk"
+		}
+	case libvirt.DOMAIN_EVENT_CRASHED:
+		switch libvirt.DomainEventCrashedDetailType(detail) {
+		case libvirt.DOMAIN_EVENT_CRASHED_PANICKED:
+			return "panicked"
+		case libvirt.DOMAIN_EVENT_CRASHED_CRASHLOADED:
+			return "crashloaded"
+		}
+	}
+	return "unknown"
+}
@@ -1,7 +1,9 @@
 package collector
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"libvirt.org/go/libvirt"
@@ -18,15 +20,21 @@ type MemoryCollector struct {
 	vmMemoryMajorFaults *prometheus.Desc
 	vmMemoryMinorFaults *prometheus.Desc
 	vmMemoryTotal       *prometheus.Desc
+	vmMemoryReclaimed   *prometheus.Desc
+	vmMemoryPageReport  *prometheus.Desc
+	vmBalloonTarget     *prometheus.Desc
+	vmBalloonDeviation  *prometheus.Desc
+	vmNUMAMemory        *prometheus.Desc
+	vmNUMATune          *prometheus.Desc
 	metricsCollector    MetricsCollector
 }
 
 // NewMemoryCollector creates a new MemoryCollector
-func NewMemoryCollector() *MemoryCollector {
+func NewMemoryCollector(metricsCollector MetricsCollector) *MemoryCollector {
 	return &MemoryCollector{
 		vmMemoryBalloon: prometheus.NewDesc(
 			"libvirt_vm_memory_balloon_bytes",
-			"Current balloon size in bytes",
+			"Current balloon size (actual memory seen by the guest) in bytes",
 			[]string{"domain", "uuid"},
 			nil,
 		),
@@ -74,11 +82,47 @@ func NewMemoryCollector() *MemoryCollector {
 		),
 		vmMemoryTotal: prometheus.NewDesc(
 			"libvirt_vm_memory_total_bytes",
-			"Total assigned memory in bytes",
+			"Configured current memory of the virtual machine in bytes",
 			[]string{"domain", "uuid"},
 			nil,
 		),
-		metricsCollector: NewLibvirtMetricsCollector(),
+		vmMemoryReclaimed: prometheus.NewDesc(
+			"libvirt_vm_memory_balloon_reclaimed_bytes",
+			"Memory returned to the host by the balloon (maximum memory minus balloon size) in bytes",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmMemoryPageReport: prometheus.NewDesc(
+			"libvirt_vm_memory_balloon_free_page_reporting",
+			"Whether free page reporting is enabled on the memory balloon (1 = enabled, 0 = disabled)",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmBalloonTarget: prometheus.NewDesc(
+			"libvirt_vm_memory_balloon_target_bytes",
+			"Memory the balloon driver is asked to leave to the guest (current memory of the domain config) in bytes",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmBalloonDeviation: prometheus.NewDesc(
+			"libvirt_vm_memory_balloon_deviation_bytes",
+			"Balloon target minus actual balloon size in bytes, non-zero while the balloon driver has not reached its target",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmNUMAMemory: prometheus.NewDesc(
+			"libvirt_vm_numa_configured_memory_bytes",
+			"Memory configured for the guest NUMA node in the domain definition in bytes, not the memory it uses",
+			[]string{"domain", "uuid", "node"},
+			nil,
+		),
+		vmNUMATune: prometheus.NewDesc(
+			"libvirt_vm_numatune_info",
+			"NUMA memory policy of the virtual machine and the host nodes its memory is bound to, value is always 1",
+			[]string{"domain", "uuid", "mode", "nodeset"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
 	}
 }
 
@@ -93,10 +137,17 @@ func (c *MemoryCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.vmMemoryMajorFaults
 	ch <- c.vmMemoryMinorFaults
 	ch <- c.vmMemoryTotal
+	ch <- c.vmMemoryReclaimed
+	ch <- c.vmMemoryPageReport
+	ch <- c.vmBalloonTarget
+	ch <- c.vmBalloonDeviation
+	ch <- c.vmNUMAMemory
+	ch <- c.vmNUMATune
 }
 
 // Collect implements the Collector interface for MemoryCollector
 func (c *MemoryCollector) Collect(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
@@ -104,7 +155,7 @@ func (c *MemoryCollector) Collect(
 	// Get domain info first to check if it's running
 	domainInfo, err := domain.GetInfo()
 	if err != nil {
-		log.Printf("Warning: Failed to get domain info for memory metrics: %v", err)
+		slog.Warn("Failed to get domain info for memory metrics", "err", err)
 		return
 	}
 
@@ -114,7 +165,7 @@ func (c *MemoryCollector) Collect(
 		return
 	}
 
-	metrics, err := c.metricsCollector.CollectMemoryStats(conn, domain)
+	metrics, err := c.metricsCollector.CollectMemoryStats(ctx, conn, domain)
 	if err != nil {
 		// Check if this is because domain is not running (expected for some operations)
 		if lverr, ok := err.(libvirt.Error); ok && lverr.Code == libvirt.ERR_OPERATION_INVALID {
@@ -123,7 +174,7 @@ func (c *MemoryCollector) Collect(
 		}
 		// For other errors, log with more context
 		domainName, _ := domain.GetName()
-		log.Printf("Warning: Failed to collect memory metrics for domain '%s': %v", domainName, err)
+		slog.Warn("Failed to collect memory metrics", "domain", domainName, "err", err)
 		return
 	}
 
@@ -199,6 +250,74 @@ func (c *MemoryCollector) Collect(
 		metrics.Name,
 		metrics.UUID,
 	)
+
+	// Only domains with a guest NUMA topology have nodes
+	for _, node := range metrics.NUMANodes {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmNUMAMemory,
+			prometheus.GaugeValue,
+			float64(node.TotalKB*1024),
+			metrics.Name,
+			metrics.UUID,
+			strconv.Itoa(node.NodeID),
+		)
+	}
+
+	if metrics.NUMAMode != "" {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmNUMATune,
+			prometheus.GaugeValue,
+			1.0,
+			metrics.Name,
+			metrics.UUID,
+			metrics.NUMAMode,
+			metrics.NUMANodeset,
+		)
+	}
+
+	// Reclaim metrics only make sense when a balloon device is present
+	if !metrics.HasBalloon {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmMemoryReclaimed,
+		prometheus.GaugeValue,
+		float64(metrics.Reclaimed*1024),
+		metrics.Name,
+		metrics.UUID,
+	)
+
+	pageReporting := 0.0
+	if metrics.FreePageReporting {
+		pageReporting = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.vmMemoryPageReport,
+		prometheus.GaugeValue,
+		pageReporting,
+		metrics.Name,
+		metrics.UUID,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmBalloonTarget,
+		prometheus.GaugeValue,
+		float64(metrics.Total*1024),
+		metrics.Name,
+		metrics.UUID,
+	)
+
+	// Without balloon statistics the actual size is unknown
+	if metrics.BalloonSize > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmBalloonDeviation,
+			prometheus.GaugeValue,
+			(float64(metrics.Total)-float64(metrics.BalloonSize))*1024,
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
 }
 
 // Reset implements the Collector interface
@@ -0,0 +1,236 @@
+package collector
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"libvirt.org/go/libvirt"
+)
+
+// DomainMetadata reads labels from an element of the <metadata> section of
+// domain definitions, e.g. the project and flavor of Nova instances, and
+// attaches them to every per-domain metric, i.e. every metric with a uuid
+// label. Domains without the element get empty label values.
+type DomainMetadata struct {
+	namespace string
+	names     []string // label names, sorted
+	paths     []metadataPath
+
+	mutex  sync.RWMutex
+	values map[string][]string // label values keyed by domain UUID
+}
+
+// metadataPath locates a value within the metadata element
+type metadataPath struct {
+	elements  []string // nested element names below the metadata element
+	attribute string   // attribute of the last element, empty for its text
+}
+
+// parseMetadataPath parses a path of element names separated by "/",
+// optionally ending with "@attribute", e.g. "owner/project@uuid"
+func parseMetadataPath(path string) (metadataPath, error) {
+	var parsed metadataPath
+	elements, attribute, hasAttribute := strings.Cut(path, "@")
+	if hasAttribute {
+		if attribute == "" || strings.ContainsAny(attribute, "/@") {
+			return parsed, fmt.Errorf("invalid attribute in metadata path %q", path)
+		}
+		parsed.attribute = attribute
+	}
+	if elements != "" {
+		parsed.elements = strings.Split(elements, "/")
+		for _, element := range parsed.elements {
+			if element == "" {
+				return parsed, fmt.Errorf("empty element in metadata path %q", path)
+			}
+		}
+	}
+	if len(parsed.elements) == 0 && parsed.attribute == "" {
+		return parsed, fmt.Errorf("empty metadata path")
+	}
+	return parsed, nil
+}
+
+// NewDomainMetadata creates a DomainMetadata reading the element of the
+// given namespace. labels maps label names to paths within the element.
+func NewDomainMetadata(namespace string, labels map[string]string) (*DomainMetadata, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("domain metadata namespace is empty")
+	}
+
+	m := &DomainMetadata{
+		namespace: namespace,
+		values:    make(map[string][]string),
+	}
+	for name := range labels {
+		m.names = append(m.names, name)
+	}
+	sort.Strings(m.names)
+	for _, name := range m.names {
+		path, err := parseMetadataPath(labels[name])
+		if err != nil {
+			return nil, fmt.Errorf("domain metadata label %q: %w", name, err)
+		}
+		m.paths = append(m.paths, path)
+	}
+	return m, nil
+}
+
+// metadataNode is an element of the metadata XML
+type metadataNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr     `xml:",any,attr"`
+	Text     string         `xml:",chardata"`
+	Children []metadataNode `xml:",any"`
+}
+
+// find returns the value at path below the node, empty if it does not exist
+func (n *metadataNode) find(path metadataPath) string {
+	node := n
+	for _, element := range path.elements {
+		var child *metadataNode
+		for i := range node.Children {
+			if node.Children[i].XMLName.Local == element {
+				child = &node.Children[i]
+				break
+			}
+		}
+		if child == nil {
+			return ""
+		}
+		node = child
+	}
+
+	if path.attribute == "" {
+		return strings.TrimSpace(node.Text)
+	}
+	for _, attr := range node.Attrs {
+		if attr.Name.Local == path.attribute {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// refresh reads the metadata labels of a domain. The previous values are
+// kept if the metadata cannot be read.
+func (m *DomainMetadata) refresh(domain *libvirt.Domain) {
+	uuid, err := domain.GetUUIDString()
+	if err != nil {
+		return
+	}
+
+	var values []string
+	data, err := domain.GetMetadata(libvirt.DOMAIN_METADATA_ELEMENT, m.namespace, libvirt.DOMAIN_AFFECT_CURRENT)
+	if err != nil {
+		if lverr, ok := err.(libvirt.Error); !ok || lverr.Code != libvirt.ERR_NO_DOMAIN_METADATA {
+			slog.Debug("Failed to get domain metadata", "uuid", uuid, "err", err)
+			return
+		}
+	} else {
+		var root metadataNode
+		if err := xml.Unmarshal([]byte(data), &root); err != nil {
+			slog.Debug("Failed to parse domain metadata", "uuid", uuid, "err", err)
+			return
+		}
+		values = make([]string, len(m.paths))
+		for i, path := range m.paths {
+			values[i] = root.find(path)
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.values[uuid] = values
+}
+
+// retain forgets the labels of domains that no longer exist
+func (m *DomainMetadata) retain(uuids map[string]bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for uuid := range m.values {
+		if !uuids[uuid] {
+			delete(m.values, uuid)
+		}
+	}
+}
+
+// wrap returns a channel attaching the metadata labels to the metrics sent to
+// ch, and a function to call once nothing is sent anymore
+func (m *DomainMetadata) wrap(ch chan<- prometheus.Metric) (chan<- prometheus.Metric, func()) {
+	metrics := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for metric := range metrics {
+			ch <- m.label(metric)
+		}
+	}()
+
+	return metrics, func() {
+		close(metrics)
+		<-done
+	}
+}
+
+// label attaches the metadata labels to a per-domain metric. Labels the
+// metric already has are not overridden.
+func (m *DomainMetadata) label(metric prometheus.Metric) prometheus.Metric {
+	var out dto.Metric
+	if err := metric.Write(&out); err != nil {
+		// Left for the registry to report
+		return metric
+	}
+	uuid := ""
+	existing := make(map[string]bool, len(out.Label))
+	for _, pair := range out.Label {
+		existing[pair.GetName()] = true
+		if pair.GetName() == "uuid" {
+			uuid = pair.GetValue()
+		}
+	}
+	if uuid == "" {
+		return metric
+	}
+
+	m.mutex.RLock()
+	values := m.values[uuid]
+	m.mutex.RUnlock()
+
+	labels := make([]*dto.LabelPair, 0, len(m.names))
+	for i, name := range m.names {
+		if existing[name] {
+			continue
+		}
+		value := ""
+		if values != nil {
+			value = values[i]
+		}
+		labels = append(labels, &dto.LabelPair{Name: &m.names[i], Value: &value})
+	}
+	return &metadataMetric{Metric: metric, labels: labels}
+}
+
+// metadataMetric is a metric with additional label pairs. Its descriptor
+// does not know the labels, which only pedantic registries check.
+type metadataMetric struct {
+	prometheus.Metric
+	labels []*dto.LabelPair
+}
+
+// Write implements the prometheus.Metric interface
+func (m *metadataMetric) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+	out.Label = append(out.Label, m.labels...)
+	return nil
+}
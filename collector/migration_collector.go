@@ -0,0 +1,272 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// migrationReconnectInterval is how often the event connection is checked
+// and re-established after it dropped
+const migrationReconnectInterval = 10 * time.Second
+
+// migrationHistory holds the completed migrations of a domain
+type migrationHistory struct {
+	in   uint64
+	out  uint64
+	last time.Time
+	gone time.Time // since when the domain is missing from libvirt
+}
+
+// MigrationCollector counts completed incoming and outgoing migrations per
+// domain from job-completed events. Events are received on a dedicated
+// connection, so the counters only cover migrations finished while the
+// exporter was running. The counts of domains gone from libvirt, e.g.
+// migrated away, are kept for a retention period.
+type MigrationCollector struct {
+	vmMigrationsIn  *prometheus.Desc
+	vmMigrationsOut *prometheus.Desc
+	vmLastMigration *prometheus.Desc
+	sanitizer       *LabelSanitizer
+
+	uris       []string
+	auth       *libvirt.ConnectAuth
+	stop       chan struct{}
+	conn       *libvirt.Connect // only used by the event goroutine
+	callbackID int
+
+	mutex     sync.Mutex
+	history   map[string]*migrationHistory // keyed by domain UUID
+	retention time.Duration
+}
+
+// NewMigrationCollector creates a new MigrationCollector and starts listening
+// for job-completed events on the first reachable URI. The counts of domains
+// gone from libvirt are dropped after retention. The libvirt event loop must
+// have been started before.
+func NewMigrationCollector(
+	uris []string,
+	auth *libvirt.ConnectAuth,
+	sanitizer *LabelSanitizer,
+	retention time.Duration,
+) *MigrationCollector {
+	c := &MigrationCollector{
+		vmMigrationsIn: prometheus.NewDesc(
+			"libvirt_vm_migrations_in_total",
+			"Total number of completed incoming migrations of the virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmMigrationsOut: prometheus.NewDesc(
+			"libvirt_vm_migrations_out_total",
+			"Total number of completed outgoing migrations of the virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmLastMigration: prometheus.NewDesc(
+			"libvirt_vm_last_migration_timestamp_seconds",
+			"Unix timestamp of the last completed migration of the virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		sanitizer: sanitizer,
+		uris:      uris,
+		auth:      auth,
+		stop:      make(chan struct{}),
+		history:   make(map[string]*migrationHistory),
+		retention: retention,
+	}
+
+	go c.run()
+	return c
+}
+
+// run keeps the event connection registered until Stop is called
+func (c *MigrationCollector) run() {
+	ticker := time.NewTicker(migrationReconnectInterval)
+	defer ticker.Stop()
+
+	c.ensureRegistered()
+	for {
+		select {
+		case <-ticker.C:
+			c.ensureRegistered()
+		case <-c.stop:
+			c.closeConnection()
+			return
+		}
+	}
+}
+
+// ensureRegistered (re)connects and registers the job-completed callback
+// when the event connection is missing or dead
+func (c *MigrationCollector) ensureRegistered() {
+	if c.conn != nil {
+		if alive, err := c.conn.IsAlive(); err == nil && alive {
+			return
+		}
+		slog.Warn("Migration event connection lost, reconnecting")
+		c.closeConnection()
+	}
+
+	conn, _, err := connectAny(c.uris, c.auth)
+	if err != nil {
+		slog.Warn("Failed to open migration event connection", "err", err)
+		return
+	}
+
+	// Keepalives make a dead remote daemon show up in IsAlive
+	if err := conn.SetKeepAlive(5, 3); err != nil {
+		slog.Warn("Failed to enable keepalive on migration event connection", "err", err)
+	}
+
+	callbackID, err := conn.DomainEventJobCompletedRegister(nil, c.jobCompleted)
+	if err != nil {
+		slog.Warn("Failed to register job completed events", "err", err)
+		conn.Close()
+		return
+	}
+
+	c.conn = conn
+	c.callbackID = callbackID
+}
+
+// closeConnection deregisters the callback and closes the event connection
+func (c *MigrationCollector) closeConnection() {
+	if c.conn == nil {
+		return
+	}
+	c.conn.DomainEventDeregister(c.callbackID)
+	c.conn.Close()
+	c.conn = nil
+}
+
+// jobCompleted records finished migrations; it runs on the event loop
+func (c *MigrationCollector) jobCompleted(
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+	event *libvirt.DomainEventJobCompleted,
+) {
+	if !event.Info.OperationSet {
+		return
+	}
+	operation := event.Info.Operation
+	if operation != libvirt.DOMAIN_JOB_OPERATION_MIGRATION_IN &&
+		operation != libvirt.DOMAIN_JOB_OPERATION_MIGRATION_OUT {
+		return
+	}
+
+	uuid, err := domain.GetUUIDString()
+	if err != nil {
+		slog.Warn("Failed to get UUID of migrated domain", "err", err)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	history, ok := c.history[uuid]
+	if !ok {
+		history = &migrationHistory{}
+		c.history[uuid] = history
+	}
+	if operation == libvirt.DOMAIN_JOB_OPERATION_MIGRATION_IN {
+		history.in++
+	} else {
+		history.out++
+	}
+	history.last = time.Now()
+}
+
+// Stop stops listening for events and closes the event connection
+func (c *MigrationCollector) Stop() {
+	close(c.stop)
+}
+
+// Describe implements the prometheus.Collector interface for MigrationCollector
+func (c *MigrationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmMigrationsIn
+	ch <- c.vmMigrationsOut
+	ch <- c.vmLastMigration
+}
+
+// Collect implements the Collector interface for MigrationCollector
+func (c *MigrationCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+	uuid, err := domain.GetUUIDString()
+	if err != nil {
+		slog.Warn("Failed to get domain UUID for migration metrics", "err", err)
+		return
+	}
+
+	c.mutex.Lock()
+	history, ok := c.history[uuid]
+	var in, out uint64
+	var last time.Time
+	if ok {
+		in, out, last = history.in, history.out, history.last
+	}
+	c.mutex.Unlock()
+
+	name, err := domain.GetName()
+	if err != nil {
+		slog.Warn("Failed to get domain name for migration metrics", "err", err)
+		return
+	}
+	name = c.sanitizer.Label(name, uuid)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmMigrationsIn,
+		prometheus.CounterValue,
+		float64(in),
+		name,
+		uuid,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmMigrationsOut,
+		prometheus.CounterValue,
+		float64(out),
+		name,
+		uuid,
+	)
+
+	// The timestamp is only known once a migration completed
+	if !ok {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmLastMigration,
+		prometheus.GaugeValue,
+		float64(last.Unix()),
+		name,
+		uuid,
+	)
+}
+
+// Reset implements the Collector interface
+func (c *MigrationCollector) Reset() {
+	// History is kept across scrapes
+}
+
+// retain drops the counts of domains gone for longer than the retention
+func (c *MigrationCollector) retain(uuids map[string]bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for uuid, history := range c.history {
+		if expired(&history.gone, uuids[uuid], now, c.retention) {
+			delete(c.history, uuid)
+		}
+	}
+}
@@ -1,7 +1,8 @@
 package collector
 
 import (
-	"log"
+	"context"
+	"log/slog"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"libvirt.org/go/libvirt"
@@ -17,11 +18,13 @@ type NetworkCollector struct {
 	vmNetworkTxErrs  *prometheus.Desc
 	vmNetworkRxDrop  *prometheus.Desc
 	vmNetworkTxDrop  *prometheus.Desc
+	vmNetworkQueues  *prometheus.Desc
+	vmNetworkInfo    *prometheus.Desc
 	metricsCollector MetricsCollector
 }
 
 // NewNetworkCollector creates a new NetworkCollector
-func NewNetworkCollector() *NetworkCollector {
+func NewNetworkCollector(metricsCollector MetricsCollector) *NetworkCollector {
 	return &NetworkCollector{
 		vmNetworkRxBytes: prometheus.NewDesc(
 			"libvirt_vm_network_rx_bytes_total",
@@ -71,7 +74,19 @@ func NewNetworkCollector() *NetworkCollector {
 			[]string{"domain", "uuid", "interface"},
 			nil,
 		),
-		metricsCollector: NewLibvirtMetricsCollector(),
+		vmNetworkQueues: prometheus.NewDesc(
+			"libvirt_vm_network_queues",
+			"Number of queues configured on a virtio network interface of the virtual machine",
+			[]string{"domain", "uuid", "interface", "driver"},
+			nil,
+		),
+		vmNetworkInfo: prometheus.NewDesc(
+			"libvirt_vm_interface_info",
+			"Configuration of the virtual machine network interface, value is always 1",
+			[]string{"domain", "uuid", "interface", "mac", "model", "type", "bridge", "network"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
 	}
 }
 
@@ -85,10 +100,13 @@ func (c *NetworkCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.vmNetworkTxErrs
 	ch <- c.vmNetworkRxDrop
 	ch <- c.vmNetworkTxDrop
+	ch <- c.vmNetworkQueues
+	ch <- c.vmNetworkInfo
 }
 
 // Collect implements the Collector interface for NetworkCollector
 func (c *NetworkCollector) Collect(
+	ctx context.Context,
 	ch chan<- prometheus.Metric,
 	conn *libvirt.Connect,
 	domain *libvirt.Domain,
@@ -96,7 +114,7 @@ func (c *NetworkCollector) Collect(
 	// Get domain info first to check if it's running
 	domainInfo, err := domain.GetInfo()
 	if err != nil {
-		log.Printf("Warning: Failed to get domain info for network metrics: %v", err)
+		slog.Warn("Failed to get domain info for network metrics", "err", err)
 		return
 	}
 
@@ -106,7 +124,7 @@ func (c *NetworkCollector) Collect(
 		return
 	}
 
-	metricsList, err := c.metricsCollector.CollectNetworkStats(conn, domain)
+	metricsList, err := c.metricsCollector.CollectNetworkStats(ctx, conn, domain)
 	if err != nil {
 		// Check if this is because domain is not running (expected for some operations)
 		if lverr, ok := err.(libvirt.Error); ok && lverr.Code == libvirt.ERR_OPERATION_INVALID {
@@ -115,7 +133,7 @@ func (c *NetworkCollector) Collect(
 		}
 		// For other errors, log with more context
 		domainName, _ := domain.GetName()
-		log.Printf("Warning: Failed to collect network metrics for domain '%s': %v", domainName, err)
+		slog.Warn("Failed to collect network metrics", "domain", domainName, "err", err)
 		return
 	}
 
@@ -191,6 +209,33 @@ func (c *NetworkCollector) Collect(
 			metrics.UUID,
 			metrics.Interface,
 		)
+
+		// Queue tuning only applies to virtio interfaces
+		if metrics.Model == "virtio" {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmNetworkQueues,
+				prometheus.GaugeValue,
+				float64(metrics.Queues),
+				metrics.Name,
+				metrics.UUID,
+				metrics.Interface,
+				valueOrDefault(metrics.Driver),
+			)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.vmNetworkInfo,
+			prometheus.GaugeValue,
+			1.0,
+			metrics.Name,
+			metrics.UUID,
+			metrics.Interface,
+			metrics.MACAddress,
+			metrics.Model,
+			metrics.Type,
+			metrics.Bridge,
+			metrics.Network,
+		)
 	}
 }
 
@@ -198,3 +243,6 @@ func (c *NetworkCollector) Collect(
 func (c *NetworkCollector) Reset() {
 	// No internal state to reset
 }
+
+// retainsCounters marks the counters of NetworkCollector as retained after the domain stops
+func (c *NetworkCollector) retainsCounters() {}
@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// NodeDeviceCollector collects the inventory of the PCI and USB devices of
+// the host: which are bound to vfio for passthrough and which active domain
+// each is assigned to, e.g. for GPU and SR-IOV dashboards
+type NodeDeviceCollector struct {
+	nodeDeviceInfo     *prometheus.Desc
+	nodeDeviceVFIO     *prometheus.Desc
+	nodeDeviceAssigned *prometheus.Desc
+	metricsCollector   MetricsCollector
+}
+
+// NewNodeDeviceCollector creates a new NodeDeviceCollector
+func NewNodeDeviceCollector(metricsCollector MetricsCollector) *NodeDeviceCollector {
+	return &NodeDeviceCollector{
+		nodeDeviceInfo: prometheus.NewDesc(
+			"libvirt_host_node_device_info",
+			"PCI or USB device of the host, value is always 1",
+			[]string{"device", "bus", "class", "vendor_id", "vendor", "product_id", "product", "driver"},
+			nil,
+		),
+		nodeDeviceVFIO: prometheus.NewDesc(
+			"libvirt_host_node_device_vfio",
+			"Whether the PCI device is bound to a vfio driver for passthrough",
+			[]string{"device"},
+			nil,
+		),
+		nodeDeviceAssigned: prometheus.NewDesc(
+			"libvirt_host_node_device_assigned",
+			"Host device assigned to a running virtual machine, value is always 1",
+			[]string{"device", "bus", "domain", "uuid"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+	}
+}
+
+// Describe implements the prometheus.Collector interface for NodeDeviceCollector
+func (c *NodeDeviceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.nodeDeviceInfo
+	ch <- c.nodeDeviceVFIO
+	ch <- c.nodeDeviceAssigned
+}
+
+// Reset implements the Collector interface for NodeDeviceCollector
+func (c *NodeDeviceCollector) Reset() {
+	// No internal state to reset
+}
+
+// Collect implements the Collector interface for NodeDeviceCollector; its
+// metrics are host-wide and sent by CollectHost
+func (c *NodeDeviceCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+}
+
+// CollectHost implements the HostCollector interface for NodeDeviceCollector
+func (c *NodeDeviceCollector) CollectHost(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	includes func(name, uuid string) bool,
+) {
+	devices, err := c.metricsCollector.CollectNodeDeviceStats(ctx, conn)
+	if err != nil {
+		slog.Warn("Failed to collect node device metrics", "err", err)
+		return
+	}
+
+	for _, device := range devices {
+		ch <- prometheus.MustNewConstMetric(
+			c.nodeDeviceInfo,
+			prometheus.GaugeValue,
+			1.0,
+			device.Name,
+			device.Bus,
+			device.Class,
+			device.VendorID,
+			device.Vendor,
+			device.ProductID,
+			device.Product,
+			device.Driver,
+		)
+
+		// Only PCI devices are passed through with vfio
+		if device.Bus == "pci" {
+			var vfio float64
+			if device.VFIO {
+				vfio = 1.0
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.nodeDeviceVFIO,
+				prometheus.GaugeValue,
+				vfio,
+				device.Name,
+			)
+		}
+
+		if device.UUID != "" {
+			ch <- prometheus.MustNewConstMetric(
+				c.nodeDeviceAssigned,
+				prometheus.GaugeValue,
+				1.0,
+				device.Name,
+				device.Bus,
+				device.Domain,
+				device.UUID,
+			)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// NodeMemoryCollector collects the free memory of each host NUMA node; the
+// size of the nodes is exported by the connection collector
+type NodeMemoryCollector struct {
+	nodeMemoryFree   *prometheus.Desc
+	metricsCollector MetricsCollector
+}
+
+// NewNodeMemoryCollector creates a new NodeMemoryCollector
+func NewNodeMemoryCollector(metricsCollector MetricsCollector) *NodeMemoryCollector {
+	return &NodeMemoryCollector{
+		nodeMemoryFree: prometheus.NewDesc(
+			"libvirt_host_numa_node_memory_free_bytes",
+			"Free memory of the host NUMA node in bytes",
+			[]string{"node"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+	}
+}
+
+// Describe implements the prometheus.Collector interface for NodeMemoryCollector
+func (c *NodeMemoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.nodeMemoryFree
+}
+
+// Reset implements the Collector interface for NodeMemoryCollector
+func (c *NodeMemoryCollector) Reset() {
+	// No internal state to reset
+}
+
+// Collect implements the Collector interface for NodeMemoryCollector; its
+// metrics are host-wide and sent by CollectHost
+func (c *NodeMemoryCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+}
+
+// CollectHost implements the HostCollector interface for NodeMemoryCollector
+func (c *NodeMemoryCollector) CollectHost(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	includes func(name, uuid string) bool,
+) {
+	nodes, err := c.metricsCollector.CollectNodeMemoryStats(ctx, conn)
+	if err != nil {
+		slog.Warn("Failed to collect NUMA node memory metrics", "err", err)
+		return
+	}
+
+	for _, node := range nodes {
+		ch <- prometheus.MustNewConstMetric(
+			c.nodeMemoryFree,
+			prometheus.GaugeValue,
+			float64(node.FreeBytes),
+			strconv.Itoa(node.Node),
+		)
+	}
+}
@@ -0,0 +1,180 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// notifyQueueSize is the number of events buffered for delivery; events
+	// arriving while the queue is full are dropped
+	notifyQueueSize = 256
+	// notifyInitialBackoff is the delay before the first retry of a failed
+	// delivery, doubled on every further retry
+	notifyInitialBackoff = time.Second
+	// notifyMaxBackoff caps the delay between retries
+	notifyMaxBackoff = 30 * time.Second
+)
+
+// LifecycleEvent is the JSON document sent for a domain lifecycle event
+type LifecycleEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Domain    string    `json:"domain"`
+	UUID      string    `json:"uuid"`
+	Event     string    `json:"event"`
+	Detail    string    `json:"detail"`
+}
+
+// EventNotifier forwards domain lifecycle events to external automation. An
+// http(s) target receives each event as a JSON POST request, a unix target
+// (unix:///path/to/socket) receives it as one line of JSON on a new stream
+// connection. Failed deliveries are retried with exponential backoff.
+type EventNotifier struct {
+	target     *url.URL
+	events     map[string]bool // nil forwards all events
+	client     *http.Client
+	timeout    time.Duration
+	maxRetries int
+	queue      chan LifecycleEvent
+	stop       chan struct{}
+}
+
+// NewEventNotifier creates a new EventNotifier delivering the given lifecycle
+// events (all events if empty) to target
+func NewEventNotifier(
+	target string,
+	events []string,
+	timeout time.Duration,
+	maxRetries int,
+) (*EventNotifier, error) {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event notification target: %w", err)
+	}
+	switch targetURL.Scheme {
+	case "http", "https":
+	case "unix":
+		if targetURL.Path == "" {
+			return nil, fmt.Errorf("event notification target %q has no socket path", target)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported event notification target scheme %q", targetURL.Scheme)
+	}
+
+	n := &EventNotifier{
+		target:     targetURL,
+		client:     &http.Client{Timeout: timeout},
+		timeout:    timeout,
+		maxRetries: maxRetries,
+		queue:      make(chan LifecycleEvent, notifyQueueSize),
+		stop:       make(chan struct{}),
+	}
+	if len(events) > 0 {
+		n.events = make(map[string]bool, len(events))
+		for _, event := range events {
+			n.events[event] = true
+		}
+	}
+
+	go n.run()
+	return n, nil
+}
+
+// Notify queues an event for delivery without blocking
+func (n *EventNotifier) Notify(event LifecycleEvent) {
+	if n.events != nil && !n.events[event.Event] {
+		return
+	}
+
+	select {
+	case n.queue <- event:
+	default:
+		slog.Warn("Event notification queue full, dropping event",
+			"domain", event.Domain, "event", event.Event)
+	}
+}
+
+// Stop stops delivering events; queued events are dropped
+func (n *EventNotifier) Stop() {
+	close(n.stop)
+}
+
+// run delivers queued events one at a time, preserving their order
+func (n *EventNotifier) run() {
+	for {
+		select {
+		case event := <-n.queue:
+			n.deliver(event)
+		case <-n.stop:
+			return
+		}
+	}
+}
+
+// deliver sends an event, retrying with exponential backoff until it is
+// accepted or the retries are used up
+func (n *EventNotifier) deliver(event LifecycleEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.Warn("Failed to encode event notification", "err", err)
+		return
+	}
+
+	backoff := notifyInitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := n.send(body)
+		if err == nil {
+			return
+		}
+		if attempt >= n.maxRetries {
+			slog.Warn("Failed to deliver event notification",
+				"domain", event.Domain, "event", event.Event, "attempts", attempt+1, "err", err)
+			return
+		}
+		slog.Debug("Retrying event notification", "domain", event.Domain, "backoff", backoff, "err", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-n.stop:
+			return
+		}
+		backoff *= 2
+		if backoff > notifyMaxBackoff {
+			backoff = notifyMaxBackoff
+		}
+	}
+}
+
+// send makes a single delivery attempt
+func (n *EventNotifier) send(body []byte) error {
+	if n.target.Scheme == "unix" {
+		conn, err := net.DialTimeout("unix", n.target.Path, n.timeout)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if n.timeout > 0 {
+			conn.SetDeadline(time.Now().Add(n.timeout))
+		}
+		_, err = conn.Write(append(body, '\n'))
+		return err
+	}
+
+	resp, err := n.client.Post(n.target.String(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// perfEvents are the perf events the collector can enable on domains
+var perfEvents = map[string]bool{
+	"cpu_cycles":    true,
+	"instructions":  true,
+	"cache_misses":  true,
+	"branch_misses": true,
+}
+
+// PerfCollector collects the hardware perf event counters of running
+// domains. Libvirt only counts the events enabled on a domain, which costs
+// some performance, so the collector reports the enabled events and can
+// enable configured events itself.
+type PerfCollector struct {
+	vmCPUCycles      *prometheus.Desc
+	vmInstructions   *prometheus.Desc
+	vmCacheMisses    *prometheus.Desc
+	vmBranchMisses   *prometheus.Desc
+	metricsCollector MetricsCollector
+
+	// Events are enabled at most once per domain
+	events     *libvirt.DomainPerfEvents // nil enables no events
+	mutex      sync.Mutex
+	enabled    map[string]uint64 // generation last seen, keyed by domain UUID
+	generation uint64
+}
+
+// NewPerfCollector creates a new PerfCollector enabling the given events
+// (e.g. "cpu_cycles") on running domains where they are disabled; no events
+// only reports events enabled by others (e.g. virsh perf --enable).
+func NewPerfCollector(metricsCollector MetricsCollector, events []string) (*PerfCollector, error) {
+	c := &PerfCollector{
+		vmCPUCycles: prometheus.NewDesc(
+			"libvirt_vm_perf_cpu_cycles_total",
+			"Total number of CPU cycles counted by the cpu_cycles perf event",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmInstructions: prometheus.NewDesc(
+			"libvirt_vm_perf_instructions_total",
+			"Total number of instructions counted by the instructions perf event",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmCacheMisses: prometheus.NewDesc(
+			"libvirt_vm_perf_cache_misses_total",
+			"Total number of cache misses counted by the cache_misses perf event",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmBranchMisses: prometheus.NewDesc(
+			"libvirt_vm_perf_branch_misses_total",
+			"Total number of branch mispredictions counted by the branch_misses perf event",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+		enabled:          make(map[string]uint64),
+	}
+
+	for _, event := range events {
+		if !perfEvents[event] {
+			return nil, fmt.Errorf("unknown perf event %q", event)
+		}
+		if c.events == nil {
+			c.events = &libvirt.DomainPerfEvents{}
+		}
+		switch event {
+		case "cpu_cycles":
+			c.events.CpuCyclesSet, c.events.CpuCycles = true, true
+		case "instructions":
+			c.events.InstructionsSet, c.events.Instructions = true, true
+		case "cache_misses":
+			c.events.CacheMissesSet, c.events.CacheMisses = true, true
+		case "branch_misses":
+			c.events.BranchMissesSet, c.events.BranchMisses = true, true
+		}
+	}
+	return c, nil
+}
+
+// Describe implements the prometheus.Collector interface for PerfCollector
+func (c *PerfCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmCPUCycles
+	ch <- c.vmInstructions
+	ch <- c.vmCacheMisses
+	ch <- c.vmBranchMisses
+}
+
+// Collect implements the Collector interface for PerfCollector
+func (c *PerfCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+	metrics, err := c.metricsCollector.CollectPerfStats(ctx, conn, domain)
+	if err != nil {
+		slog.Warn("Failed to collect perf metrics", "err", err)
+		return
+	}
+	if !metrics.Running {
+		return
+	}
+
+	counters := []struct {
+		desc  *prometheus.Desc
+		set   bool
+		value uint64
+	}{
+		{c.vmCPUCycles, metrics.HasCPUCycles, metrics.CPUCycles},
+		{c.vmInstructions, metrics.HasInstructions, metrics.Instructions},
+		{c.vmCacheMisses, metrics.HasCacheMisses, metrics.CacheMisses},
+		{c.vmBranchMisses, metrics.HasBranchMisses, metrics.BranchMisses},
+	}
+	for _, counter := range counters {
+		// Only enabled events are reported
+		if !counter.set {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			counter.desc,
+			prometheus.CounterValue,
+			float64(counter.value),
+			metrics.Name,
+			metrics.UUID,
+		)
+	}
+
+	// Events enabled now are reported by later scrapes
+	if c.events != nil && c.due(metrics.UUID) {
+		if err := domain.SetPerfEvents(c.events, libvirt.DOMAIN_AFFECT_LIVE); err != nil {
+			slog.Warn("Failed to enable perf events", "domain", metrics.Name, "err", err)
+		}
+	}
+}
+
+// due reports whether the events still have to be enabled on a domain and
+// records the domain as seen
+func (c *PerfCollector) due(uuid string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, ok := c.enabled[uuid]
+	c.enabled[uuid] = c.generation
+	return !ok
+}
+
+// Reset implements the Collector interface
+func (c *PerfCollector) Reset() {
+	// Cached domains are pruned after full scrapes only
+}
+
+// prune implements the pruner interface
+func (c *PerfCollector) prune() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Drop domains that were not seen during the scrape, so events are
+	// enabled again once they run again
+	for uuid, generation := range c.enabled {
+		if generation < c.generation {
+			delete(c.enabled, uuid)
+		}
+	}
+	c.generation++
+}
@@ -0,0 +1,217 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// ProbeCollector probes the libvirt daemon in the background, independently of
+// scrapes, so daemon degradation shows up even with long scrape intervals.
+// It uses its own connection so probes are not queued behind a running scrape.
+type ProbeCollector struct {
+	probeLatency        *prometheus.Desc
+	probeSuccess        *prometheus.Desc
+	consecutiveFailures *prometheus.Desc
+	probeFailures       *prometheus.Desc
+
+	uris     []string
+	auth     *libvirt.ConnectAuth
+	interval time.Duration
+	stop     chan struct{}
+	conn     *libvirt.Connect // only used by the probing goroutine
+
+	// Results of the last probe
+	mutex         sync.Mutex
+	probed        bool
+	latency       float64
+	success       bool
+	failuresInRow uint64
+	failuresTotal uint64
+}
+
+// NewProbeCollector creates a new ProbeCollector and starts probing the first
+// reachable URI every interval
+func NewProbeCollector(
+	uris []string,
+	auth *libvirt.ConnectAuth,
+	interval time.Duration,
+) *ProbeCollector {
+	c := &ProbeCollector{
+		probeLatency: prometheus.NewDesc(
+			"libvirt_daemon_probe_latency_seconds",
+			"Latency of the last libvirt daemon health probe in seconds",
+			[]string{},
+			nil,
+		),
+		probeSuccess: prometheus.NewDesc(
+			"libvirt_daemon_probe_success",
+			"Whether the last libvirt daemon health probe succeeded (1=success, 0=failure)",
+			[]string{},
+			nil,
+		),
+		consecutiveFailures: prometheus.NewDesc(
+			"libvirt_daemon_probe_consecutive_failures",
+			"Number of consecutive failed libvirt daemon health probes",
+			[]string{},
+			nil,
+		),
+		probeFailures: prometheus.NewDesc(
+			"libvirt_daemon_probe_failures_total",
+			"Total number of failed libvirt daemon health probes",
+			[]string{},
+			nil,
+		),
+		uris:     uris,
+		auth:     auth,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	go c.run()
+	return c
+}
+
+// run probes the daemon until Stop is called
+func (c *ProbeCollector) run() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.probe()
+	for {
+		select {
+		case <-ticker.C:
+			c.probe()
+		case <-c.stop:
+			if c.conn != nil {
+				c.conn.Close()
+				c.conn = nil
+			}
+			return
+		}
+	}
+}
+
+// probe performs a single cheap round trip to the daemon
+func (c *ProbeCollector) probe() {
+	start := time.Now()
+	err := c.roundTrip()
+	latency := time.Since(start).Seconds()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.latency = latency
+	c.probed = true
+
+	if err != nil {
+		if c.failuresInRow == 0 {
+			slog.Warn("libvirt daemon health probe failed", "err", err)
+		}
+		c.success = false
+		c.failuresInRow++
+		c.failuresTotal++
+		return
+	}
+	c.success = true
+	c.failuresInRow = 0
+}
+
+// roundTrip calls GetLibVersion, reconnecting first if needed
+func (c *ProbeCollector) roundTrip() error {
+	if c.conn == nil {
+		conn, _, err := connectAny(c.uris, c.auth)
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+	}
+
+	if _, err := c.conn.GetLibVersion(); err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Stop stops probing and closes the probe connection
+func (c *ProbeCollector) Stop() {
+	close(c.stop)
+}
+
+// Describe implements the prometheus.Collector interface for ProbeCollector
+func (c *ProbeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.probeLatency
+	ch <- c.probeSuccess
+	ch <- c.consecutiveFailures
+	ch <- c.probeFailures
+}
+
+// Reset implements the Collector interface for ProbeCollector
+func (c *ProbeCollector) Reset() {
+	// No internal state to reset
+}
+
+// Collect implements the Collector interface for ProbeCollector; its metrics
+// are host-wide and sent by CollectHost
+func (c *ProbeCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+}
+
+// CollectHost implements the HostCollector interface for ProbeCollector
+func (c *ProbeCollector) CollectHost(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	includes func(name, uuid string) bool,
+) {
+	c.mutex.Lock()
+	probed := c.probed
+	latency := c.latency
+	success := c.success
+	failuresInRow := c.failuresInRow
+	failuresTotal := c.failuresTotal
+	c.mutex.Unlock()
+
+	if !probed {
+		return
+	}
+
+	var successValue float64
+	if success {
+		successValue = 1.0
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.probeLatency,
+		prometheus.GaugeValue,
+		latency,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.probeSuccess,
+		prometheus.GaugeValue,
+		successValue,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.consecutiveFailures,
+		prometheus.GaugeValue,
+		float64(failuresInRow),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.probeFailures,
+		prometheus.CounterValue,
+		float64(failuresTotal),
+	)
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// ProcessCollector collects host-side statistics of the QEMU process of each
+// domain. /proc is only meaningful when the exporter runs on the hypervisor,
+// so nothing is collected for remote connections.
+type ProcessCollector struct {
+	vmProcessResident *prometheus.Desc
+	vmProcessCPU      *prometheus.Desc
+	vmProcessThreads  *prometheus.Desc
+	vmProcessFDs      *prometheus.Desc
+	metricsCollector  MetricsCollector
+
+	localOnce sync.Once
+	local     bool
+}
+
+// NewProcessCollector creates a new ProcessCollector
+func NewProcessCollector(metricsCollector MetricsCollector) *ProcessCollector {
+	return &ProcessCollector{
+		vmProcessResident: prometheus.NewDesc(
+			"libvirt_vm_qemu_process_resident_memory_bytes",
+			"Resident memory of the QEMU process of the virtual machine in bytes",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmProcessCPU: prometheus.NewDesc(
+			"libvirt_vm_qemu_process_cpu_seconds_total",
+			"User and system CPU time of the QEMU process of the virtual machine in seconds",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmProcessThreads: prometheus.NewDesc(
+			"libvirt_vm_qemu_process_threads",
+			"Number of threads of the QEMU process of the virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmProcessFDs: prometheus.NewDesc(
+			"libvirt_vm_qemu_process_open_fds",
+			"Number of open file descriptors of the QEMU process of the virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+	}
+}
+
+// Describe implements the prometheus.Collector interface for ProcessCollector
+func (c *ProcessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmProcessResident
+	ch <- c.vmProcessCPU
+	ch <- c.vmProcessThreads
+	ch <- c.vmProcessFDs
+}
+
+// Collect implements the Collector interface for ProcessCollector
+func (c *ProcessCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+	c.localOnce.Do(func() {
+		c.local = isLocalConnection(conn)
+		if !c.local {
+			slog.Warn("libvirt connection is not local, QEMU process metrics are disabled")
+		}
+	})
+	if !c.local {
+		return
+	}
+
+	// Get domain info first to check if it's running
+	domainInfo, err := domain.GetInfo()
+	if err != nil {
+		slog.Warn("Failed to get domain info for process metrics", "err", err)
+		return
+	}
+
+	// Only running domains have a QEMU process
+	if domainInfo.State != libvirt.DOMAIN_RUNNING {
+		return
+	}
+
+	metrics, err := c.metricsCollector.CollectProcessStats(ctx, conn, domain)
+	if err != nil {
+		domainName, _ := domain.GetName()
+		slog.Warn("Failed to collect QEMU process metrics", "domain", domainName, "err", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmProcessResident,
+		prometheus.GaugeValue,
+		float64(metrics.ResidentBytes),
+		metrics.Name,
+		metrics.UUID,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmProcessCPU,
+		prometheus.CounterValue,
+		metrics.CPUSeconds,
+		metrics.Name,
+		metrics.UUID,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmProcessThreads,
+		prometheus.GaugeValue,
+		float64(metrics.Threads),
+		metrics.Name,
+		metrics.UUID,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmProcessFDs,
+		prometheus.GaugeValue,
+		float64(metrics.OpenFDs),
+		metrics.Name,
+		metrics.UUID,
+	)
+}
+
+// Reset implements the Collector interface
+func (c *ProcessCollector) Reset() {
+	// No internal state to reset
+}
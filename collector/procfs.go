@@ -0,0 +1,199 @@
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"libvirt.org/go/libvirt"
+)
+
+const (
+	// procStatPath is the kernel/system statistics file of the local host
+	procStatPath = "/proc/stat"
+	// userHZ is the tick rate of /proc/stat CPU times on Linux
+	userHZ = 100
+	// procNetDevPath is the network device statistics file of the local host
+	procNetDevPath = "/proc/net/dev"
+)
+
+// procCPUTimes holds the aggregated CPU times of /proc/stat in seconds
+type procCPUTimes struct {
+	Iowait float64
+	Steal  float64
+}
+
+// readProcCPUTimes parses the aggregated "cpu" line of /proc/stat
+func readProcCPUTimes() (*procCPUTimes, error) {
+	file, err := os.Open(procStatPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "cpu" {
+			continue
+		}
+
+		// cpu user nice system idle iowait irq softirq steal ...
+		if len(fields) < 9 {
+			return nil, fmt.Errorf("unexpected cpu line in %s", procStatPath)
+		}
+		iowait, err := strconv.ParseUint(fields[5], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		steal, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &procCPUTimes{
+			Iowait: float64(iowait) / userHZ,
+			Steal:  float64(steal) / userHZ,
+		}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no cpu line in %s", procStatPath)
+}
+
+// procNetDevStats holds the counters of a network device from /proc/net/dev
+type procNetDevStats struct {
+	RxBytes   uint64
+	RxPackets uint64
+	TxBytes   uint64
+	TxPackets uint64
+}
+
+// readProcNetDev parses /proc/net/dev into counters keyed by device name
+func readProcNetDev() (map[string]procNetDevStats, error) {
+	file, err := os.Open(procNetDevPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	devices := make(map[string]procNetDevStats)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// The first two lines are headers without a device name
+		name, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		// rx: bytes packets errs drop fifo frame compressed multicast
+		// tx: bytes packets ...
+		fields := strings.Fields(counters)
+		if len(fields) < 10 {
+			return nil, fmt.Errorf("unexpected device line in %s", procNetDevPath)
+		}
+		var values [10]uint64
+		for i := range values {
+			values[i], err = strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		devices[strings.TrimSpace(name)] = procNetDevStats{
+			RxBytes:   values[0],
+			RxPackets: values[1],
+			TxBytes:   values[8],
+			TxPackets: values[9],
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// procProcessStats holds host-side statistics of a process
+type procProcessStats struct {
+	ResidentBytes uint64
+	CPUSeconds    float64
+	Threads       int
+	OpenFDs       int
+}
+
+// readPIDFile reads a process ID written by libvirt
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// readProcessStats reads the statistics of a process from /proc/<pid>
+func readProcessStats(pid int) (*procProcessStats, error) {
+	procDir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	data, err := os.ReadFile(filepath.Join(procDir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The command name may contain spaces, so fields are counted from the
+	// closing parenthesis: state is field 3, utime 14, stime 15,
+	// num_threads 20 and rss 24
+	stat := string(data)
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return nil, fmt.Errorf("malformed stat of process %d", pid)
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("malformed stat of process %d", pid)
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	threads, err := strconv.Atoi(fields[17])
+	if err != nil {
+		return nil, err
+	}
+	rssPages, err := strconv.ParseUint(fields[21], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	fds, err := os.ReadDir(filepath.Join(procDir, "fd"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &procProcessStats{
+		ResidentBytes: rssPages * uint64(os.Getpagesize()),
+		CPUSeconds:    float64(utime+stime) / userHZ,
+		Threads:       threads,
+		OpenFDs:       len(fds),
+	}, nil
+}
+
+// isLocalConnection reports whether the libvirt connection targets the host
+// the exporter runs on, so that its /proc describes the hypervisor
+func isLocalConnection(conn *libvirt.Connect) bool {
+	remoteHostname, err := conn.GetHostname()
+	if err != nil {
+		return false
+	}
+	localHostname, err := os.Hostname()
+	if err != nil {
+		return false
+	}
+	return remoteHostname == localHostname
+}
@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates a custom sub-collector. It receives the metrics collector
+// shared by all sub-collectors, so custom metrics agree with the built-in ones
+// on domain labels, and the exporter options. A nil Collector without error
+// skips the collector.
+type Factory func(metricsCollector MetricsCollector, opts Options) (Collector, error)
+
+// builtinCollectors are the names of the sub-collectors of this package
+var builtinCollectors = map[string]bool{
+	"exporter":        true,
+	"domain":          true,
+	"cpu":             true,
+	"memory":          true,
+	"disk":            true,
+	"network":         true,
+	"device":          true,
+	"connection":      true,
+	"node_memory":     true,
+	"ksm":             true,
+	"node_device":     true,
+	"job":             true,
+	"migration":       true,
+	"lifecycle":       true,
+	"snapshot":        true,
+	"process":         true,
+	"dirty_rate":      true,
+	"perf":            true,
+	"resctrl":         true,
+	"launch_security": true,
+	"filesystem":      true,
+	"probe":           true,
+	"admin":           true,
+}
+
+var (
+	factoriesMutex sync.Mutex
+	factories      = make(map[string]Factory)
+)
+
+// Register makes a custom sub-collector available under a name, usually from
+// the init function of a package compiled into the exporter. Registered
+// collectors are only created when enabled in Options.Collectors. Register
+// panics if the name is empty or already taken.
+func Register(name string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if name == "" || factory == nil {
+		panic("collector: Register called with an empty name or nil factory")
+	}
+	if builtinCollectors[name] || factories[name] != nil {
+		panic(fmt.Sprintf("collector: Register called twice for collector %q", name))
+	}
+	factories[name] = factory
+}
+
+// registeredCollectors returns the names of the registered custom collectors
+// in a stable order
+func registeredCollectors() []string {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registeredFactory returns the factory of a custom collector, nil if none is
+// registered under the name
+func registeredFactory(name string) Factory {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	return factories[name]
+}
+
+// knownCollector reports whether a name is a built-in or registered collector
+func knownCollector(name string) bool {
+	return builtinCollectors[name] || registeredFactory(name) != nil
+}
@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// ResctrlCollector collects the Intel RDT (resctrl) monitoring statistics of
+// running domains: the last level cache occupancy (CMT) and the memory
+// bandwidth (MBM) of each vCPU group with a cachetune or memorytune monitor.
+// Domains without monitors report nothing.
+type ResctrlCollector struct {
+	vmCacheOccupancy       *prometheus.Desc
+	vmMemoryBandwidthLocal *prometheus.Desc
+	vmMemoryBandwidth      *prometheus.Desc
+	metricsCollector       MetricsCollector
+}
+
+// NewResctrlCollector creates a new ResctrlCollector
+func NewResctrlCollector(metricsCollector MetricsCollector) *ResctrlCollector {
+	return &ResctrlCollector{
+		vmCacheOccupancy: prometheus.NewDesc(
+			"libvirt_vm_resctrl_cache_occupancy_bytes",
+			"Last level cache occupied by the vCPU group of a cache monitor on a cache bank in bytes",
+			[]string{"domain", "uuid", "monitor", "vcpus", "bank"},
+			nil,
+		),
+		vmMemoryBandwidthLocal: prometheus.NewDesc(
+			"libvirt_vm_resctrl_memory_bandwidth_local_bytes_total",
+			"Total bytes transferred by the vCPU group of a memory bandwidth monitor through the local memory controller of a NUMA node",
+			[]string{"domain", "uuid", "monitor", "vcpus", "node"},
+			nil,
+		),
+		vmMemoryBandwidth: prometheus.NewDesc(
+			"libvirt_vm_resctrl_memory_bandwidth_bytes_total",
+			"Total bytes transferred by the vCPU group of a memory bandwidth monitor through the memory controllers of a NUMA node, local and remote",
+			[]string{"domain", "uuid", "monitor", "vcpus", "node"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+	}
+}
+
+// Describe implements the prometheus.Collector interface for ResctrlCollector
+func (c *ResctrlCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmCacheOccupancy
+	ch <- c.vmMemoryBandwidthLocal
+	ch <- c.vmMemoryBandwidth
+}
+
+// Collect implements the Collector interface for ResctrlCollector
+func (c *ResctrlCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+	metrics, err := c.metricsCollector.CollectResctrlStats(ctx, conn, domain)
+	if err != nil {
+		slog.Warn("Failed to collect resctrl metrics", "err", err)
+		return
+	}
+
+	for _, cache := range metrics.CacheOccupancy {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmCacheOccupancy,
+			prometheus.GaugeValue,
+			float64(cache.Bytes),
+			metrics.Name,
+			metrics.UUID,
+			cache.Monitor,
+			cache.VCPUs,
+			strconv.FormatUint(uint64(cache.Bank), 10),
+		)
+	}
+
+	for _, bandwidth := range metrics.MemoryBandwidth {
+		node := strconv.FormatUint(uint64(bandwidth.Node), 10)
+		if bandwidth.HasLocalBytes {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmMemoryBandwidthLocal,
+				prometheus.CounterValue,
+				float64(bandwidth.LocalBytes),
+				metrics.Name,
+				metrics.UUID,
+				bandwidth.Monitor,
+				bandwidth.VCPUs,
+				node,
+			)
+		}
+		if bandwidth.HasTotalBytes {
+			ch <- prometheus.MustNewConstMetric(
+				c.vmMemoryBandwidth,
+				prometheus.CounterValue,
+				float64(bandwidth.TotalBytes),
+				metrics.Name,
+				metrics.UUID,
+				bandwidth.Monitor,
+				bandwidth.VCPUs,
+				node,
+			)
+		}
+	}
+}
+
+// Reset implements the Collector interface
+func (c *ResctrlCollector) Reset() {
+	// No internal state to reset
+}
@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"libvirt.org/go/libvirt"
+)
+
+// counterCollector is implemented by collectors whose counters are kept
+// around for a while after the domain stops running
+type counterCollector interface {
+	Collector
+	retainsCounters()
+}
+
+// CounterRetention keeps the last counter samples of running domains and
+// replays them for a retention period once the domain stops, so the final
+// values stay visible over the shutdown window. Replayed samples get a state
+// label with the state of the domain ("shutoff", or "undefined" once the
+// domain is gone). Gauges are never replayed, and domains not seen running
+// are collected as usual.
+type CounterRetention struct {
+	period  time.Duration
+	mutex   sync.Mutex
+	domains map[string]*retainedDomain // keyed by domain UUID
+}
+
+// retainedDomain holds the last counter samples emitted for a running domain
+type retainedDomain struct {
+	name        string
+	metrics     map[Collector][]prometheus.Metric
+	lastRunning time.Time
+	seen        bool
+}
+
+// retentionStateLabel is the label carrying the state of replayed domains
+var retentionStateLabel = "state"
+
+// NewCounterRetention creates a new CounterRetention
+func NewCounterRetention(period time.Duration) *CounterRetention {
+	return &CounterRetention{
+		period:  period,
+		domains: make(map[string]*retainedDomain),
+	}
+}
+
+// Collect runs a counter collector for a domain. Counter samples of running
+// domains are recorded; stopped domains get their gauges collected and the
+// last recorded counter samples replayed.
+func (r *CounterRetention) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	collector Collector,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+	name, uuid string,
+	state libvirt.DomainState,
+) {
+	if state != libvirt.DOMAIN_RUNNING {
+		metrics := r.retained(collector, uuid)
+		if metrics == nil {
+			collector.Collect(ctx, ch, conn, domain)
+			return
+		}
+
+		// Live counters would mix with the replayed ones
+		collectFiltered(ctx, ch, collector, conn, domain, func(metric prometheus.Metric) bool {
+			return !isCounter(metric)
+		})
+		stateName := domainStateToString(state)
+		for _, metric := range metrics {
+			ch <- withState(metric, stateName)
+		}
+		return
+	}
+
+	// Record the counter samples so they can be replayed later
+	var metrics []prometheus.Metric
+	collectFiltered(ctx, ch, collector, conn, domain, func(metric prometheus.Metric) bool {
+		if isCounter(metric) {
+			metrics = append(metrics, metric)
+		}
+		return true
+	})
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	retained, ok := r.domains[uuid]
+	if !ok {
+		retained = &retainedDomain{metrics: make(map[Collector][]prometheus.Metric)}
+		r.domains[uuid] = retained
+	}
+	retained.name = name
+	retained.metrics[collector] = metrics
+	retained.lastRunning = time.Now()
+	retained.seen = true
+}
+
+// retained returns the recorded counter samples of a stopped domain for one
+// collector, nil if the domain was not seen running within the period
+func (r *CounterRetention) retained(collector Collector, uuid string) []prometheus.Metric {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	retained, ok := r.domains[uuid]
+	if !ok || time.Since(retained.lastRunning) > r.period {
+		return nil
+	}
+	retained.seen = true
+	return retained.metrics[collector]
+}
+
+// Finish replays the retained counter samples of the collectors in scope for
+// domains that disappeared entirely (e.g. transient domains that shut down),
+// and forgets domains whose retention period has expired
+func (r *CounterRetention) Finish(
+	ch chan<- prometheus.Metric,
+	states map[string]libvirt.DomainState,
+	scope scrapeScope,
+) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for uuid, retained := range r.domains {
+		if time.Since(retained.lastRunning) > r.period {
+			delete(r.domains, uuid)
+			continue
+		}
+		if !scope.includes(retained.name, uuid) {
+			continue
+		}
+
+		_, exists := states[uuid]
+		if !exists && !retained.seen {
+			for _, collector := range scope.collectors {
+				for _, metric := range retained.metrics[collector] {
+					ch <- withState(metric, "undefined")
+				}
+			}
+		}
+		retained.seen = false
+	}
+}
+
+// collectFiltered runs a collector and forwards the samples keep accepts
+func collectFiltered(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	collector Collector,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+	keep func(prometheus.Metric) bool,
+) {
+	metrics := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for metric := range metrics {
+			if keep(metric) {
+				ch <- metric
+			}
+		}
+	}()
+	func() {
+		// Close the channel even if the collector panics
+		defer close(metrics)
+		collector.Collect(ctx, metrics, conn, domain)
+	}()
+	<-done
+}
+
+// isCounter reports whether a sample is a counter
+func isCounter(metric prometheus.Metric) bool {
+	var out dto.Metric
+	if err := metric.Write(&out); err != nil {
+		return false
+	}
+	return out.Counter != nil
+}
+
+// withState adds the state label to a replayed sample
+func withState(metric prometheus.Metric, state string) prometheus.Metric {
+	return &metadataMetric{
+		Metric: metric,
+		labels: []*dto.LabelPair{{Name: &retentionStateLabel, Value: &state}},
+	}
+}
@@ -0,0 +1,173 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"libvirt.org/go/libvirt"
+)
+
+// Label sanitization policies for domain label values
+const (
+	// LabelPolicyNone keeps domain names as-is (invalid UTF-8 is still replaced)
+	LabelPolicyNone = "none"
+	// LabelPolicyReplace replaces whitespace and control characters with '_'
+	LabelPolicyReplace = "replace"
+	// LabelPolicyASCII additionally replaces every non-ASCII character with '_'
+	LabelPolicyASCII = "ascii"
+)
+
+// LabelPolicies lists the supported label sanitization policies
+var LabelPolicies = []string{LabelPolicyNone, LabelPolicyReplace, LabelPolicyASCII}
+
+// LabelSanitizer maps domain names to label values according to a policy and
+// makes sure no two domains share the same label value within a scrape
+type LabelSanitizer struct {
+	policy string
+	mutex  sync.RWMutex
+	labels map[string]string // domain UUID -> label value
+}
+
+// NewLabelSanitizer creates a new LabelSanitizer for the given policy
+func NewLabelSanitizer(policy string) (*LabelSanitizer, error) {
+	if policy == "" {
+		policy = LabelPolicyNone
+	}
+	valid := false
+	for _, p := range LabelPolicies {
+		if p == policy {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown label policy %q", policy)
+	}
+
+	return &LabelSanitizer{
+		policy: policy,
+		labels: make(map[string]string),
+	}, nil
+}
+
+// Update rebuilds the label table for the given domains and returns the
+// number of collisions that had to be resolved. Domains are processed in
+// UUID order so the domain receiving a suffix is stable across scrapes.
+func (s *LabelSanitizer) Update(domains []libvirt.Domain) int {
+	type entry struct {
+		name string
+		uuid string
+	}
+
+	entries := make([]entry, 0, len(domains))
+	for i := range domains {
+		name, err := domains[i].GetName()
+		if err != nil {
+			continue
+		}
+		uuid, err := domains[i].GetUUIDString()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{name: name, uuid: uuid})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].uuid < entries[j].uuid
+	})
+
+	collisions := 0
+	labels := make(map[string]string, len(entries))
+	used := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		name := s.sanitize(e.name)
+		label := name
+		// A suffixed label may itself be taken, e.g. by a domain named
+		// after it, so disambiguate until the label is unused
+		for step := 0; used[label]; step++ {
+			label = disambiguate(name, e.uuid, step)
+			collisions++
+		}
+		used[label] = true
+		labels[e.uuid] = label
+	}
+
+	s.mutex.Lock()
+	s.labels = labels
+	s.mutex.Unlock()
+
+	return collisions
+}
+
+// Label returns the label value for a domain
+func (s *LabelSanitizer) Label(name, uuid string) string {
+	if s == nil {
+		return strings.ToValidUTF8(name, "�")
+	}
+
+	s.mutex.RLock()
+	label, ok := s.labels[uuid]
+	s.mutex.RUnlock()
+	if ok {
+		return label
+	}
+
+	// Domain was not part of the last update (e.g. created mid-scrape)
+	return s.sanitize(name)
+}
+
+// sanitize applies the configured policy to a single name
+func (s *LabelSanitizer) sanitize(name string) string {
+	name = strings.ToValidUTF8(name, "�")
+
+	switch s.policy {
+	case LabelPolicyReplace:
+		name = strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+				return '_'
+			}
+			return r
+		}, name)
+	case LabelPolicyASCII:
+		name = strings.Map(func(r rune) rune {
+			if r <= ' ' || r > '~' {
+				return '_'
+			}
+			return r
+		}, name)
+	}
+
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// shortUUID returns the first block of a UUID string
+func shortUUID(uuid string) string {
+	if i := strings.IndexByte(uuid, '-'); i > 0 {
+		return uuid[:i]
+	}
+	return uuid
+}
+
+// disambiguate returns the label of a domain whose name collides, suffixed
+// with the UUID prefix, then the full UUID, then the full UUID and a counter
+func disambiguate(name, uuid string, step int) string {
+	switch step {
+	case 0:
+		return fmt.Sprintf("%s_%s", name, shortUUID(uuid))
+	case 1:
+		return fmt.Sprintf("%s_%s", name, uuid)
+	default:
+		return fmt.Sprintf("%s_%s_%d", name, uuid, step-1)
+	}
+}
+
+// guestLabel returns the label value of a string chosen by a guest (e.g. its
+// hostname or mount points), which may not be valid UTF-8
+func guestLabel(value string) string {
+	return strings.ToValidUTF8(value, "�")
+}
@@ -0,0 +1,219 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"libvirt.org/go/libvirt"
+)
+
+// SnapshotCollector collects domain snapshot statistics
+type SnapshotCollector struct {
+	vmSnapshotCount  *prometheus.Desc
+	vmOldestAge      *prometheus.Desc
+	vmNewestAge      *prometheus.Desc
+	vmMaxDepth       *prometheus.Desc
+	vmSnapshotInfo   *prometheus.Desc
+	metricsCollector MetricsCollector
+
+	// Snapshot listing is expensive, so results may be reused between scrapes
+	interval   time.Duration
+	mutex      sync.Mutex
+	cache      map[string]*cachedSnapshots // keyed by domain UUID
+	generation uint64
+}
+
+// cachedSnapshots holds the last snapshot metrics collected for a domain
+type cachedSnapshots struct {
+	metrics     *SnapshotMetrics
+	collectedAt time.Time
+	generation  uint64
+}
+
+// NewSnapshotCollector creates a new SnapshotCollector. Snapshots of a domain
+// are listed at most once per interval; an interval of 0 lists them every scrape.
+func NewSnapshotCollector(metricsCollector MetricsCollector, interval time.Duration) *SnapshotCollector {
+	return &SnapshotCollector{
+		vmSnapshotCount: prometheus.NewDesc(
+			"libvirt_vm_snapshot_count",
+			"Number of snapshots for the virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmOldestAge: prometheus.NewDesc(
+			"libvirt_vm_snapshot_oldest_age_seconds",
+			"Age of the oldest snapshot of the virtual machine in seconds",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmNewestAge: prometheus.NewDesc(
+			"libvirt_vm_snapshot_newest_age_seconds",
+			"Age of the newest snapshot of the virtual machine in seconds",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmMaxDepth: prometheus.NewDesc(
+			"libvirt_vm_snapshot_max_depth",
+			"Length of the longest snapshot chain of the virtual machine",
+			[]string{"domain", "uuid"},
+			nil,
+		),
+		vmSnapshotInfo: prometheus.NewDesc(
+			"libvirt_vm_snapshot_info",
+			"Snapshot of the virtual machine, value is the creation time as a Unix timestamp",
+			[]string{"domain", "uuid", "snapshot", "state", "is_current"},
+			nil,
+		),
+		metricsCollector: metricsCollector,
+		interval:         interval,
+		cache:            make(map[string]*cachedSnapshots),
+	}
+}
+
+// Describe implements the prometheus.Collector interface for SnapshotCollector
+func (c *SnapshotCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.vmSnapshotCount
+	ch <- c.vmOldestAge
+	ch <- c.vmNewestAge
+	ch <- c.vmMaxDepth
+	ch <- c.vmSnapshotInfo
+}
+
+// Collect implements the Collector interface for SnapshotCollector
+func (c *SnapshotCollector) Collect(
+	ctx context.Context,
+	ch chan<- prometheus.Metric,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) {
+	snapshotMetrics, err := c.snapshotStats(ctx, conn, domain)
+	if err != nil {
+		slog.Warn("Failed to collect snapshot metrics", "err", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmSnapshotCount,
+		prometheus.GaugeValue,
+		float64(snapshotMetrics.Count),
+		snapshotMetrics.Name,
+		snapshotMetrics.UUID,
+	)
+
+	if len(snapshotMetrics.Snapshots) == 0 {
+		return
+	}
+
+	for _, snapshot := range snapshotMetrics.Snapshots {
+		var creationTime float64
+		if !snapshot.CreationTime.IsZero() {
+			creationTime = float64(snapshot.CreationTime.Unix())
+		}
+		current := "no"
+		if snapshot.Current {
+			current = "yes"
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.vmSnapshotInfo,
+			prometheus.GaugeValue,
+			creationTime,
+			snapshotMetrics.Name,
+			snapshotMetrics.UUID,
+			snapshot.Name,
+			snapshot.State,
+			current,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.vmMaxDepth,
+		prometheus.GaugeValue,
+		float64(snapshotMetrics.MaxDepth),
+		snapshotMetrics.Name,
+		snapshotMetrics.UUID,
+	)
+
+	// Ages are computed at scrape time, the snapshot list may be cached
+	if !snapshotMetrics.Oldest.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmOldestAge,
+			prometheus.GaugeValue,
+			time.Since(snapshotMetrics.Oldest).Seconds(),
+			snapshotMetrics.Name,
+			snapshotMetrics.UUID,
+		)
+	}
+
+	if !snapshotMetrics.LastCreate.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			c.vmNewestAge,
+			prometheus.GaugeValue,
+			time.Since(snapshotMetrics.LastCreate).Seconds(),
+			snapshotMetrics.Name,
+			snapshotMetrics.UUID,
+		)
+	}
+}
+
+// snapshotStats returns the snapshot metrics of a domain, reusing the cached
+// result while it is younger than the configured interval
+func (c *SnapshotCollector) snapshotStats(
+	ctx context.Context,
+	conn *libvirt.Connect,
+	domain *libvirt.Domain,
+) (*SnapshotMetrics, error) {
+	if c.interval <= 0 {
+		return c.metricsCollector.CollectSnapshotStats(ctx, conn, domain)
+	}
+
+	uuid, err := domain.GetUUIDString()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	cached, ok := c.cache[uuid]
+	if ok && time.Since(cached.collectedAt) < c.interval {
+		cached.generation = c.generation
+		c.mutex.Unlock()
+		return cached.metrics, nil
+	}
+	c.mutex.Unlock()
+
+	metrics, err := c.metricsCollector.CollectSnapshotStats(ctx, conn, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.cache[uuid] = &cachedSnapshots{
+		metrics:     metrics,
+		collectedAt: time.Now(),
+		generation:  c.generation,
+	}
+	c.mutex.Unlock()
+
+	return metrics, nil
+}
+
+// Reset implements the Collector interface
+func (c *SnapshotCollector) Reset() {
+	// Cached domains are pruned after full scrapes only
+}
+
+// prune implements the pruner interface
+func (c *SnapshotCollector) prune() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Drop domains that were not seen during the scrape
+	for uuid, cached := range c.cache {
+		if cached.generation < c.generation {
+			delete(c.cache, uuid)
+		}
+	}
+	c.generation++
+}
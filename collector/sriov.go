@@ -0,0 +1,193 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"libvirt.org/go/libvirt"
+	"libvirt.org/go/libvirtxml"
+)
+
+const (
+	// sysfsPCIDevicesPath lists the PCI devices of the local host
+	sysfsPCIDevicesPath = "/sys/bus/pci/devices"
+	// sriovTimeout bounds a single statistics query to the ip command
+	sriovTimeout = 5 * time.Second
+)
+
+// SRIOVStats fetches the counters of SR-IOV virtual functions passed through
+// to domains, for which libvirt's InterfaceStats fails as the guest drives the
+// device directly. Devices are resolved through sysfs, so only domains of the
+// local host are supported.
+type SRIOVStats struct {
+	command string // ip command of iproute2
+}
+
+// NewSRIOVStats creates an SRIOVStats reading the per-VF counters of physical
+// functions with the given ip command
+func NewSRIOVStats(ipCommand string) *SRIOVStats {
+	if ipCommand == "" {
+		ipCommand = "ip"
+	}
+	return &SRIOVStats{command: ipCommand}
+}
+
+// hostdevAddress returns the host PCI address of an interface of type
+// hostdev, e.g. "0000:3b:02.1", empty for other interfaces
+func hostdevAddress(iface *libvirtxml.DomainInterface) string {
+	if iface == nil || iface.Source == nil || iface.Source.Hostdev == nil {
+		return ""
+	}
+	source := iface.Source.Hostdev.PCI
+	if source == nil || source.Address == nil {
+		return ""
+	}
+	return pciAddressString(source.Address)
+}
+
+// InterfaceStats returns the counters of the virtual function at a PCI
+// address. A VF still bound to a host network driver is read from its
+// netdev; otherwise the physical function reports the counters of the VF,
+// which are already from the guest's point of view.
+func (s *SRIOVStats) InterfaceStats(address string) (*libvirt.DomainInterfaceStats, error) {
+	vfPath := filepath.Join(sysfsPCIDevicesPath, address)
+	if netdev := pciNetdev(vfPath); netdev != "" {
+		return readNetdevStats(netdev)
+	}
+
+	pfLink, err := os.Readlink(filepath.Join(vfPath, "physfn"))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an SR-IOV virtual function: %w", address, err)
+	}
+	pfPath := filepath.Join(sysfsPCIDevicesPath, filepath.Base(pfLink))
+	pfNetdev := pciNetdev(pfPath)
+	if pfNetdev == "" {
+		return nil, fmt.Errorf("physical function of %s has no network device", address)
+	}
+	index, err := vfIndex(pfPath, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.vfStats(pfNetdev, index)
+}
+
+// pciNetdev returns the network device of a PCI device, empty if it has none
+func pciNetdev(devicePath string) string {
+	entries, err := os.ReadDir(filepath.Join(devicePath, "net"))
+	if err != nil || len(entries) == 0 {
+		return ""
+	}
+	return entries[0].Name()
+}
+
+// vfIndex returns the index of the virtual function at address among the
+// virtual functions of a physical function
+func vfIndex(pfPath, address string) (int, error) {
+	links, err := filepath.Glob(filepath.Join(pfPath, "virtfn*"))
+	if err != nil {
+		return 0, err
+	}
+	for _, link := range links {
+		target, err := os.Readlink(link)
+		if err != nil || filepath.Base(target) != address {
+			continue
+		}
+		return strconv.Atoi(strings.TrimPrefix(filepath.Base(link), "virtfn"))
+	}
+	return 0, fmt.Errorf("virtual function %s not found on its physical function", address)
+}
+
+// readNetdevStats reads the counters of a host network device from sysfs
+func readNetdevStats(netdev string) (*libvirt.DomainInterfaceStats, error) {
+	dir := filepath.Join("/sys/class/net", netdev, "statistics")
+	read := func(name string) (int64, bool) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return 0, false
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		return value, err == nil
+	}
+
+	stats := &libvirt.DomainInterfaceStats{}
+	stats.RxBytes, stats.RxBytesSet = read("rx_bytes")
+	if !stats.RxBytesSet {
+		return nil, fmt.Errorf("failed to read statistics of %s", netdev)
+	}
+	stats.RxPackets, stats.RxPacketsSet = read("rx_packets")
+	stats.RxErrs, stats.RxErrsSet = read("rx_errors")
+	stats.RxDrop, stats.RxDropSet = read("rx_dropped")
+	stats.TxBytes, stats.TxBytesSet = read("tx_bytes")
+	stats.TxPackets, stats.TxPacketsSet = read("tx_packets")
+	stats.TxErrs, stats.TxErrsSet = read("tx_errors")
+	stats.TxDrop, stats.TxDropSet = read("tx_dropped")
+	return stats, nil
+}
+
+// ipLink is the part of the JSON output of "ip -s -j link show" holding the
+// virtual function counters
+type ipLink struct {
+	VFs []struct {
+		VF    int `json:"vf"`
+		Stats *struct {
+			Rx ipVFCounters `json:"rx"`
+			Tx ipVFCounters `json:"tx"`
+		} `json:"stats"`
+	} `json:"vfinfo_list"`
+}
+
+// ipVFCounters are the counters of a virtual function in one direction
+type ipVFCounters struct {
+	Bytes   *int64 `json:"bytes"`
+	Packets *int64 `json:"packets"`
+	Dropped *int64 `json:"dropped"`
+}
+
+// vfStats asks the physical function for the counters of one of its virtual
+// functions
+func (s *SRIOVStats) vfStats(pfNetdev string, index int) (*libvirt.DomainInterfaceStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sriovTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, s.command, "-s", "-j", "link", "show", "dev", pfNetdev).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ip link failed for %s: %w", pfNetdev, err)
+	}
+	var links []ipLink
+	if err := json.Unmarshal(output, &links); err != nil {
+		return nil, fmt.Errorf("failed to parse ip link output for %s: %w", pfNetdev, err)
+	}
+
+	for _, link := range links {
+		for _, vf := range link.VFs {
+			if vf.VF != index || vf.Stats == nil || vf.Stats.Rx.Bytes == nil {
+				continue
+			}
+			stats := &libvirt.DomainInterfaceStats{}
+			stats.RxBytes, stats.RxBytesSet = counterValue(vf.Stats.Rx.Bytes)
+			stats.RxPackets, stats.RxPacketsSet = counterValue(vf.Stats.Rx.Packets)
+			stats.RxDrop, stats.RxDropSet = counterValue(vf.Stats.Rx.Dropped)
+			stats.TxBytes, stats.TxBytesSet = counterValue(vf.Stats.Tx.Bytes)
+			stats.TxPackets, stats.TxPacketsSet = counterValue(vf.Stats.Tx.Packets)
+			stats.TxDrop, stats.TxDropSet = counterValue(vf.Stats.Tx.Dropped)
+			return stats, nil
+		}
+	}
+	return nil, fmt.Errorf("%s reports no counters for virtual function %d", pfNetdev, index)
+}
+
+// counterValue returns an optional counter and whether it is set
+func counterValue(value *int64) (int64, bool) {
+	if value == nil {
+		return 0, false
+	}
+	return *value, true
+}
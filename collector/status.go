@@ -0,0 +1,158 @@
+package collector
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status summarizes the state of the exporter for the status page
+type Status struct {
+	Version   string
+	Commit    string
+	URI       string // connected libvirt URI, empty while disconnected
+	StartTime time.Time
+	// LastScrape is when the last scrape finished, zero before the first one
+	LastScrape         time.Time
+	LastScrapeDuration time.Duration
+	LastScrapeError    string
+	Collectors         []CollectorStatus
+}
+
+// CollectorStatus is the state of a sub-collector
+type CollectorStatus struct {
+	Name    string
+	Enabled bool
+	// LastError is the last failure (panic or timeout) of the collector
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// statusTracker records the outcome of scrapes and sub-collector failures
+type statusTracker struct {
+	mutex           sync.Mutex
+	running         time.Time // start of the running scrape, zero if none
+	progress        time.Time // last domain or host collection finished
+	lastScrape      time.Time
+	lastDuration    time.Duration
+	lastErr         string
+	collectorErrors map[string]collectorError // keyed by collector name
+}
+
+// collectorError is the last failure of a sub-collector
+type collectorError struct {
+	err  string
+	time time.Time
+}
+
+// scraping records the start of a scrape
+func (t *statusTracker) scraping(start time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.running = start
+	t.progress = start
+}
+
+// progressed records that the running scrape finished collecting a domain
+// or the host
+func (t *statusTracker) progressed() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.progress = time.Now()
+}
+
+// scraped records a scrape started at start, err is nil if it succeeded
+func (t *statusTracker) scraped(start time.Time, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.running = time.Time{}
+	t.lastScrape = time.Now()
+	t.lastDuration = t.lastScrape.Sub(start)
+	t.lastErr = ""
+	if err != nil {
+		t.lastErr = err.Error()
+	}
+}
+
+// collectorFailed records the failure of a sub-collector
+func (t *statusTracker) collectorFailed(name string, err string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.collectorErrors == nil {
+		t.collectorErrors = make(map[string]collectorError)
+	}
+	t.collectorErrors[name] = collectorError{err: err, time: time.Now()}
+}
+
+// stuck reports whether the running scrape has not finished collecting a
+// domain or the host for longer than timeout. Long scrapes of many domains
+// are not stuck as long as domains keep being collected.
+func (t *statusTracker) stuck(timeout time.Duration) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return !t.running.IsZero() && time.Since(t.progress) > timeout
+}
+
+// Healthy reports whether the exporter makes progress: no scrape, of this
+// host or of a peer, has gone for longer than timeout without collecting a
+// domain or the host. A scrape stuck in a libvirt call also blocks all later
+// scrapes.
+func (c *LibvirtCollector) Healthy(timeout time.Duration) bool {
+	if c.status.stuck(timeout) {
+		return false
+	}
+
+	c.hostsMutex.RLock()
+	defer c.hostsMutex.RUnlock()
+	for _, peer := range c.hosts {
+		if peer.status.stuck(timeout) {
+			return false
+		}
+	}
+	return true
+}
+
+// Status returns the state of the exporter. Built-in and registered
+// collectors are listed by name, whether they run or not.
+func (c *LibvirtCollector) Status() Status {
+	status := Status{
+		Version:   c.opts.Version,
+		Commit:    c.opts.Commit,
+		StartTime: c.exporterCollector.startTime,
+	}
+	if c.connections.Connected() {
+		status.URI = c.exporterCollector.ActiveURI()
+	}
+
+	names := registeredCollectors()
+	for name := range builtinCollectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	enabled := make(map[string]bool, len(c.collectorNames))
+	for _, name := range c.collectorNames {
+		enabled[name] = true
+	}
+
+	c.status.mutex.Lock()
+	defer c.status.mutex.Unlock()
+
+	status.LastScrape = c.status.lastScrape
+	status.LastScrapeDuration = c.status.lastDuration
+	status.LastScrapeError = c.status.lastErr
+	for _, name := range names {
+		failure := c.status.collectorErrors[name]
+		status.Collectors = append(status.Collectors, CollectorStatus{
+			Name:          name,
+			Enabled:       enabled[name],
+			LastError:     failure.err,
+			LastErrorTime: failure.time,
+		})
+	}
+	return status
+}
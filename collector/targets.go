@@ -0,0 +1,307 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTargetNotAllowed is returned for targets not matching any allowed pattern
+	ErrTargetNotAllowed = errors.New("target not allowed")
+	// ErrTooManyTargets is returned when every cached target is in use
+	ErrTooManyTargets = errors.New("too many targets")
+)
+
+// safeTargetParams are the only URI parameters a client of the probe endpoint
+// may choose: the driver URI opened by the remote daemon and which daemon
+// answers. All others decide which local programs, sockets and key files are
+// used or how the server is verified (e.g. no_verify, known_hosts), which
+// must not be left to clients the configured credentials are sent through.
+var safeTargetParams = map[string]bool{
+	"name": true,
+	"mode": true,
+}
+
+// TargetCache keeps one LibvirtCollector per remote libvirt URI scraped
+// through the probe endpoint, so that a single exporter can monitor a fleet
+// of hypervisors. Connections are reused between scrapes and closed once
+// idle; the number of targets and of concurrent scrapes per target are bounded.
+type TargetCache struct {
+	opts          Options // without credentials, see targetOptions
+	credentials   Options // credentials attached to allowed targets only
+	allowed       []*regexp.Regexp
+	maxTargets    int
+	maxConcurrent int
+	idleTimeout   time.Duration
+	mutex         sync.Mutex
+	targets       map[string]*cachedTarget
+	stop          chan struct{}
+}
+
+// cachedTarget is the collector of a target and its usage
+type cachedTarget struct {
+	collector *LibvirtCollector
+	slots     chan struct{} // one token per running scrape
+	users     int
+	lastUsed  time.Time
+}
+
+// NewTargetCache creates a new TargetCache. Collectors are created with opts,
+// without further hosts and the features tied to the local host (fallback
+// URIs, daemon probe and admin metrics, events, leader election, background
+// collection and tracing).
+// Targets must fully match one of the allowed patterns, which are required.
+// Targets unused for idleTimeout are disconnected.
+func NewTargetCache(
+	opts Options,
+	allowed []string,
+	maxTargets int,
+	maxConcurrent int,
+	idleTimeout time.Duration,
+) (*TargetCache, error) {
+	opts.FallbackURIs = nil
+	opts.HostURIs = nil
+	opts.ProbeInterval = 0
+	opts.AdminURI = ""
+	opts.LeaderLock = ""
+	opts.EnableMigrations = false
+	opts.EnableEvents = false
+	opts.BackgroundInterval = 0
+	opts.TracingEndpoint = ""
+	if len(allowed) == 0 {
+		return nil, errors.New("probe targets require at least one allowed target pattern")
+	}
+
+	// Credentials are only handed to targets that passed the allow-list
+	credentials := Options{
+		AuthUsername:  opts.AuthUsername,
+		AuthPassword:  opts.AuthPassword,
+		TLSPKIPath:    opts.TLSPKIPath,
+		SSHKeyFile:    opts.SSHKeyFile,
+		SSHKnownHosts: opts.SSHKnownHosts,
+	}
+	opts.AuthUsername = ""
+	opts.AuthPassword = ""
+	opts.TLSPKIPath = ""
+	opts.SSHKeyFile = ""
+	opts.SSHKnownHosts = ""
+
+	t := &TargetCache{
+		opts:          opts,
+		credentials:   credentials,
+		maxTargets:    maxTargets,
+		maxConcurrent: maxConcurrent,
+		idleTimeout:   idleTimeout,
+		targets:       make(map[string]*cachedTarget),
+		stop:          make(chan struct{}),
+	}
+	for _, pattern := range allowed {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed target pattern %q: %w", pattern, err)
+		}
+		t.allowed = append(t.allowed, re)
+	}
+
+	go t.run()
+	return t, nil
+}
+
+// Acquire returns the collector of a target, connecting to it if needed, and
+// a function to call once the scrape is done. It waits for a free scrape slot
+// of the target until ctx is done.
+func (t *TargetCache) Acquire(ctx context.Context, uri string) (*LibvirtCollector, func(), error) {
+	if err := checkTargetURI(uri); err != nil {
+		return nil, nil, err
+	}
+	if !t.isAllowed(uri) {
+		return nil, nil, ErrTargetNotAllowed
+	}
+
+	target, err := t.target(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	select {
+	case target.slots <- struct{}{}:
+	case <-ctx.Done():
+		t.release(target)
+		return nil, nil, ctx.Err()
+	}
+
+	done := func() {
+		<-target.slots
+		t.release(target)
+	}
+	return target.collector, done, nil
+}
+
+// checkTargetURI rejects target URIs making libvirt run local programs or
+// use local sockets: the ext and unix transports, and every parameter not in
+// safeTargetParams
+func checkTargetURI(uri string) error {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTargetNotAllowed, err)
+	}
+	switch transport := uriTransport(uri); transport {
+	case "ext", "unix":
+		return fmt.Errorf("%w: transport %s", ErrTargetNotAllowed, transport)
+	}
+	for name := range parsed.Query() {
+		if !safeTargetParams[strings.ToLower(name)] {
+			return fmt.Errorf("%w: parameter %s", ErrTargetNotAllowed, name)
+		}
+	}
+	return nil
+}
+
+// isAllowed reports whether a target matches an allowed pattern
+func (t *TargetCache) isAllowed(uri string) bool {
+	for _, re := range t.allowed {
+		if re.MatchString(uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// target returns the cached target of a URI marked as in use, connecting to
+// it first if it is not cached yet
+func (t *TargetCache) target(uri string) (*cachedTarget, error) {
+	t.mutex.Lock()
+	if target, ok := t.targets[uri]; ok {
+		target.users++
+		t.mutex.Unlock()
+		return target, nil
+	}
+	t.mutex.Unlock()
+
+	// Connect without holding the lock, remote hosts may be slow to answer
+	collector, err := NewLibvirtCollector(uri, t.targetOptions(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// Another scrape may have connected to the same target meanwhile
+	if target, ok := t.targets[uri]; ok {
+		collector.Close()
+		target.users++
+		return target, nil
+	}
+	if t.maxTargets > 0 && len(t.targets) >= t.maxTargets && !t.evictLocked() {
+		collector.Close()
+		return nil, ErrTooManyTargets
+	}
+
+	target := &cachedTarget{
+		collector: collector,
+		slots:     make(chan struct{}, t.maxConcurrent),
+		users:     1,
+		lastUsed:  time.Now(),
+	}
+	t.targets[uri] = target
+	return target, nil
+}
+
+// targetOptions returns the options of a target's collector, with the
+// configured credentials only if the target is allowed
+func (t *TargetCache) targetOptions(uri string) Options {
+	opts := t.opts
+	if checkTargetURI(uri) == nil && t.isAllowed(uri) {
+		opts.AuthUsername = t.credentials.AuthUsername
+		opts.AuthPassword = t.credentials.AuthPassword
+		opts.TLSPKIPath = t.credentials.TLSPKIPath
+		opts.SSHKeyFile = t.credentials.SSHKeyFile
+		opts.SSHKnownHosts = t.credentials.SSHKnownHosts
+	}
+	return opts
+}
+
+// release marks a target as no longer used by a scrape
+func (t *TargetCache) release(target *cachedTarget) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	target.users--
+	target.lastUsed = time.Now()
+}
+
+// evictLocked disconnects the least recently used target not in use and
+// reports whether one was found
+func (t *TargetCache) evictLocked() bool {
+	var oldest string
+	for uri, target := range t.targets {
+		if target.users > 0 {
+			continue
+		}
+		if oldest == "" || target.lastUsed.Before(t.targets[oldest].lastUsed) {
+			oldest = uri
+		}
+	}
+	if oldest == "" {
+		return false
+	}
+
+	slog.Info("Disconnecting least recently used target", "target", oldest)
+	t.targets[oldest].collector.Close()
+	delete(t.targets, oldest)
+	return true
+}
+
+// run disconnects idle targets until Close is called
+func (t *TargetCache) run() {
+	interval := t.idleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.expire()
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// expire disconnects the targets unused for longer than the idle timeout
+func (t *TargetCache) expire() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for uri, target := range t.targets {
+		if target.users == 0 && time.Since(target.lastUsed) > t.idleTimeout {
+			slog.Info("Disconnecting idle target", "target", uri)
+			target.collector.Close()
+			delete(t.targets, uri)
+		}
+	}
+}
+
+// Close disconnects every target
+func (t *TargetCache) Close() {
+	close(t.stop)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for uri, target := range t.targets {
+		target.collector.Close()
+		delete(t.targets, uri)
+	}
+}
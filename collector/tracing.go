@@ -0,0 +1,349 @@
+package collector
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// traceQueueSize is the number of finished spans buffered for export;
+	// spans finished while the queue is full are dropped
+	traceQueueSize = 4096
+	// traceBatchSize is the number of spans sent in one export request
+	traceBatchSize = 512
+	// traceFlushInterval is how often buffered spans are exported
+	traceFlushInterval = 5 * time.Second
+	// traceScope is the instrumentation scope reported with every span
+	traceScope = "gitee.com/openeuler/uos-libvirtd-exporter/collector"
+)
+
+// OTLP span kinds and status codes
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusCodeError  = 2
+)
+
+// Tracer records spans of scrapes, domains and libvirt calls and exports
+// them to an OpenTelemetry collector with the OTLP/HTTP JSON protocol. A nil
+// Tracer records nothing.
+type Tracer struct {
+	endpoint    string
+	headers     map[string]string
+	client      *http.Client
+	resource    otlpResource
+	sampleRatio float64
+	queue       chan otlpSpan
+	stop        chan struct{}
+	done        chan struct{}
+	droppedMu   sync.Mutex
+	dropped     int
+}
+
+// NewTracer creates a new Tracer exporting to the OTLP/HTTP endpoint (e.g.
+// http://localhost:4318). sampleRatio is the fraction of scrapes traced.
+func NewTracer(
+	endpoint string,
+	serviceName string,
+	headers map[string]string,
+	timeout time.Duration,
+	sampleRatio float64,
+) (*Tracer, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracing endpoint: %w", err)
+	}
+	if endpointURL.Scheme != "http" && endpointURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported tracing endpoint scheme %q", endpointURL.Scheme)
+	}
+	endpointURL.Path = strings.TrimSuffix(endpointURL.Path, "/") + "/v1/traces"
+
+	t := &Tracer{
+		endpoint:    endpointURL.String(),
+		headers:     headers,
+		client:      &http.Client{Timeout: timeout},
+		sampleRatio: sampleRatio,
+		resource: otlpResource{
+			Attributes: []otlpAttribute{stringAttribute("service.name", serviceName)},
+		},
+		queue: make(chan otlpSpan, traceQueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go t.run()
+	return t, nil
+}
+
+// StartScrape starts the root span of a scrape, or returns nil if the scrape
+// is not sampled
+func (t *Tracer) StartScrape(name string) *Span {
+	if t == nil || !t.sampled() {
+		return nil
+	}
+
+	span := &Span{tracer: t, name: name, kind: spanKindInternal, start: time.Now()}
+	rand.Read(span.traceID[:])
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// sampled decides whether a new scrape is traced
+func (t *Tracer) sampled() bool {
+	return t.sampleRatio >= 1 || mathrand.Float64() < t.sampleRatio
+}
+
+// Stop stops the tracer after exporting the spans still buffered
+func (t *Tracer) Stop() {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+	<-t.done
+}
+
+// Span is a timed operation of a traced scrape. A nil Span is not recorded;
+// its methods do nothing, so untraced scrapes need no special handling. A
+// span is used by one goroutine at a time.
+type Span struct {
+	tracer     *Tracer
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	name       string
+	kind       int
+	start      time.Time
+	attributes []otlpAttribute
+	err        error
+}
+
+// Child starts a span nested in s
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+
+	span := &Span{
+		tracer:   s.tracer,
+		traceID:  s.traceID,
+		parentID: s.spanID,
+		name:     name,
+		kind:     spanKindInternal,
+		start:    time.Now(),
+	}
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// Call starts a span nested in s for a call to libvirt
+func (s *Span) Call(name string) *Span {
+	span := s.Child(name)
+	if span != nil {
+		span.kind = spanKindClient
+	}
+	return span
+}
+
+// SetAttribute attaches a key/value pair to the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.attributes = append(s.attributes, stringAttribute(key, value))
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.err = err
+}
+
+// TraceID returns the hex encoded trace ID, empty for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// End finishes the span and queues it for export without blocking
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes:        s.attributes,
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != nil {
+		span.Status = &otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+	}
+
+	select {
+	case s.tracer.queue <- span:
+	default:
+		s.tracer.droppedMu.Lock()
+		s.tracer.dropped++
+		s.tracer.droppedMu.Unlock()
+	}
+}
+
+// run exports finished spans in batches until the tracer is stopped
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]otlpSpan, 0, traceBatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			t.export(batch)
+			batch = batch[:0]
+		}
+
+		t.droppedMu.Lock()
+		dropped := t.dropped
+		t.dropped = 0
+		t.droppedMu.Unlock()
+		if dropped > 0 {
+			slog.Warn("Trace queue full, dropped spans", "spans", dropped)
+		}
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) >= traceBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case span := <-t.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends a batch of spans to the OTLP endpoint
+func (t *Tracer) export(spans []otlpSpan) {
+	body, err := json.Marshal(otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: t.resource,
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: traceScope},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		slog.Warn("Failed to encode spans", "err", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("Failed to create span export request", "err", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		slog.Warn("Failed to export spans", "endpoint", t.endpoint, "spans", len(spans), "err", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		slog.Warn("Failed to export spans", "endpoint", t.endpoint, "spans", len(spans), "status", resp.Status)
+	}
+}
+
+// otlpTraces is the OTLP/HTTP JSON encoding of an ExportTraceServiceRequest
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// stringAttribute returns an OTLP attribute with a string value
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAnyValue{StringValue: value}}
+}
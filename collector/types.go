@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"time"
 	"libvirt.org/go/libvirt"
 )
@@ -10,6 +11,8 @@ type DomainInfoMetrics struct {
 	Name          string    // domain name
 	UUID          string    // domain uuid
 	Status        float64   // domain state (running, paused, etc.)
+	State         string    // domain state name
+	VCPUs         uint      // current vCPU count
 	StateReason   string    // optional: state reason description
 	CPUTime       float64   // accumulated CPU time (ns)
 	Uptime        float64   // uptime in seconds
@@ -20,67 +23,111 @@ type DomainInfoMetrics struct {
 	Persistent    bool      // whether domain is persistent
 	ManagedSave   bool      // managed save image exists
 	BootTime      time.Time // guest boot time
+	GuestHostname string    // hostname reported by the guest agent
+	GenID         string    // VM generation ID, empty if not defined
+	ID            uint      // domain ID, only valid if HasID
+	HasID         bool      // the domain is active and has an ID
+	CPUSockets    int       // configured vCPU sockets, 0 if unknown
+	CPUCores      int       // cores per socket
+	CPUThreads    int       // threads per core
+	SecLabels     []SecLabel
+}
+
+// SecLabel represents the security label of a domain for one security driver
+type SecLabel struct {
+	Model   string // security driver, e.g. "selinux", "apparmor", "dac"
+	Type    string // "dynamic", "static" or "none"
+	Label   string // process label, empty if unconfined or not yet assigned
+	Relabel bool   // whether libvirt relabels the resources of the domain
 }
 
 // CPUStatsMetrics represents vCPU and scheduling metrics
 type CPUStatsMetrics struct {
-	Name         string
-	UUID         string
-	VCPUsMax     uint   // maximum vCPU count
-	VCPUsCurrent uint   // current active vCPUs
-	CPUTime      uint64 // total CPU time (ns)
-	UserTime     uint64 // guest user time (ns)
-	SystemTime   uint64 // guest system time (ns)
-	StealTime    uint64 // vCPU steal time (ns)
-	Scheduler    string // scheduler type (e.g. "cfs", "rt")
-	Quota        int64  // CPU quota in microseconds
-	Period       int64  // CPU period in microseconds
-	Affinity     string // CPU affinity bitmap string
+	Name          string
+	UUID          string
+	VCPUsMax      uint   // maximum vCPU count
+	VCPUsCurrent  uint   // current active vCPUs
+	CPUTime       uint64 // total CPU time (ns)
+	UserTime      uint64 // guest user time (ns)
+	SystemTime    uint64 // guest system time (ns)
+	StealTime     uint64 // vCPU steal time (ns), time spent waiting for a host CPU
+	WaitTime      uint64 // vCPU wait time (ns), time spent waiting for host I/O
+	Scheduler     string // scheduler type (e.g. "cfs", "rt")
+	HasQuota      bool   // the scheduler reports vCPU bandwidth
+	Quota         int64  // vCPU quota in microseconds (-1 = unlimited)
+	Period        int64  // vCPU period in microseconds
+	HasShares     bool   // the scheduler reports CPU shares
+	Shares        uint64 // relative CPU weight of the domain
+	HasEmulator   bool   // the scheduler reports emulator thread bandwidth
+	EmulatorQuota int64  // emulator thread quota in microseconds (-1 = unlimited)
+	Affinity      string // CPU affinity bitmap string
+	VCPUs         []VCPUMetrics
+}
+
+// VCPUMetrics represents the statistics of a single vCPU
+type VCPUMetrics struct {
+	Number  uint32
+	State   string // "running", "blocked" or "offline"
+	CPUTime uint64 // CPU time used by the vCPU (ns)
+	CPUs    []int  // host CPUs the vCPU may run on (nil = unknown)
+	Pinned  bool   // the vCPU is restricted to a subset of the host CPUs
 }
 
 // MemoryStatsMetrics represents guest memory balloon and usage metrics
 type MemoryStatsMetrics struct {
-	Name        string
-	UUID        string
-	BalloonSize uint64 // current balloon size (KB)
-	Unused      uint64 // guest unused memory (KB)
-	Available   uint64 // guest available memory (KB)
-	RSS         uint64 // resident set size (KB)
-	SwapIn      uint64 // swap in (KB)
-	SwapOut     uint64 // swap out (KB)
-	MajorFaults uint64 // major page faults
-	MinorFaults uint64 // minor page faults
-	Total       uint64 // total assigned memory (KB)
-	NUMANodes   []NUMANodeMemory
-}
-
-// NUMANodeMemory represents per-node memory statistics
+	Name              string
+	UUID              string
+	BalloonSize       uint64 // current balloon size (KB)
+	Unused            uint64 // guest unused memory (KB)
+	Available         uint64 // guest available memory (KB)
+	RSS               uint64 // resident set size (KB)
+	SwapIn            uint64 // swap in (KB)
+	SwapOut           uint64 // swap out (KB)
+	MajorFaults       uint64 // major page faults
+	MinorFaults       uint64 // minor page faults
+	Total             uint64 // configured current memory (KB)
+	Reclaimed         uint64 // memory returned to the host by the balloon (KB)
+	HasBalloon        bool   // a virtio balloon device is configured
+	FreePageReporting bool   // free page reporting enabled on the balloon
+	NUMANodes         []NUMANodeMemory
+	NUMAMode          string // numatune memory mode, empty if not tuned
+	NUMANodeset       string // host nodes the memory is bound to
+}
+
+// NUMANodeMemory represents the memory of a guest NUMA node
 type NUMANodeMemory struct {
-	NodeID   int
-	UsedKB   uint64
-	TotalKB  uint64
-	FreeKB   uint64
+	NodeID  int
+	TotalKB uint64 // memory assigned to the guest node
 }
 
 // DiskMetrics represents raw disk I/O and capacity metrics
 type DiskMetrics struct {
-	Name        string
-	UUID        string
-	Device      string
-	Path        string
-	ReadBytes   uint64
-	WriteBytes  uint64
-	ReadOps     uint64
-	WriteOps    uint64
-	ReadTimeNs  uint64
-	WriteTimeNs uint64
-	FlushOps    uint64
-	FlushBytes  uint64
-	Capacity    uint64 // total virtual disk size
-	Allocation  uint64 // allocated bytes on host
-	Physical    uint64 // physical bytes consumed on storage
-	CacheMode   string
-	BlockJob    *BlockJobMetrics
+	Name             string
+	UUID             string
+	Device           string
+	Path             string
+	ReadBytes        uint64
+	WriteBytes       uint64
+	ReadOps          uint64
+	WriteOps         uint64
+	ReadTimeNs       uint64
+	WriteTimeNs      uint64
+	FlushOps         uint64
+	FlushBytes       uint64
+	FlushTimeNs      uint64
+	Capacity         uint64 // total virtual disk size
+	Allocation       uint64 // allocated bytes on host
+	Physical         uint64 // physical bytes consumed on storage
+	Source           string // backing file, block device, pool/volume or network name
+	Format           string // image format, e.g. "qcow2", "raw"
+	Bus              string // e.g. "virtio", "scsi", "sata"
+	CacheMode        string
+	Encrypted        bool
+	EncryptionFormat string // e.g. "luks", "qcow"
+	IOMode           string // "native", "threads", "io_uring" (empty = default)
+	Discard          string // "unmap", "ignore" (empty = default)
+	BlockJob         *BlockJobMetrics
+	IOTune           *BlockIOTuneMetrics
 }
 
 // BlockJobMetrics represents active disk job (e.g. commit, copy, mirror)
@@ -88,6 +135,19 @@ type BlockJobMetrics struct {
 	Type      string  // "copy", "commit", "active-commit", etc.
 	Progress  float64 // 0.0 - 1.0
 	Bandwidth uint64  // bytes per second
+	Cursor    uint64  // progress of the job, in units of End
+	End       uint64  // value of Cursor once the job completes
+}
+
+// BlockIOTuneMetrics represents the I/O throttle limits of a disk, 0 means
+// unlimited
+type BlockIOTuneMetrics struct {
+	TotalBytesSec uint64
+	ReadBytesSec  uint64
+	WriteBytesSec uint64
+	TotalIOPSSec  uint64
+	ReadIOPSSec   uint64
+	WriteIOPSSec  uint64
 }
 
 // NetworkMetrics represents network interface statistics
@@ -97,6 +157,8 @@ type NetworkMetrics struct {
 	Interface    string
 	MACAddress   string
 	Type         string // bridge, macvtap, vhostuser, etc.
+	Bridge       string // host bridge the interface is attached to
+	Network      string // libvirt network the interface is attached to
 	RxBytes      uint64
 	TxBytes      uint64
 	RxPackets    uint64
@@ -108,6 +170,9 @@ type NetworkMetrics struct {
 	BandwidthRx  uint64 // bandwidth limit (bps)
 	BandwidthTx  uint64 // bandwidth limit (bps)
 	Multiqueue   bool
+	Model        string // device model, e.g. "virtio", "e1000"
+	Driver       string // backend driver, e.g. "vhost", "qemu" (empty = default)
+	Queues       uint   // configured queue count
 }
 
 // DeviceMetrics represents virtual devices attached to the domain
@@ -119,9 +184,61 @@ type DeviceMetrics struct {
 	PCIDevices  []PCIDevice
 	USBDevices  []USBDevice
 	VGPUDevices []VGPUDevice
+	VSock       *VSockDevice
+	Shmems      []ShmemDevice
+	Watchdogs   []WatchdogDevice
+	RNGs        []RNGDevice
+	CharDevices []CharDevice
+	Videos      []VideoDevice
+	Inputs      []InputDevice
 	Snapshots   int
 }
 
+// WatchdogDevice represents a watchdog device
+type WatchdogDevice struct {
+	Model  string // e.g. "i6300esb", "itco"
+	Action string // action when the watchdog fires, e.g. "reset"
+}
+
+// RNGDevice represents a random number generator device
+type RNGDevice struct {
+	Model   string // e.g. "virtio"
+	Backend string // "random", "egd" or "builtin"
+}
+
+// CharDevice represents a serial port, console or channel
+type CharDevice struct {
+	Kind string // "serial", "console" or "channel"
+	Type string // source type, e.g. "pty", "unix"
+}
+
+// VideoDevice represents a video device
+type VideoDevice struct {
+	Model     string // e.g. "virtio", "qxl", "vga"
+	VRAMBytes uint64 // video memory, 0 if not configured
+	Primary   bool
+}
+
+// InputDevice represents an input device
+type InputDevice struct {
+	Type string // e.g. "tablet", "mouse", "keyboard"
+	Bus  string // e.g. "usb", "virtio", "ps2"
+}
+
+// ShmemDevice represents a shared memory (ivshmem) device
+type ShmemDevice struct {
+	Name      string
+	Model     string // e.g. "ivshmem-plain", "ivshmem-doorbell"
+	SizeBytes uint64
+}
+
+// VSockDevice represents a virtio vsock device
+type VSockDevice struct {
+	Model   string
+	CID     uint64 // guest context ID, 0 if not assigned yet
+	AutoCID bool   // CID assigned automatically by libvirt
+}
+
 // PCIDevice represents a PCI passthrough device
 type PCIDevice struct {
 	Address string // e.g. "0000:00:02.0"
@@ -147,7 +264,8 @@ type VGPUDevice struct {
 type DomainJobMetrics struct {
 	Name        string
 	UUID        string
-	Type        string  // "migration", "block-commit", etc.
+	Type        string  // "bounded" or "unbounded", empty without a job
+	Operation   string  // "migration_out", "backup", etc.
 	Progress    float64 // 0.0 ~ 1.0
 	Remaining   uint64  // bytes remaining
 	Transferred uint64  // bytes transferred
@@ -162,6 +280,18 @@ type SnapshotMetrics struct {
 	Count      int
 	LastCreate time.Time
 	LastDelete time.Time
+	Oldest     time.Time // creation time of the oldest snapshot
+	MaxDepth   int       // length of the longest parent chain
+	Snapshots  []SnapshotInfo
+}
+
+// SnapshotInfo represents a single domain snapshot
+type SnapshotInfo struct {
+	Name         string
+	Parent       string // name of the parent snapshot, empty for roots
+	State        string // domain state captured, e.g. "running", "shutoff"
+	Current      bool   // the snapshot is the current snapshot of the domain
+	CreationTime time.Time
 }
 
 // ConnectionMetrics represents libvirt connection and host statistics
@@ -177,6 +307,8 @@ type ConnectionMetrics struct {
 	TotalMemoryBytes    uint64
 	TotalCPUs           int
 	HostCPUUsagePercent float64
+	HasHostCPUUsage     bool               // usage needs the CPU times of a previous call
+	HostCPUTimes        map[string]float64 // seconds keyed by mode (kernel, user, idle, iowait)
 	StoragePools        []StoragePoolMetrics
 	Networks            []NetworkPoolMetrics
 	Interfaces          []HostInterfaceMetrics
@@ -195,9 +327,21 @@ type StoragePoolMetrics struct {
 
 // NetworkPoolMetrics represents virtual network stats
 type NetworkPoolMetrics struct {
-	Name   string
-	Active bool
-	Bridge string
+	Name       string
+	Active     bool
+	Autostart  bool
+	Persistent bool
+	Bridge     string
+	DHCPLeases int // leases handed out by the network's DHCP server
+	Leases     []DHCPLeaseMetrics
+}
+
+// DHCPLeaseMetrics represents a DHCP lease of a virtual network
+type DHCPLeaseMetrics struct {
+	MAC        string
+	IP         string
+	Hostname   string    // empty if the client did not send one
+	ExpiryTime time.Time // zero for leases that never expire
 }
 
 // HostInterfaceMetrics represents physical NIC stats on host
@@ -209,6 +353,176 @@ type HostInterfaceMetrics struct {
 	TxPackets uint64
 }
 
+// DirtyRateMetrics represents the result of the last memory dirty rate
+// calculation of a domain
+type DirtyRateMetrics struct {
+	Name           string
+	UUID           string
+	Running        bool   // dirty rates are only calculated for running domains
+	Measuring      bool   // a calculation is in progress
+	Measured       bool   // a calculation has completed
+	BytesPerSecond uint64 // dirty rate of the last completed calculation
+}
+
+// PerfMetrics represents the perf event counters of a running domain. Only
+// the events enabled on the domain are set.
+type PerfMetrics struct {
+	Name            string
+	UUID            string
+	Running         bool // perf events are only counted for running domains
+	HasCPUCycles    bool
+	CPUCycles       uint64
+	HasInstructions bool
+	Instructions    uint64
+	HasCacheMisses  bool
+	CacheMisses     uint64
+	HasBranchMisses bool
+	BranchMisses    uint64
+}
+
+// ResctrlMetrics represents the Intel RDT monitoring statistics of a domain
+type ResctrlMetrics struct {
+	Name            string
+	UUID            string
+	CacheOccupancy  []ResctrlCacheMetrics
+	MemoryBandwidth []ResctrlBandwidthMetrics
+}
+
+// ResctrlCacheMetrics represents the cache occupancy of a cache monitor on a
+// cache bank
+type ResctrlCacheMetrics struct {
+	Monitor string // monitor name
+	VCPUs   string // monitored vCPUs, e.g. "0-3"
+	Bank    uint   // cache bank ID
+	Bytes   uint64 // occupied cache in bytes
+}
+
+// ResctrlBandwidthMetrics represents the memory bandwidth used by a memory
+// bandwidth monitor on a NUMA node
+type ResctrlBandwidthMetrics struct {
+	Monitor       string // monitor name
+	VCPUs         string // monitored vCPUs, e.g. "0-3"
+	Node          uint   // NUMA node ID
+	HasLocalBytes bool
+	LocalBytes    uint64 // bytes through the local memory controller
+	HasTotalBytes bool
+	TotalBytes    uint64 // bytes through local and remote memory controllers
+}
+
+// LaunchSecurityMetrics represents the confidential computing technology
+// protecting a domain
+type LaunchSecurityMetrics struct {
+	Name        string
+	UUID        string
+	Type        string // "sev", "sev-es", "sev-snp", "tdx", "s390-pv" or "none"
+	HasPolicy   bool
+	Policy      uint64 // guest policy of the technology
+	Measured    bool   // the launch measurement of a running SEV domain is available
+	HasFirmware bool
+	APIMajor    uint // SEV firmware API version of a running SEV domain
+	APIMinor    uint
+	BuildID     uint // SEV firmware build
+}
+
+// HostSEVMetrics represents the AMD SEV capabilities of the host
+type HostSEVMetrics struct {
+	CBitPos         uint // position of the encryption bit in page table entries
+	ReducedPhysBits uint // physical address bits lost to memory encryption
+	HasMaxGuests    bool
+	MaxGuests       uint // maximum number of SEV guests
+	HasMaxESGuests  bool
+	MaxESGuests     uint // maximum number of SEV-ES guests
+}
+
+// NodeDeviceMetrics represents a PCI or USB device of the host
+type NodeDeviceMetrics struct {
+	Name      string // node device name, e.g. pci_0000_3b_00_0
+	Bus       string // "pci" or "usb"
+	Class     string // PCI device type, e.g. "GPU", empty for USB devices
+	VendorID  string
+	Vendor    string
+	ProductID string
+	Product   string
+	Driver    string // bound host driver, empty if none
+	VFIO      bool   // bound to a vfio driver for passthrough
+	Domain    string // active domain the device is assigned to, empty if none
+	UUID      string
+}
+
+// FilesystemMetrics represents the usage of a guest filesystem reported by
+// the guest agent
+type FilesystemMetrics struct {
+	Name       string
+	UUID       string
+	MountPoint string
+	Device     string // guest device name, e.g. "/dev/vda1"
+	FSType     string
+	TotalBytes uint64
+	UsedBytes  uint64
+}
+
+// ProcessMetrics represents host-side statistics of a domain's QEMU process
+type ProcessMetrics struct {
+	Name          string
+	UUID          string
+	PID           int
+	ResidentBytes uint64  // resident set size
+	CPUSeconds    float64 // user + system CPU time
+	Threads       int
+	OpenFDs       int
+}
+
+// HostResourceMetrics represents host CPU contention and the resources
+// allocated to running domains
+type HostResourceMetrics struct {
+	HasProcStat          bool    // iowait and steal are only read for local connections
+	IowaitSeconds        float64 // accumulated time waiting for I/O
+	StealSeconds         float64 // accumulated time stolen by an underlying hypervisor
+	PhysicalCores        uint    // sockets * cores over all NUMA nodes
+	OnlineCPUs           uint    // logical CPUs
+	AllocatedVCPUs       uint    // vCPUs of running domains
+	PhysicalMemoryBytes  uint64  // host physical memory
+	AllocatedMemoryBytes uint64  // configured maximum memory of running domains
+	BalloonMemoryBytes   uint64  // actual (ballooned) memory of running domains
+}
+
+// HostTopologyMetrics represents the host topology parsed from capabilities
+type HostTopologyMetrics struct {
+	NUMANodes    []NUMANodeMetrics
+	IOMMUEnabled bool
+	IOMMUGroups  int // distinct IOMMU groups of host PCI devices
+	VFIODevices  int // PCI devices bound to vfio-pci
+}
+
+// NodeMemoryMetrics represents the memory of a host NUMA node
+type NodeMemoryMetrics struct {
+	Node      int
+	FreeBytes uint64
+}
+
+// KSMMetrics represents the kernel samepage merging counters of the host
+type KSMMetrics struct {
+	PagesShared   uint64
+	PagesSharing  uint64
+	PagesUnshared uint64
+	PagesVolatile uint64
+	FullScans     uint64
+}
+
+// NUMANodeMetrics represents a host NUMA node
+type NUMANodeMetrics struct {
+	ID          int
+	CPUs        int
+	MemoryBytes uint64
+	Distances   []NUMADistance
+}
+
+// NUMADistance represents the distance from a NUMA node to another node
+type NUMADistance struct {
+	Sibling int
+	Value   int
+}
+
 // HostMetrics represents host system metrics (deprecated, use ConnectionMetrics)
 type HostMetrics struct {
 	Name              string
@@ -237,43 +551,112 @@ type ExporterMetrics struct {
 // MetricsCollector defines interface for collecting raw metrics from libvirt
 type MetricsCollector interface {
 	CollectDomainInfo(
+		ctx context.Context,
 		conn *libvirt.Connect,
 		domain *libvirt.Domain,
 	) (*DomainInfoMetrics, error)
 	CollectCPUStats(
+		ctx context.Context,
 		conn *libvirt.Connect,
 		domain *libvirt.Domain,
 	) (*CPUStatsMetrics, error)
 	CollectMemoryStats(
+		ctx context.Context,
 		conn *libvirt.Connect,
 		domain *libvirt.Domain,
 	) (*MemoryStatsMetrics, error)
 	CollectDiskStats(
+		ctx context.Context,
 		conn *libvirt.Connect,
 		domain *libvirt.Domain,
 	) ([]DiskMetrics, error)
 	CollectNetworkStats(
+		ctx context.Context,
 		conn *libvirt.Connect,
 		domain *libvirt.Domain,
 	) ([]NetworkMetrics, error)
 	CollectDeviceStats(
+		ctx context.Context,
 		conn *libvirt.Connect,
 		domain *libvirt.Domain,
 	) (*DeviceMetrics, error)
 	CollectJobStats(
+		ctx context.Context,
 		conn *libvirt.Connect,
 		domain *libvirt.Domain,
 	) (*DomainJobMetrics, error)
 	CollectSnapshotStats(
+		ctx context.Context,
 		conn *libvirt.Connect,
 		domain *libvirt.Domain,
 	) (*SnapshotMetrics, error)
+	CollectProcessStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+		domain *libvirt.Domain,
+	) (*ProcessMetrics, error)
 	CollectConnectionStats(
+		ctx context.Context,
 		conn *libvirt.Connect,
 	) (*ConnectionMetrics, error)
+	CollectHostCPUUsage(
+		ctx context.Context,
+		conn *libvirt.Connect,
+		metrics *ConnectionMetrics,
+	)
 	CollectHostStats(
+		ctx context.Context,
 		conn *libvirt.Connect,
 	) (*HostMetrics, error)
+	CollectHostTopology(
+		ctx context.Context,
+		conn *libvirt.Connect,
+	) (*HostTopologyMetrics, error)
+	CollectHostResourceStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+	) (*HostResourceMetrics, error)
+	CollectNodeMemoryStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+	) ([]NodeMemoryMetrics, error)
+	CollectKSMStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+	) (*KSMMetrics, error)
+	CollectNodeDeviceStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+	) ([]NodeDeviceMetrics, error)
+	CollectDirtyRateStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+		domain *libvirt.Domain,
+	) (*DirtyRateMetrics, error)
+	CollectPerfStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+		domain *libvirt.Domain,
+	) (*PerfMetrics, error)
+	CollectResctrlStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+		domain *libvirt.Domain,
+	) (*ResctrlMetrics, error)
+	CollectLaunchSecurityStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+		domain *libvirt.Domain,
+	) (*LaunchSecurityMetrics, error)
+	CollectHostSEVStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+	) (*HostSEVMetrics, error)
+	CollectFilesystemStats(
+		ctx context.Context,
+		conn *libvirt.Connect,
+		domain *libvirt.Domain,
+	) ([]FilesystemMetrics, error)
 }
 
 // DomainMetrics aggregates all metrics for one domain
@@ -0,0 +1,157 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"libvirt.org/go/libvirt"
+)
+
+// vhostUserTimeout bounds a single statistics query to the vhost-user backend
+const vhostUserTimeout = 5 * time.Second
+
+// VHostUserBackend fetches the counters of vhost-user interfaces, for which
+// libvirt's InterfaceStats returns nothing because the traffic bypasses the
+// kernel. port is the backend port name of the interface. Counters may be
+// read once per scrape; Reset is called at the start of every scrape.
+type VHostUserBackend interface {
+	InterfaceStats(port string) (*libvirt.DomainInterfaceStats, error)
+	Reset()
+}
+
+// NewVHostUserBackend creates the vhost-user backend selected by name:
+// "none" disables vhost-user statistics and "ovs" queries Open vSwitch
+// through the ovs-vsctl command
+func NewVHostUserBackend(name, ovsVsctl string) (VHostUserBackend, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "ovs":
+		return &ovsBackend{command: ovsVsctl}, nil
+	default:
+		return nil, fmt.Errorf("unknown vhost-user backend %q", name)
+	}
+}
+
+// ovsBackend reads vhost-user port statistics from the Open vSwitch database.
+// The statistics of all interfaces are listed with one ovs-vsctl call per
+// scrape.
+type ovsBackend struct {
+	command string
+
+	mutex sync.Mutex
+	ports map[string]map[string]int64 // counters keyed by port, nil until listed
+	err   error                       // error of listing the ports
+}
+
+// Reset implements VHostUserBackend
+func (b *ovsBackend) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.ports = nil
+	b.err = nil
+}
+
+// InterfaceStats implements VHostUserBackend. OVS counts from the switch's
+// point of view, so receive and transmit are swapped to match the guest's
+// point of view used by libvirt.
+func (b *ovsBackend) InterfaceStats(port string) (*libvirt.DomainInterfaceStats, error) {
+	ports, err := b.listPorts()
+	if err != nil {
+		return nil, err
+	}
+	counters, ok := ports[port]
+	if !ok {
+		return nil, fmt.Errorf("port %s not found in Open vSwitch", port)
+	}
+
+	stats := &libvirt.DomainInterfaceStats{}
+	stats.RxBytes, stats.RxBytesSet = counters["tx_bytes"]
+	stats.RxPackets, stats.RxPacketsSet = counters["tx_packets"]
+	stats.RxErrs, stats.RxErrsSet = counters["tx_errors"]
+	stats.RxDrop, stats.RxDropSet = counters["tx_dropped"]
+	stats.TxBytes, stats.TxBytesSet = counters["rx_bytes"]
+	stats.TxPackets, stats.TxPacketsSet = counters["rx_packets"]
+	stats.TxErrs, stats.TxErrsSet = counters["rx_errors"]
+	stats.TxDrop, stats.TxDropSet = counters["rx_dropped"]
+	return stats, nil
+}
+
+// listPorts returns the statistics of all interfaces, listed on the first
+// call of the scrape
+func (b *ovsBackend) listPorts() (map[string]map[string]int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.ports != nil || b.err != nil {
+		return b.ports, b.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vhostUserTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, b.command,
+		"--timeout=5", "--columns=name,statistics", "list", "Interface").Output()
+	if err != nil {
+		b.err = fmt.Errorf("ovs-vsctl failed: %w", err)
+		return nil, b.err
+	}
+	b.ports = parseOVSInterfaces(string(output))
+	return b.ports, nil
+}
+
+// parseOVSInterfaces parses the output of ovs-vsctl list, records of
+// "column : value" lines separated by blank lines, into the statistics of
+// each interface keyed by name
+func parseOVSInterfaces(output string) map[string]map[string]int64 {
+	result := make(map[string]map[string]int64)
+
+	var name string
+	var counters map[string]int64
+	for _, line := range strings.Split(output+"\n", "\n") {
+		column, value, ok := strings.Cut(line, ":")
+		if !ok {
+			// End of a record
+			if name != "" && counters != nil {
+				result[name] = counters
+			}
+			name, counters = "", nil
+			continue
+		}
+		switch strings.TrimSpace(column) {
+		case "name":
+			name = strings.Trim(strings.TrimSpace(value), `"`)
+		case "statistics":
+			counters = parseOVSMap(value)
+		}
+	}
+	return result
+}
+
+// parseOVSMap parses an OVS database map of integers such as
+// {rx_bytes=1234, tx_bytes=5678}; malformed entries are skipped
+func parseOVSMap(value string) map[string]int64 {
+	result := make(map[string]int64)
+
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, "{")
+	value = strings.TrimSuffix(value, "}")
+	for _, entry := range strings.Split(value, ",") {
+		key, raw, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		number, err := strconv.ParseInt(strings.Trim(raw, `"`), 10, 64)
+		if err != nil {
+			continue
+		}
+		result[strings.Trim(key, `"`)] = number
+	}
+	return result
+}
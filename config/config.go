@@ -3,7 +3,7 @@ package config
 import (
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 )
 
 // Config holds the application configuration
@@ -56,7 +56,7 @@ func ParseConfig() (*Config, error) {
 			return nil, fmt.Errorf("failed to load config file: %w", err)
 		}
 		// 如果是默认加载且没有找到配置文件，继续使用命令行参数
-		log.Println("No configuration file found, using command line arguments and default values")
+		slog.Info("No configuration file found, using command line arguments and default values")
 	} else {
 		config.FileConfig = fileConfig
 	}
@@ -101,6 +101,16 @@ func (c *Config) mergeConfig() {
 	}
 }
 
+// Settings returns the file configuration, or the defaults if no file was loaded
+func (c *Config) Settings() *FileConfig {
+	if c.FileConfig != nil {
+		return c.FileConfig
+	}
+	defaults := &FileConfig{}
+	defaults.applyDefaults()
+	return defaults
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	if c.LibvirtURI == "" {
@@ -117,21 +127,12 @@ func (c *Config) Validate() error {
 
 // Log logs the configuration values
 func (c *Config) Log() {
-	log.Println(
-		"--------------------------------------------------------------------",
-	)
-	log.Println("UOS Libvirt Exporter Configuration:")
-
 	if c.FileConfig != nil {
-		log.Printf("  Config File      : loaded")
 		c.FileConfig.Log()
-	} else {
-		log.Printf("  Libvirt URI      : %s", c.LibvirtURI)
-		log.Printf("  Listen Address   : %s", c.ListenAddr)
-		log.Printf("  Metrics Path     : %s", c.MetricsPath)
+		return
 	}
-
-	log.Println(
-		"--------------------------------------------------------------------",
-	)
+	slog.Info("Configuration",
+		"libvirt_uri", c.LibvirtURI,
+		"listen_address", c.ListenAddr,
+		"metrics_path", c.MetricsPath)
 }
@@ -3,9 +3,11 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"go.yaml.in/yaml/v2"
 )
@@ -17,21 +19,81 @@ type FileConfig struct {
 	Logging    LoggingConfig    `yaml:"logging"`
 	Collection CollectionConfig `yaml:"collection"`
 	Metrics    MetricsConfig    `yaml:"metrics"`
+	HA         HAConfig         `yaml:"ha"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Probe      ProbeConfig      `yaml:"probe"`
 }
 
 // LibvirtConfig holds libvirt connection settings
 type LibvirtConfig struct {
-	URI               string `yaml:"uri"`
-	Timeout           int    `yaml:"timeout"`
-	ReconnectInterval int    `yaml:"reconnect_interval"`
+	URI               string                 `yaml:"uri"`
+	FallbackURIs      []string               `yaml:"fallback_uris"`
+	URIs              []string               `yaml:"uris"`
+	Discovery         LibvirtDiscoveryConfig `yaml:"discovery"`
+	Timeout           int                    `yaml:"timeout"`
+	ReconnectInterval int                    `yaml:"reconnect_interval"`
+	ProbeInterval     *int                   `yaml:"probe_interval"`
+	AdminURI          string                 `yaml:"admin_uri"`
+	Auth              LibvirtAuthConfig      `yaml:"auth"`
+	TLS               LibvirtTLSConfig       `yaml:"tls"`
+	SSH               LibvirtSSHConfig       `yaml:"ssh"`
+}
+
+// LibvirtDiscoveryConfig holds the DNS discovery settings of further hosts
+type LibvirtDiscoveryConfig struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	URITemplate string `yaml:"uri_template"`
+	Interval    int    `yaml:"interval"`
+}
+
+// LibvirtAuthConfig holds the credentials of remote connections, e.g. SASL
+type LibvirtAuthConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// LibvirtTLSConfig holds the client certificates of qemu+tls connections
+type LibvirtTLSConfig struct {
+	PKIPath string `yaml:"pki_path"`
+}
+
+// LibvirtSSHConfig holds the keys of qemu+ssh connections
+type LibvirtSSHConfig struct {
+	KeyFile    string `yaml:"key_file"`
+	KnownHosts string `yaml:"known_hosts"`
 }
 
 // WebConfig holds HTTP server settings
 type WebConfig struct {
-	ListenAddress string `yaml:"listen_address"`
-	TelemetryPath string `yaml:"telemetry_path"`
-	EnablePprof   bool   `yaml:"enable_pprof"`
-	PprofAddress  string `yaml:"pprof_address"`
+	ListenAddress        string     `yaml:"listen_address"`
+	TelemetryPath        string     `yaml:"telemetry_path"`
+	EnablePprof          bool       `yaml:"enable_pprof"`
+	PprofAddress         string     `yaml:"pprof_address"`
+	EnableRuntimeMetrics bool       `yaml:"enable_runtime_metrics"`
+	EnableOpenMetrics    bool       `yaml:"enable_openmetrics"`
+	DisableCompression   bool       `yaml:"disable_compression"`
+	HandlerTimeout       int        `yaml:"handler_timeout"`
+	MaxRequestsInFlight  int        `yaml:"max_requests_in_flight"`
+	ErrorHandling        string     `yaml:"error_handling"`
+	ScrapeAuditSize      *int       `yaml:"scrape_audit_size"`
+	ShutdownTimeout      int        `yaml:"shutdown_timeout"`
+	ScrapeTimeoutOffset  *float64   `yaml:"scrape_timeout_offset"`
+	TLS                  TLSConfig  `yaml:"tls"`
+	Auth                 AuthConfig `yaml:"auth"`
+}
+
+// AuthConfig holds the credentials required to access the HTTP endpoints
+type AuthConfig struct {
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+	BearerTokens   []string          `yaml:"bearer_tokens"`
+}
+
+// TLSConfig holds HTTPS settings
+type TLSConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ReloadInterval int    `yaml:"reload_interval"`
 }
 
 // LoggingConfig holds logging settings
@@ -42,15 +104,218 @@ type LoggingConfig struct {
 
 // CollectionConfig holds metrics collection settings
 type CollectionConfig struct {
-	Interval      int `yaml:"interval"`
-	Timeout       int `yaml:"timeout"`
-	MaxConcurrent int `yaml:"max_concurrent"`
+	Mode              string               `yaml:"mode"`
+	Interval          int                  `yaml:"interval"`
+	Timeout           int                  `yaml:"timeout"`
+	CollectorTimeouts map[string]int       `yaml:"collector_timeouts"`
+	Collectors        map[string]bool      `yaml:"collectors"`
+	MaxConcurrent     int                  `yaml:"max_concurrent"`
+	Autoscale         AutoscaleConfig      `yaml:"autoscale"`
+	Jobs              JobsConfig           `yaml:"jobs"`
+	Migrations        MigrationsConfig     `yaml:"migrations"`
+	Snapshots         SnapshotsConfig      `yaml:"snapshots"`
+	Process           ProcessConfig        `yaml:"process"`
+	VHostUser         VHostUserConfig      `yaml:"vhostuser"`
+	SRIOV             SRIOVConfig          `yaml:"sriov"`
+	CounterWraps      string               `yaml:"counter_wraps"`
+	CounterRetention  int                  `yaml:"counter_retention"`
+	EventRetention    int                  `yaml:"event_retention"`
+	MaxDomains        int                  `yaml:"max_domains"`
+	MemoryLimit       int                  `yaml:"memory_limit"`
+	DeviceCacheTTL    *int                 `yaml:"device_cache_ttl"`
+	HostInterfaces    string               `yaml:"host_interfaces"`
+	DirtyRate         DirtyRateConfig      `yaml:"dirty_rate"`
+	Perf              PerfConfig           `yaml:"perf"`
+	Resctrl           ResctrlConfig        `yaml:"resctrl"`
+	NodeDevices       NodeDeviceConfig     `yaml:"node_devices"`
+	LaunchSecurity    LaunchSecurityConfig `yaml:"launch_security"`
+	Filesystems       FilesystemConfig     `yaml:"filesystems"`
+	GuestHostname     GuestHostnameConfig  `yaml:"guest_hostname"`
+	Disks             DisksConfig          `yaml:"disks"`
+	Events            EventsConfig         `yaml:"events"`
+	Background        BackgroundConfig     `yaml:"background"`
+}
+
+// AutoscaleConfig holds settings for scaling collection workers with the domain count
+type AutoscaleConfig struct {
+	Enabled          bool `yaml:"enabled"`
+	DomainsPerWorker int  `yaml:"domains_per_worker"`
+	MaxWorkers       int  `yaml:"max_workers"`
+}
+
+// BackgroundConfig holds settings for collecting metrics in the background
+type BackgroundConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// JobsConfig holds domain job collector settings
+type JobsConfig struct {
+	Enabled *bool `yaml:"enabled"`
+}
+
+// MigrationsConfig holds migration history collector settings
+type MigrationsConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// EventsConfig holds domain lifecycle event collector settings
+type EventsConfig struct {
+	Enabled bool         `yaml:"enabled"`
+	Notify  NotifyConfig `yaml:"notify"`
+}
+
+// NotifyConfig holds settings for forwarding lifecycle events
+type NotifyConfig struct {
+	URL        string   `yaml:"url"`
+	Events     []string `yaml:"events"`
+	Timeout    int      `yaml:"timeout"`
+	MaxRetries *int     `yaml:"max_retries"`
+}
+
+// lifecycleEvents are the lifecycle event names that can be forwarded
+var lifecycleEvents = map[string]bool{
+	"defined":     true,
+	"undefined":   true,
+	"started":     true,
+	"suspended":   true,
+	"resumed":     true,
+	"stopped":     true,
+	"shutdown":    true,
+	"pmsuspended": true,
+	"crashed":     true,
+}
+
+// SnapshotsConfig holds snapshot collector settings
+type SnapshotsConfig struct {
+	Enabled  *bool `yaml:"enabled"`
+	Interval int   `yaml:"interval"`
+}
+
+// ProcessConfig holds QEMU process collector settings
+type ProcessConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	PIDDir  string `yaml:"pid_dir"`
+}
+
+// DirtyRateConfig holds memory dirty rate collector settings
+type DirtyRateConfig struct {
+	Enabled  bool `yaml:"enabled"`
+	Interval int  `yaml:"interval"`
+	Period   int  `yaml:"period"`
+}
+
+// PerfConfig holds perf event collector settings
+type PerfConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Events  []string `yaml:"events"`
+}
+
+// ResctrlConfig holds Intel RDT monitoring collector settings
+type ResctrlConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// NodeDeviceConfig holds host device inventory collector settings
+type NodeDeviceConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// LaunchSecurityConfig holds confidential computing collector settings
+type LaunchSecurityConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// FilesystemConfig holds guest filesystem collector settings
+type FilesystemConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// GuestHostnameConfig holds settings for reading guest hostnames
+type GuestHostnameConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// DisksConfig holds settings for the per-disk metrics that cost an extra
+// libvirt call per disk
+type DisksConfig struct {
+	BlockJobs bool `yaml:"block_jobs"`
+	Throttle  bool `yaml:"throttle"`
+}
+
+// SRIOVConfig holds settings for reading SR-IOV interface counters
+type SRIOVConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	IPCommand string `yaml:"ip_command"`
+}
+
+// VHostUserConfig holds settings for reading vhost-user interface counters
+type VHostUserConfig struct {
+	Backend  string `yaml:"backend"`
+	OVSVsctl string `yaml:"ovs_vsctl"`
 }
 
 // MetricsConfig holds metric filtering settings
 type MetricsConfig struct {
 	Enabled     []string          `yaml:"enabled"`
 	ExtraLabels map[string]string `yaml:"extra_labels"`
+	LabelPolicy string            `yaml:"label_policy"`
+	Timestamps  bool              `yaml:"timestamps"`
+	// DomainMetadata attaches labels read from domain metadata
+	DomainMetadata DomainMetadataConfig `yaml:"domain_metadata"`
+}
+
+// DomainMetadataConfig holds settings for labels read from the <metadata>
+// element of domain definitions
+type DomainMetadataConfig struct {
+	Enabled   bool              `yaml:"enabled"`
+	Namespace string            `yaml:"namespace"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+// HAConfig holds leader election settings for redundant exporters
+type HAConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	LockFile      string `yaml:"lock_file"`
+	RetryInterval int    `yaml:"retry_interval"`
+}
+
+// TracingConfig holds settings for exporting scrape traces with OTLP
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Endpoint    string            `yaml:"endpoint"`
+	ServiceName string            `yaml:"service_name"`
+	Headers     map[string]string `yaml:"headers"`
+	Timeout     int               `yaml:"timeout"`
+	SampleRatio *float64          `yaml:"sample_ratio"`
+}
+
+// ProbeConfig holds settings for scraping remote libvirt hosts through /probe
+type ProbeConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	AllowedTargets []string `yaml:"allowed_targets"`
+	MaxTargets     int      `yaml:"max_targets"`
+	MaxConcurrent  int      `yaml:"max_concurrent"`
+	IdleTimeout    int      `yaml:"idle_timeout"`
+}
+
+// labelNameRE matches valid Prometheus label names
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabelNames are the label names of the exported metrics, which
+// extra labels cannot reuse
+var reservedLabelNames = []string{
+	"action", "address", "api_version", "auto", "backend", "bank",
+	"bridge", "build_id", "bus", "cache", "class", "collector", "commit",
+	"cores", "cpu", "detail", "device", "direction", "discard", "domain",
+	"driver", "event", "exporter_version", "format", "fstype", "genid",
+	"host", "hostname", "hypervisor_version", "index", "interface", "io",
+	"ip", "is_current", "kind", "label", "libvirt_version", "mac",
+	"mdev_uuid", "mode", "model", "monitor", "mountpoint", "name",
+	"network", "node", "nodeset", "operation", "primary", "priority",
+	"product", "product_id", "reason", "relabel", "server", "sibling",
+	"snapshot", "sockets", "source", "state", "subsystem", "threads",
+	"transport", "type", "uri", "uuid", "vcpu", "vcpus",
+	"vendor", "vendor_id", "version",
 }
 
 // getDefaultConfigPaths 返回默认配置文件路径列表，按优先级排序
@@ -119,7 +384,7 @@ func LoadConfigFromFile(configFile string) (*FileConfig, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	log.Printf("Configuration loaded from file: %s", usedPath)
+	slog.Info("Configuration loaded from file", "path", usedPath)
 	return &config, nil
 }
 
@@ -135,6 +400,20 @@ func (c *FileConfig) applyDefaults() {
 	if c.Libvirt.ReconnectInterval == 0 {
 		c.Libvirt.ReconnectInterval = 10
 	}
+	if c.Libvirt.ProbeInterval == nil {
+		// The probe opens a second connection to the daemon, so it is opt-in
+		interval := 0
+		c.Libvirt.ProbeInterval = &interval
+	}
+	if c.Libvirt.Discovery.Type == "" {
+		c.Libvirt.Discovery.Type = "srv"
+	}
+	if c.Libvirt.Discovery.URITemplate == "" {
+		c.Libvirt.Discovery.URITemplate = "qemu+tcp://{host}/system"
+	}
+	if c.Libvirt.Discovery.Interval == 0 {
+		c.Libvirt.Discovery.Interval = 60
+	}
 
 	// Web defaults
 	if c.Web.ListenAddress == "" {
@@ -143,8 +422,25 @@ func (c *FileConfig) applyDefaults() {
 	if c.Web.TelemetryPath == "" {
 		c.Web.TelemetryPath = "/metrics"
 	}
+	if c.Web.ErrorHandling == "" {
+		c.Web.ErrorHandling = "http_error"
+	}
 	if c.Web.PprofAddress == "" {
-		c.Web.PprofAddress = ":6060"
+		c.Web.PprofAddress = "localhost:6060"
+	}
+	if c.Web.ScrapeAuditSize == nil {
+		size := 100
+		c.Web.ScrapeAuditSize = &size
+	}
+	if c.Web.ShutdownTimeout == 0 {
+		c.Web.ShutdownTimeout = 30
+	}
+	if c.Web.ScrapeTimeoutOffset == nil {
+		offset := 0.5
+		c.Web.ScrapeTimeoutOffset = &offset
+	}
+	if c.Web.TLS.ReloadInterval == 0 {
+		c.Web.TLS.ReloadInterval = 60
 	}
 
 	// Logging defaults
@@ -156,6 +452,9 @@ func (c *FileConfig) applyDefaults() {
 	}
 
 	// Collection defaults
+	if c.Collection.Mode == "" {
+		c.Collection.Mode = "legacy"
+	}
 	if c.Collection.Interval == 0 {
 		c.Collection.Interval = 15
 	}
@@ -165,6 +464,89 @@ func (c *FileConfig) applyDefaults() {
 	if c.Collection.MaxConcurrent == 0 {
 		c.Collection.MaxConcurrent = 10
 	}
+	if c.Collection.Autoscale.DomainsPerWorker == 0 {
+		c.Collection.Autoscale.DomainsPerWorker = 10
+	}
+	if c.Collection.Autoscale.MaxWorkers == 0 {
+		c.Collection.Autoscale.MaxWorkers = 32
+	}
+	if c.Collection.CounterWraps == "" {
+		c.Collection.CounterWraps = "detect"
+	}
+	if c.Collection.EventRetention == 0 {
+		c.Collection.EventRetention = 3600
+	}
+	if c.Collection.HostInterfaces == "" {
+		c.Collection.HostInterfaces = "libvirt"
+	}
+	if c.Collection.DeviceCacheTTL == nil {
+		ttl := 300
+		c.Collection.DeviceCacheTTL = &ttl
+	}
+	if c.Collection.Jobs.Enabled == nil {
+		enabled := true
+		c.Collection.Jobs.Enabled = &enabled
+	}
+	if c.Collection.Process.PIDDir == "" {
+		c.Collection.Process.PIDDir = "/run/libvirt/qemu"
+	}
+	if c.Collection.DirtyRate.Period == 0 {
+		c.Collection.DirtyRate.Period = 1
+	}
+	if c.Collection.Events.Notify.Events == nil {
+		c.Collection.Events.Notify.Events = []string{"started", "stopped", "crashed"}
+	}
+	if c.Collection.Events.Notify.Timeout == 0 {
+		c.Collection.Events.Notify.Timeout = 5
+	}
+	if c.Collection.Events.Notify.MaxRetries == nil {
+		retries := 5
+		c.Collection.Events.Notify.MaxRetries = &retries
+	}
+	if c.Collection.Snapshots.Enabled == nil {
+		enabled := true
+		c.Collection.Snapshots.Enabled = &enabled
+	}
+	if c.Collection.VHostUser.Backend == "" {
+		c.Collection.VHostUser.Backend = "none"
+	}
+	if c.Collection.VHostUser.OVSVsctl == "" {
+		c.Collection.VHostUser.OVSVsctl = "ovs-vsctl"
+	}
+	if c.Collection.SRIOV.IPCommand == "" {
+		c.Collection.SRIOV.IPCommand = "ip"
+	}
+
+	// HA defaults
+	if c.HA.RetryInterval == 0 {
+		c.HA.RetryInterval = 5
+	}
+
+	// Tracing defaults
+	if c.Tracing.Endpoint == "" {
+		c.Tracing.Endpoint = "http://localhost:4318"
+	}
+	if c.Tracing.ServiceName == "" {
+		c.Tracing.ServiceName = "uos-libvirtd-exporter"
+	}
+	if c.Tracing.Timeout == 0 {
+		c.Tracing.Timeout = 10
+	}
+	if c.Tracing.SampleRatio == nil {
+		ratio := 1.0
+		c.Tracing.SampleRatio = &ratio
+	}
+
+	// Probe defaults
+	if c.Probe.MaxTargets == 0 {
+		c.Probe.MaxTargets = 100
+	}
+	if c.Probe.MaxConcurrent == 0 {
+		c.Probe.MaxConcurrent = 1
+	}
+	if c.Probe.IdleTimeout == 0 {
+		c.Probe.IdleTimeout = 300
+	}
 
 	// Metrics defaults
 	if len(c.Metrics.Enabled) == 0 {
@@ -175,11 +557,29 @@ func (c *FileConfig) applyDefaults() {
 			"vm_disk",
 			"vm_network",
 			"vm_uptime",
+			"vm_device",
+			"host",
 		}
 	}
 	if c.Metrics.ExtraLabels == nil {
 		c.Metrics.ExtraLabels = make(map[string]string)
 	}
+	if c.Metrics.LabelPolicy == "" {
+		c.Metrics.LabelPolicy = "none"
+	}
+	// Domain metadata defaults to the labels of OpenStack Nova instances
+	if c.Metrics.DomainMetadata.Namespace == "" {
+		c.Metrics.DomainMetadata.Namespace = "http://openstack.org/xmlns/libvirt/nova/1.1"
+	}
+	if c.Metrics.DomainMetadata.Labels == nil {
+		c.Metrics.DomainMetadata.Labels = map[string]string{
+			"project":       "owner/project",
+			"project_id":    "owner/project@uuid",
+			"user":          "owner/user",
+			"flavor":        "flavor@name",
+			"instance_name": "name",
+		}
+	}
 }
 
 // Validate validates the file configuration
@@ -187,44 +587,361 @@ func (c *FileConfig) Validate() error {
 	if c.Libvirt.URI == "" {
 		return fmt.Errorf("libvirt URI cannot be empty")
 	}
+	if *c.Libvirt.ProbeInterval < 0 {
+		return fmt.Errorf("libvirt probe interval cannot be negative")
+	}
+	if c.Libvirt.ReconnectInterval < 0 {
+		return fmt.Errorf("libvirt reconnect interval cannot be negative")
+	}
+	for _, uri := range c.Libvirt.URIs {
+		if uri == "" {
+			return fmt.Errorf("libvirt uris cannot contain an empty URI")
+		}
+	}
+	if len(c.Libvirt.URIs) > 0 && c.HA.Enabled {
+		return fmt.Errorf("ha cannot be enabled with libvirt uris")
+	}
+	switch c.Libvirt.Discovery.Type {
+	case "srv", "a":
+	default:
+		return fmt.Errorf("unknown libvirt discovery type: %s", c.Libvirt.Discovery.Type)
+	}
+	if !strings.Contains(c.Libvirt.Discovery.URITemplate, "{host}") {
+		return fmt.Errorf("libvirt discovery uri template must contain {host}")
+	}
+	if c.Libvirt.Discovery.Interval <= 0 {
+		return fmt.Errorf("libvirt discovery interval must be positive")
+	}
+	if c.Libvirt.Discovery.Name != "" && c.HA.Enabled {
+		return fmt.Errorf("ha cannot be enabled with libvirt discovery")
+	}
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		return fmt.Errorf("unknown logging level %q", c.Logging.Level)
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown logging format %q", c.Logging.Format)
+	}
 	if c.Web.ListenAddress == "" {
 		return fmt.Errorf("web listen address cannot be empty")
 	}
 	if c.Web.TelemetryPath == "" {
 		return fmt.Errorf("web telemetry path cannot be empty")
 	}
+	if c.Web.MaxRequestsInFlight < 0 {
+		return fmt.Errorf("web max requests in flight cannot be negative")
+	}
+	switch c.Web.ErrorHandling {
+	case "http_error", "continue":
+	default:
+		return fmt.Errorf("unknown web error handling: %s", c.Web.ErrorHandling)
+	}
+	if c.Web.HandlerTimeout < 0 {
+		return fmt.Errorf("web handler timeout cannot be negative")
+	}
+	if *c.Web.ScrapeAuditSize < 0 {
+		return fmt.Errorf("web scrape audit size cannot be negative")
+	}
+	if c.Web.ShutdownTimeout < 0 {
+		return fmt.Errorf("web shutdown timeout cannot be negative")
+	}
+	if *c.Web.ScrapeTimeoutOffset < 0 {
+		return fmt.Errorf("web scrape timeout offset cannot be negative")
+	}
+	if (c.Web.TLS.CertFile == "") != (c.Web.TLS.KeyFile == "") {
+		return fmt.Errorf("web TLS cert file and key file must be set together")
+	}
+	if c.Web.TLS.ReloadInterval < 0 {
+		return fmt.Errorf("web TLS reload interval cannot be negative")
+	}
+	for user, password := range c.Web.Auth.BasicAuthUsers {
+		if user == "" || strings.Contains(user, ":") {
+			return fmt.Errorf("invalid web basic auth user name: %q", user)
+		}
+		if password == "" {
+			return fmt.Errorf("web basic auth password of user %s cannot be empty", user)
+		}
+	}
+	for _, token := range c.Web.Auth.BearerTokens {
+		if token == "" {
+			return fmt.Errorf("web bearer tokens cannot be empty")
+		}
+	}
 	if c.Collection.Interval <= 0 {
 		return fmt.Errorf("collection interval must be positive")
 	}
 	if c.Collection.Timeout <= 0 {
 		return fmt.Errorf("collection timeout must be positive")
 	}
+	// Collector names are checked by the collector, which knows the custom
+	// collectors compiled in
+	for name, timeout := range c.Collection.CollectorTimeouts {
+		if timeout <= 0 {
+			return fmt.Errorf("collection collector timeout of %s must be positive", name)
+		}
+	}
 	if c.Collection.MaxConcurrent <= 0 {
 		return fmt.Errorf("max concurrent must be positive")
 	}
+	if c.Collection.Autoscale.DomainsPerWorker <= 0 {
+		return fmt.Errorf("autoscale domains per worker must be positive")
+	}
+	if c.Collection.Autoscale.MaxWorkers <= 0 {
+		return fmt.Errorf("autoscale max workers must be positive")
+	}
+	if c.Collection.Snapshots.Interval < 0 {
+		return fmt.Errorf("snapshot interval cannot be negative")
+	}
+	if c.Collection.DirtyRate.Interval < 0 {
+		return fmt.Errorf("dirty rate interval cannot be negative")
+	}
+	if c.Collection.DirtyRate.Period < 0 {
+		return fmt.Errorf("dirty rate period cannot be negative")
+	}
+	for _, event := range c.Collection.Perf.Events {
+		switch event {
+		case "cpu_cycles", "instructions", "cache_misses", "branch_misses":
+		default:
+			return fmt.Errorf("unknown perf event: %s", event)
+		}
+	}
+	if notify := c.Collection.Events.Notify; notify.URL != "" {
+		if !c.Collection.Events.Enabled {
+			return fmt.Errorf("events notify url requires events to be enabled")
+		}
+		if !strings.HasPrefix(notify.URL, "http://") &&
+			!strings.HasPrefix(notify.URL, "https://") &&
+			!strings.HasPrefix(notify.URL, "unix://") {
+			return fmt.Errorf("unknown events notify url scheme: %s", notify.URL)
+		}
+		for _, event := range notify.Events {
+			if !lifecycleEvents[event] {
+				return fmt.Errorf("unknown events notify event: %s", event)
+			}
+		}
+	}
+	if c.Collection.Events.Notify.Timeout < 0 {
+		return fmt.Errorf("events notify timeout cannot be negative")
+	}
+	if *c.Collection.Events.Notify.MaxRetries < 0 {
+		return fmt.Errorf("events notify max retries cannot be negative")
+	}
+	if c.Collection.CounterRetention < 0 {
+		return fmt.Errorf("collection counter retention cannot be negative")
+	}
+	if c.Collection.EventRetention < 0 {
+		return fmt.Errorf("collection event retention cannot be negative")
+	}
+	if c.Collection.MaxDomains < 0 {
+		return fmt.Errorf("collection max domains cannot be negative")
+	}
+	if c.Collection.MemoryLimit < 0 {
+		return fmt.Errorf("collection memory limit cannot be negative")
+	}
+	if *c.Collection.DeviceCacheTTL < 0 {
+		return fmt.Errorf("collection device cache TTL cannot be negative")
+	}
+	switch c.Collection.Mode {
+	case "legacy", "batched":
+	default:
+		return fmt.Errorf("unknown collection mode: %s", c.Collection.Mode)
+	}
+	switch c.Collection.HostInterfaces {
+	case "libvirt", "proc", "none":
+	default:
+		return fmt.Errorf("unknown collection host interfaces source: %s", c.Collection.HostInterfaces)
+	}
+	switch c.Collection.CounterWraps {
+	case "detect", "correct":
+	default:
+		return fmt.Errorf("unknown collection counter wraps mode: %s", c.Collection.CounterWraps)
+	}
+	switch c.Collection.VHostUser.Backend {
+	case "none", "ovs":
+	default:
+		return fmt.Errorf("unknown collection vhost-user backend: %s", c.Collection.VHostUser.Backend)
+	}
+	if c.HA.Enabled && c.HA.LockFile == "" {
+		return fmt.Errorf("ha lock file cannot be empty when ha is enabled")
+	}
+	if c.HA.RetryInterval <= 0 {
+		return fmt.Errorf("ha retry interval must be positive")
+	}
+	if !strings.HasPrefix(c.Tracing.Endpoint, "http://") &&
+		!strings.HasPrefix(c.Tracing.Endpoint, "https://") {
+		return fmt.Errorf("unknown tracing endpoint scheme: %s", c.Tracing.Endpoint)
+	}
+	if c.Tracing.Timeout < 0 {
+		return fmt.Errorf("tracing timeout cannot be negative")
+	}
+	if *c.Tracing.SampleRatio < 0 || *c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+	if c.Probe.Enabled && len(c.Probe.AllowedTargets) == 0 {
+		return fmt.Errorf("probe allowed targets must be set when probe is enabled")
+	}
+	for _, pattern := range c.Probe.AllowedTargets {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid probe allowed target: %s", pattern)
+		}
+	}
+	if c.Probe.MaxTargets <= 0 {
+		return fmt.Errorf("probe max targets must be positive")
+	}
+	if c.Probe.MaxConcurrent <= 0 {
+		return fmt.Errorf("probe max concurrent must be positive")
+	}
+	if c.Probe.IdleTimeout <= 0 {
+		return fmt.Errorf("probe idle timeout must be positive")
+	}
+	switch c.Metrics.LabelPolicy {
+	case "none", "replace", "ascii":
+	default:
+		return fmt.Errorf("unknown metrics label policy: %s", c.Metrics.LabelPolicy)
+	}
+	for name := range c.Metrics.ExtraLabels {
+		if !labelNameRE.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid metrics extra label name: %s", name)
+		}
+		for _, reserved := range reservedLabelNames {
+			if name == reserved {
+				return fmt.Errorf("metrics extra label clashes with an exported label: %s", name)
+			}
+		}
+		if _, ok := c.Metrics.DomainMetadata.Labels[name]; ok && c.Metrics.DomainMetadata.Enabled {
+			return fmt.Errorf("metrics extra label clashes with a domain metadata label: %s", name)
+		}
+	}
+	if c.Metrics.DomainMetadata.Enabled {
+		if len(c.Metrics.DomainMetadata.Labels) == 0 {
+			return fmt.Errorf("domain metadata labels cannot be empty")
+		}
+		for name, path := range c.Metrics.DomainMetadata.Labels {
+			if !labelNameRE.MatchString(name) || strings.HasPrefix(name, "__") ||
+				name == "domain" || name == "uuid" {
+				return fmt.Errorf("invalid domain metadata label name: %s", name)
+			}
+			if path == "" {
+				return fmt.Errorf("domain metadata label %s has an empty path", name)
+			}
+		}
+	}
 	return nil
 }
 
 // Log logs the file configuration
 func (c *FileConfig) Log() {
-	log.Println("Configuration from file:")
-	log.Printf("  Libvirt:")
-	log.Printf("    URI:              %s", c.Libvirt.URI)
-	log.Printf("    Timeout:          %d", c.Libvirt.Timeout)
-	log.Printf("    Reconnect Interval: %d", c.Libvirt.ReconnectInterval)
-	log.Printf("  Web:")
-	log.Printf("    Listen Address:   %s", c.Web.ListenAddress)
-	log.Printf("    Telemetry Path:   %s", c.Web.TelemetryPath)
-	log.Printf("    Enable Pprof:     %t", c.Web.EnablePprof)
-	log.Printf("    Pprof Address:    %s", c.Web.PprofAddress)
-	log.Printf("  Logging:")
-	log.Printf("    Level:            %s", c.Logging.Level)
-	log.Printf("    Format:           %s", c.Logging.Format)
-	log.Printf("  Collection:")
-	log.Printf("    Interval:         %d", c.Collection.Interval)
-	log.Printf("    Timeout:          %d", c.Collection.Timeout)
-	log.Printf("    Max Concurrent:   %d", c.Collection.MaxConcurrent)
-	log.Printf("  Metrics:")
-	log.Printf("    Enabled:          %v", c.Metrics.Enabled)
-	log.Printf("    Extra Labels:     %v", c.Metrics.ExtraLabels)
+	slog.Info("Configuration from file",
+		slog.Group("libvirt",
+			"uri", c.Libvirt.URI,
+			"fallback_uris", c.Libvirt.FallbackURIs,
+			"uris", c.Libvirt.URIs,
+			"discovery_name", c.Libvirt.Discovery.Name,
+			"discovery_type", c.Libvirt.Discovery.Type,
+			"discovery_uri_template", c.Libvirt.Discovery.URITemplate,
+			"discovery_interval", c.Libvirt.Discovery.Interval,
+			"timeout", c.Libvirt.Timeout,
+			"reconnect_interval", c.Libvirt.ReconnectInterval,
+			"probe_interval", *c.Libvirt.ProbeInterval,
+			"admin_uri", c.Libvirt.AdminURI,
+			"auth_username", c.Libvirt.Auth.Username,
+			"auth_password", c.Libvirt.Auth.Password != "",
+			"tls_pki_path", c.Libvirt.TLS.PKIPath,
+			"ssh_key_file", c.Libvirt.SSH.KeyFile,
+			"ssh_known_hosts", c.Libvirt.SSH.KnownHosts),
+		slog.Group("web",
+			"listen_address", c.Web.ListenAddress,
+			"telemetry_path", c.Web.TelemetryPath,
+			"enable_pprof", c.Web.EnablePprof,
+			"pprof_address", c.Web.PprofAddress,
+			"enable_runtime_metrics", c.Web.EnableRuntimeMetrics,
+			"enable_openmetrics", c.Web.EnableOpenMetrics,
+			"disable_compression", c.Web.DisableCompression,
+			"handler_timeout", c.Web.HandlerTimeout,
+			"max_requests_in_flight", c.Web.MaxRequestsInFlight,
+			"error_handling", c.Web.ErrorHandling,
+			"scrape_audit_size", *c.Web.ScrapeAuditSize,
+			"shutdown_timeout", c.Web.ShutdownTimeout,
+			"scrape_timeout_offset", *c.Web.ScrapeTimeoutOffset,
+			"tls", c.Web.TLS.CertFile != "",
+			"tls_reload_interval", c.Web.TLS.ReloadInterval,
+			"basic_auth_users", len(c.Web.Auth.BasicAuthUsers),
+			"bearer_tokens", len(c.Web.Auth.BearerTokens)),
+		slog.Group("logging",
+			"level", c.Logging.Level,
+			"format", c.Logging.Format),
+		slog.Group("collection",
+			"mode", c.Collection.Mode,
+			"interval", c.Collection.Interval,
+			"background", c.Collection.Background.Enabled,
+			"timeout", c.Collection.Timeout,
+			"collector_timeouts", c.Collection.CollectorTimeouts,
+			"collectors", c.Collection.Collectors,
+			"max_concurrent", c.Collection.MaxConcurrent,
+			"autoscale", c.Collection.Autoscale.Enabled,
+			"domains_per_worker", c.Collection.Autoscale.DomainsPerWorker,
+			"max_workers", c.Collection.Autoscale.MaxWorkers,
+			"counter_wraps", c.Collection.CounterWraps,
+			"counter_retention", c.Collection.CounterRetention,
+			"event_retention", c.Collection.EventRetention,
+			"max_domains", c.Collection.MaxDomains,
+			"memory_limit_mib", c.Collection.MemoryLimit,
+			"device_cache_ttl", *c.Collection.DeviceCacheTTL,
+			"host_interfaces", c.Collection.HostInterfaces,
+			"jobs", *c.Collection.Jobs.Enabled,
+			"migrations", c.Collection.Migrations.Enabled,
+			"events", c.Collection.Events.Enabled,
+			"events_notify_url", c.Collection.Events.Notify.URL,
+			"events_notify_events", c.Collection.Events.Notify.Events,
+			"events_notify_timeout", c.Collection.Events.Notify.Timeout,
+			"events_notify_max_retries", *c.Collection.Events.Notify.MaxRetries,
+			"snapshots", *c.Collection.Snapshots.Enabled,
+			"snapshot_interval", c.Collection.Snapshots.Interval,
+			"process", c.Collection.Process.Enabled,
+			"pid_dir", c.Collection.Process.PIDDir,
+			"dirty_rate", c.Collection.DirtyRate.Enabled,
+			"dirty_rate_interval", c.Collection.DirtyRate.Interval,
+			"dirty_rate_period", c.Collection.DirtyRate.Period,
+			"perf", c.Collection.Perf.Enabled,
+			"perf_events", c.Collection.Perf.Events,
+			"resctrl", c.Collection.Resctrl.Enabled,
+			"node_devices", c.Collection.NodeDevices.Enabled,
+			"launch_security", c.Collection.LaunchSecurity.Enabled,
+			"filesystems", c.Collection.Filesystems.Enabled,
+			"guest_hostname", c.Collection.GuestHostname.Enabled,
+			"disk_block_jobs", c.Collection.Disks.BlockJobs,
+			"disk_throttle", c.Collection.Disks.Throttle,
+			"vhostuser_backend", c.Collection.VHostUser.Backend,
+			"ovs_vsctl", c.Collection.VHostUser.OVSVsctl,
+			"sriov", c.Collection.SRIOV.Enabled,
+			"sriov_ip_command", c.Collection.SRIOV.IPCommand),
+		slog.Group("metrics",
+			"enabled", c.Metrics.Enabled,
+			"extra_labels", c.Metrics.ExtraLabels,
+			"label_policy", c.Metrics.LabelPolicy,
+			"timestamps", c.Metrics.Timestamps,
+			"domain_metadata", c.Metrics.DomainMetadata.Enabled,
+			"domain_metadata_namespace", c.Metrics.DomainMetadata.Namespace,
+			"domain_metadata_labels", c.Metrics.DomainMetadata.Labels),
+		slog.Group("ha",
+			"enabled", c.HA.Enabled,
+			"lock_file", c.HA.LockFile,
+			"retry_interval", c.HA.RetryInterval),
+		slog.Group("tracing",
+			"enabled", c.Tracing.Enabled,
+			"endpoint", c.Tracing.Endpoint,
+			"service_name", c.Tracing.ServiceName,
+			"headers", len(c.Tracing.Headers),
+			"timeout", c.Tracing.Timeout,
+			"sample_ratio", *c.Tracing.SampleRatio),
+		slog.Group("probe",
+			"enabled", c.Probe.Enabled,
+			"allowed_targets", c.Probe.AllowedTargets,
+			"max_targets", c.Probe.MaxTargets,
+			"max_concurrent", c.Probe.MaxConcurrent,
+			"idle_timeout", c.Probe.IdleTimeout),
+	)
 }
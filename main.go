@@ -1,16 +1,23 @@
 package main
 
 import (
-	"log"
+	"log/slog"
+	"os"
+	"time"
 
 	"gitee.com/openeuler/uos-libvirtd-exporter/collector"
 	"gitee.com/openeuler/uos-libvirtd-exporter/config"
+	"gitee.com/openeuler/uos-libvirtd-exporter/logging"
 	"gitee.com/openeuler/uos-libvirtd-exporter/server"
 	"gitee.com/openeuler/uos-libvirtd-exporter/signal"
+	"gitee.com/openeuler/uos-libvirtd-exporter/systemd"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var version = "dev"
+var (
+	version = "dev"
+	commit  = "unknown"
+)
 
 // configWrapper wraps the config struct to implement the server.Config interface
 type configWrapper struct {
@@ -25,42 +32,232 @@ func (c *configWrapper) GetMetricsPath() string {
 	return c.Config.MetricsPath
 }
 
+func (c *configWrapper) GetHandlerOptions() server.HandlerOptions {
+	web := c.Config.Settings().Web
+	var pprofAddress string
+	if web.EnablePprof {
+		pprofAddress = web.PprofAddress
+	}
+	return server.HandlerOptions{
+		EnableOpenMetrics:   web.EnableOpenMetrics,
+		DisableCompression:  web.DisableCompression,
+		Timeout:             time.Duration(web.HandlerTimeout) * time.Second,
+		ScrapeAuditSize:     *web.ScrapeAuditSize,
+		ExtraLabels:         c.Config.Settings().Metrics.ExtraLabels,
+		ScrapeTimeoutOffset: time.Duration(*web.ScrapeTimeoutOffset * float64(time.Second)),
+		RuntimeMetrics:      web.EnableRuntimeMetrics,
+		MaxRequestsInFlight: web.MaxRequestsInFlight,
+		ContinueOnError:     web.ErrorHandling == "continue",
+		PprofAddress:        pprofAddress,
+	}
+}
+
+func (c *configWrapper) GetTLSOptions() server.TLSOptions {
+	tls := c.Config.Settings().Web.TLS
+	return server.TLSOptions{
+		CertFile:       tls.CertFile,
+		KeyFile:        tls.KeyFile,
+		ReloadInterval: time.Duration(tls.ReloadInterval) * time.Second,
+	}
+}
+
+func (c *configWrapper) GetAuthOptions() server.AuthOptions {
+	auth := c.Config.Settings().Web.Auth
+	return server.AuthOptions{
+		BasicAuthUsers: auth.BasicAuthUsers,
+		BearerTokens:   auth.BearerTokens,
+	}
+}
+
 func main() {
 	// Parse configuration
 	cfg, err := config.ParseConfig()
 	if err != nil {
-		log.Fatalf("Failed to parse configuration: %v", err)
+		slog.Error("Failed to parse configuration", "err", err)
+		os.Exit(1)
+	}
+
+	// Setup logging
+	settings := cfg.Settings()
+	if err := logging.Setup(settings.Logging.Level, settings.Logging.Format); err != nil {
+		slog.Error("Failed to setup logging", "err", err)
+		os.Exit(1)
 	}
 
-	log.Printf("Starting UOS Libvirt Exporter %s", version)
+	slog.Info("Starting UOS Libvirt Exporter", "version", version)
 	cfg.Log()
 
 	// Create libvirt collector
-	collector, err := collector.NewLibvirtCollector(cfg.LibvirtURI)
+	var leaderLock string
+	if settings.HA.Enabled {
+		leaderLock = settings.HA.LockFile
+	}
+	collectorTimeouts := make(map[string]time.Duration, len(settings.Collection.CollectorTimeouts))
+	for name, timeout := range settings.Collection.CollectorTimeouts {
+		collectorTimeouts[name] = time.Duration(timeout) * time.Second
+	}
+	var backgroundInterval time.Duration
+	if settings.Collection.Background.Enabled {
+		backgroundInterval = time.Duration(settings.Collection.Interval) * time.Second
+	}
+	var metadataLabels map[string]string
+	if settings.Metrics.DomainMetadata.Enabled {
+		metadataLabels = settings.Metrics.DomainMetadata.Labels
+	}
+	var tracingEndpoint string
+	if settings.Tracing.Enabled {
+		tracingEndpoint = settings.Tracing.Endpoint
+	}
+	opts := collector.Options{
+		FallbackURIs:         settings.Libvirt.FallbackURIs,
+		HostURIs:             settings.Libvirt.URIs,
+		DiscoveryName:        settings.Libvirt.Discovery.Name,
+		DiscoveryType:        settings.Libvirt.Discovery.Type,
+		DiscoveryURITemplate: settings.Libvirt.Discovery.URITemplate,
+		DiscoveryInterval:    time.Duration(settings.Libvirt.Discovery.Interval) * time.Second,
+		ReconnectInterval:    time.Duration(settings.Libvirt.ReconnectInterval) * time.Second,
+		AuthUsername:         settings.Libvirt.Auth.Username,
+		AuthPassword:         settings.Libvirt.Auth.Password,
+		TLSPKIPath:           settings.Libvirt.TLS.PKIPath,
+		SSHKeyFile:           settings.Libvirt.SSH.KeyFile,
+		SSHKnownHosts:        settings.Libvirt.SSH.KnownHosts,
+		ProbeInterval:        time.Duration(*settings.Libvirt.ProbeInterval) * time.Second,
+		AdminURI:             settings.Libvirt.AdminURI,
+		Version:              version,
+		Commit:               commit,
+		LabelPolicy:          settings.Metrics.LabelPolicy,
+		CollectionMode:       settings.Collection.Mode,
+		DeviceCacheTTL:       time.Duration(*settings.Collection.DeviceCacheTTL) * time.Second,
+		HostInterfaces:       settings.Collection.HostInterfaces,
+		EnableDirtyRate:      settings.Collection.DirtyRate.Enabled,
+		DirtyRateInterval:    time.Duration(settings.Collection.DirtyRate.Interval) * time.Second,
+		DirtyRatePeriod:      time.Duration(settings.Collection.DirtyRate.Period) * time.Second,
+		EnablePerf:           settings.Collection.Perf.Enabled,
+		PerfEvents:           settings.Collection.Perf.Events,
+		EnableResctrl:        settings.Collection.Resctrl.Enabled,
+		EnableNodeDevices:    settings.Collection.NodeDevices.Enabled,
+		EnableLaunchSecurity: settings.Collection.LaunchSecurity.Enabled,
+		EnableFilesystems:    settings.Collection.Filesystems.Enabled,
+		EnableGuestHostname:  settings.Collection.GuestHostname.Enabled,
+		EnableBlockJobs:      settings.Collection.Disks.BlockJobs,
+		EnableDiskThrottle:   settings.Collection.Disks.Throttle,
+		EnableEvents:         settings.Collection.Events.Enabled,
+		EventsNotifyURL:      settings.Collection.Events.Notify.URL,
+		EventsNotifyEvents:   settings.Collection.Events.Notify.Events,
+		EventsNotifyTimeout:  time.Duration(settings.Collection.Events.Notify.Timeout) * time.Second,
+		EventsNotifyRetries:  *settings.Collection.Events.Notify.MaxRetries,
+		MaxConcurrent:        settings.Collection.MaxConcurrent,
+		Autoscale:            settings.Collection.Autoscale.Enabled,
+		DomainsPerWorker:     settings.Collection.Autoscale.DomainsPerWorker,
+		MaxWorkers:           settings.Collection.Autoscale.MaxWorkers,
+		EnableJobs:           *settings.Collection.Jobs.Enabled,
+		EnableMigrations:     settings.Collection.Migrations.Enabled,
+		EnableSnapshots:      *settings.Collection.Snapshots.Enabled,
+		SnapshotInterval:     time.Duration(settings.Collection.Snapshots.Interval) * time.Second,
+		EnableProcess:        settings.Collection.Process.Enabled,
+		PIDDir:               settings.Collection.Process.PIDDir,
+		VHostUserBackend:     settings.Collection.VHostUser.Backend,
+		OVSVsctl:             settings.Collection.VHostUser.OVSVsctl,
+		EnableSRIOV:          settings.Collection.SRIOV.Enabled,
+		IPCommand:            settings.Collection.SRIOV.IPCommand,
+		DomainTimeout:        time.Duration(settings.Collection.Timeout) * time.Second,
+		CollectorTimeouts:    collectorTimeouts,
+		Collectors:           settings.Collection.Collectors,
+		BackgroundInterval:   backgroundInterval,
+		MaxDomains:           settings.Collection.MaxDomains,
+		MemoryLimit:          uint64(settings.Collection.MemoryLimit) << 20,
+		CounterWrapMode:      settings.Collection.CounterWraps,
+		Timestamps:           settings.Metrics.Timestamps,
+		MetadataLabels:       metadataLabels,
+		MetadataNamespace:    settings.Metrics.DomainMetadata.Namespace,
+		EnabledMetrics:       settings.Metrics.Enabled,
+		CounterRetention:     time.Duration(settings.Collection.CounterRetention) * time.Second,
+		EventRetention:       time.Duration(settings.Collection.EventRetention) * time.Second,
+		LeaderLock:           leaderLock,
+		LeaderRetryInterval:  time.Duration(settings.HA.RetryInterval) * time.Second,
+		TracingEndpoint:      tracingEndpoint,
+		TracingServiceName:   settings.Tracing.ServiceName,
+		TracingHeaders:       settings.Tracing.Headers,
+		TracingTimeout:       time.Duration(settings.Tracing.Timeout) * time.Second,
+		TracingSampleRatio:   *settings.Tracing.SampleRatio,
+	}
+
+	// Create the cache of remote targets scraped through /probe
+	var targets *collector.TargetCache
+	if settings.Probe.Enabled {
+		targets, err = collector.NewTargetCache(
+			opts,
+			settings.Probe.AllowedTargets,
+			settings.Probe.MaxTargets,
+			settings.Probe.MaxConcurrent,
+			time.Duration(settings.Probe.IdleTimeout)*time.Second,
+		)
+		if err != nil {
+			slog.Error("Failed to create probe targets", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	collector, err := collector.NewLibvirtCollector(cfg.LibvirtURI, opts)
 	if err != nil {
-		log.Fatalf("Failed to create libvirt collector: %v", err)
+		slog.Error("Failed to create libvirt collector", "err", err)
+		os.Exit(1)
 	}
-	defer collector.Close()
 
 	// Register collector
 	prometheus.MustRegister(collector)
 
 	// Create and setup HTTP server
 	server := server.NewServer(&configWrapper{cfg}, collector)
+	if targets != nil {
+		server.SetTargets(targets)
+	}
 	server.SetupHandlers()
 
+	// Accept connections on the socket passed by systemd socket activation
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		slog.Error("Failed to use sockets passed by systemd", "err", err)
+		os.Exit(1)
+	}
+	if len(listeners) > 0 {
+		for _, extra := range listeners[1:] {
+			slog.Warn("Ignoring extra socket passed by systemd", "address", extra.Addr())
+			extra.Close()
+		}
+		slog.Info("Using socket passed by systemd", "address", listeners[0].Addr())
+		server.SetListener(listeners[0])
+	}
+	if err := server.Listen(); err != nil {
+		slog.Error("Server failed", "err", err)
+		os.Exit(1)
+	}
+
 	// Setup signal handling
-	signalHandler := signal.NewHandler(collector)
+	shutdownTimeout := time.Duration(settings.Web.ShutdownTimeout) * time.Second
+	signalHandler := signal.NewHandler(collector, server, shutdownTimeout)
 	signalHandler.Start()
 
-	log.Printf(
-		"UOS Libvirt Exporter is ready to serve requests on %s%s",
-		cfg.ListenAddr,
-		cfg.MetricsPath,
-	)
+	slog.Info("UOS Libvirt Exporter is ready to serve requests",
+		"address", cfg.ListenAddr,
+		"path", cfg.MetricsPath)
+
+	// Tell systemd the exporter is ready and keep its watchdog fed
+	if _, err := systemd.Notify("READY=1"); err != nil {
+		slog.Warn("Failed to notify systemd", "err", err)
+	}
+	watchdog := systemd.StartWatchdog(collector.Healthy)
 
 	// Start HTTP server
 	if err := server.Start(); err != nil {
-		log.Fatalf("Server failed: %v", err)
+		slog.Error("Server failed", "err", err)
+		os.Exit(1)
+	}
+
+	// The server only stops on its own after a shutdown signal
+	<-signalHandler.Done()
+	watchdog.Stop()
+	if targets != nil {
+		targets.Close()
 	}
 }
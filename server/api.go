@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"gitee.com/openeuler/uos-libvirtd-exporter/collector"
+)
+
+// hostResponse is the JSON document served at /api/v1/host
+type hostResponse struct {
+	Hostname          string                `json:"hostname"`
+	Driver            string                `json:"driver"`
+	LibvirtVersion    string                `json:"libvirt_version"`
+	HypervisorVersion string                `json:"hypervisor_version"`
+	ExporterVersion   string                `json:"exporter_version"`
+	CPUs              int                   `json:"cpus"`
+	MemoryTotalBytes  uint64                `json:"memory_total_bytes"`
+	MemoryFreeBytes   uint64                `json:"memory_free_bytes"`
+	ActiveDomains     int                   `json:"active_domains"`
+	DefinedDomains    int                   `json:"defined_domains"`
+	StoragePools      []storagePoolResponse `json:"storage_pools"`
+	Networks          []networkResponse     `json:"networks"`
+}
+
+// storagePoolResponse summarizes a storage pool
+type storagePoolResponse struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	State           string `json:"state"`
+	CapacityBytes   uint64 `json:"capacity_bytes"`
+	AllocationBytes uint64 `json:"allocation_bytes"`
+	AvailableBytes  uint64 `json:"available_bytes"`
+	Volumes         int    `json:"volumes"`
+}
+
+// networkResponse summarizes a virtual network
+type networkResponse struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+	Bridge string `json:"bridge"`
+}
+
+// hostHandler serves host level data as JSON
+func (s *Server) hostHandler(w http.ResponseWriter, r *http.Request) {
+	metrics, err := s.collector.HostInfo(r.Context())
+	if err != nil {
+		slog.Warn("Failed to collect host info", "err", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	response := hostResponse{
+		Hostname:          metrics.Hostname,
+		Driver:            metrics.DriverType,
+		LibvirtVersion:    collector.FormatVersion(metrics.LibvirtVersion),
+		HypervisorVersion: collector.FormatVersion(metrics.HypervisorVersion),
+		ExporterVersion:   version,
+		CPUs:              metrics.TotalCPUs,
+		MemoryTotalBytes:  metrics.TotalMemoryBytes,
+		MemoryFreeBytes:   metrics.FreeMemoryBytes,
+		ActiveDomains:     metrics.ActiveDomains,
+		DefinedDomains:    metrics.DefinedDomains,
+		StoragePools:      make([]storagePoolResponse, 0, len(metrics.StoragePools)),
+		Networks:          make([]networkResponse, 0, len(metrics.Networks)),
+	}
+	for _, pool := range metrics.StoragePools {
+		response.StoragePools = append(response.StoragePools, storagePoolResponse{
+			Name:            pool.Name,
+			Type:            pool.Type,
+			State:           pool.State,
+			CapacityBytes:   pool.Capacity,
+			AllocationBytes: pool.Allocation,
+			AvailableBytes:  pool.Available,
+			Volumes:         pool.Volumes,
+		})
+	}
+	for _, network := range metrics.Networks {
+		response.Networks = append(response.Networks, networkResponse{
+			Name:   network.Name,
+			Active: network.Active,
+			Bridge: network.Bridge,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(response)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// scrapeRecord describes a single scrape of the metrics endpoint
+type scrapeRecord struct {
+	Time            time.Time `json:"time"`
+	Client          string    `json:"client"`
+	UserAgent       string    `json:"user_agent,omitempty"`
+	Query           string    `json:"query,omitempty"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Series          int       `json:"series"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// scrapeAudit keeps the most recent scrapes in a ring buffer
+type scrapeAudit struct {
+	mutex   sync.Mutex
+	records []scrapeRecord
+	next    int
+	full    bool
+}
+
+// newScrapeAudit creates a scrapeAudit holding up to size records
+func newScrapeAudit(size int) *scrapeAudit {
+	return &scrapeAudit{records: make([]scrapeRecord, size)}
+}
+
+// add stores a record, overwriting the oldest one when the buffer is full
+func (a *scrapeAudit) add(record scrapeRecord) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.records[a.next] = record
+	a.next = (a.next + 1) % len(a.records)
+	if a.next == 0 {
+		a.full = true
+	}
+}
+
+// snapshot returns the stored records, oldest first
+func (a *scrapeAudit) snapshot() []scrapeRecord {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.full {
+		return append([]scrapeRecord(nil), a.records[:a.next]...)
+	}
+	records := make([]scrapeRecord, 0, len(a.records))
+	records = append(records, a.records[a.next:]...)
+	return append(records, a.records[:a.next]...)
+}
+
+// gatherer wraps a gatherer so that every gather of the request is recorded
+func (a *scrapeAudit) gatherer(gatherer prometheus.Gatherer, r *http.Request) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		start := time.Now()
+		families, err := gatherer.Gather()
+
+		record := scrapeRecord{
+			Time:            start,
+			Client:          r.RemoteAddr,
+			UserAgent:       r.UserAgent(),
+			Query:           r.URL.RawQuery,
+			DurationSeconds: time.Since(start).Seconds(),
+		}
+		for _, family := range families {
+			record.Series += len(family.GetMetric())
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		a.add(record)
+
+		return families, err
+	})
+}
+
+// ServeHTTP serves the recorded scrapes as JSON
+func (a *scrapeAudit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(a.snapshot())
+}
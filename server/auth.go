@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AuthOptions holds the credentials accepted by the HTTP endpoints;
+// authentication is disabled when no credentials are configured
+type AuthOptions struct {
+	// BasicAuthUsers maps basic auth user names to their passwords
+	BasicAuthUsers map[string]string
+	// BearerTokens are the accepted static bearer tokens
+	BearerTokens []string
+}
+
+// enabled reports whether any credentials are configured
+func (o AuthOptions) enabled() bool {
+	return len(o.BasicAuthUsers) > 0 || len(o.BearerTokens) > 0
+}
+
+// authorized reports whether a request carries valid credentials
+func (o AuthOptions) authorized(r *http.Request) bool {
+	if user, password, ok := r.BasicAuth(); ok {
+		expected, found := o.BasicAuthUsers[user]
+		// Compare anyway so unknown users take as long as wrong passwords
+		match := subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1
+		return found && match
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		valid := false
+		for _, expected := range o.BearerTokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+				valid = true
+			}
+		}
+		return valid
+	}
+
+	return false
+}
+
+// authenticate wraps a handler so that it rejects requests without valid
+// credentials when authentication is enabled
+func (s *Server) authenticate(handler http.Handler) http.Handler {
+	opts := s.config.GetAuthOptions()
+	if !opts.enabled() {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !opts.authorized(r) {
+			if len(opts.BasicAuthUsers) > 0 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="uos-libvirtd-exporter"`)
+			} else {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// domainsTemplate renders the domain inventory page
+var domainsTemplate = template.Must(template.New("domains").Funcs(template.FuncMap{
+	"bytes":    formatBytes,
+	"duration": formatSeconds,
+	"since":    formatSince,
+}).Parse(`<html>
+<head><title>UOS Libvirt Exporter - Domains</title></head>
+<body>
+<h1>Domains</h1>
+<p>{{len .}} domain(s) seen by the last scrapes</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Name</th><th>UUID</th><th>State</th><th>vCPUs</th><th>Memory</th><th>Uptime</th><th>Last collected</th><th>Status</th></tr>
+{{range .}}<tr>
+<td>{{.Name}}</td>
+<td>{{.UUID}}</td>
+<td>{{.State}}</td>
+<td>{{.VCPUs}}</td>
+<td>{{bytes .MemoryBytes}}</td>
+<td>{{if .HasUptime}}{{duration .Uptime}}{{else}}-{{end}}</td>
+<td>{{since .LastCollected}}</td>
+<td>{{if .LastError}}error: {{.LastError}}{{else}}ok{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>`))
+
+// domainsHandler lists the domains seen by the last scrapes
+func (s *Server) domainsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := domainsTemplate.Execute(w, s.collector.Domains()); err != nil {
+		slog.Warn("Failed to render domains page", "err", err)
+	}
+}
+
+// formatBytes formats a byte count using binary units
+func formatBytes(value float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// formatSeconds formats a number of seconds as a duration
+func formatSeconds(seconds float64) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+// formatSince formats the time elapsed since t
+func formatSince(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return time.Since(t).Truncate(time.Second).String() + " ago"
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+)
+
+// startPprof serves the pprof profiling endpoints on their own address, so
+// they are never exposed on the metrics port
+func startPprof(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	pprofServer := &http.Server{
+		Addr:    address,
+		Handler: mux,
+	}
+	go func() {
+		slog.Info("Starting pprof server", "address", address)
+		if err := pprofServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("pprof server failed", "err", err)
+		}
+	}()
+	return pprofServer
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"gitee.com/openeuler/uos-libvirtd-exporter/collector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// SetTargets enables the /probe endpoint scraping the remote libvirt hosts of
+// targets. It must be called before SetupHandlers.
+func (s *Server) SetTargets(targets *collector.TargetCache) {
+	s.targets = targets
+}
+
+// probeHandler scrapes the libvirt URI given by the target query parameter,
+// e.g. /probe?target=qemu+tcp://host/system. Like the metrics endpoint it
+// accepts collect[] and domain parameters and honours the scrape timeout
+// announced by Prometheus.
+func (s *Server) probeHandler() http.Handler {
+	handlerOpts := s.config.GetHandlerOptions()
+	opts := promHandlerOpts(handlerOpts)
+
+	return limitInFlight(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		target := query.Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel, err := scrapeContext(r, handlerOpts.ScrapeTimeoutOffset)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer cancel()
+		if ctx == nil {
+			ctx = r.Context()
+		}
+
+		targetCollector, release, err := s.targets.Acquire(ctx, target)
+		if err != nil {
+			if errors.Is(err, collector.ErrTargetNotAllowed) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			if !errors.Is(err, context.Canceled) {
+				slog.Warn("Failed to probe target", "target", target, "err", err)
+			}
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+
+		selected, err := targetCollector.Select(ctx, query["collect[]"], query["domain"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		registry := prometheus.NewRegistry()
+		if err := s.registerer(registry).Register(selected); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var gatherer prometheus.Gatherer = registry
+		if s.audit != nil {
+			gatherer = s.audit.gatherer(gatherer, r)
+		}
+		promhttp.HandlerFor(gatherer, opts).ServeHTTP(w, r)
+	}), handlerOpts.MaxRequestsInFlight)
+}
@@ -1,30 +1,84 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"html/template"
+	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"gitee.com/openeuler/uos-libvirtd-exporter/collector"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 )
 
 var version = "dev"
 
 // Server represents the HTTP server
 type Server struct {
-	config interface {
-		GetListenAddr() string
-		GetMetricsPath() string
-	}
-	collector *collector.LibvirtCollector
+	config     Config
+	collector  *collector.LibvirtCollector
+	audit      *scrapeAudit
+	mux        *http.ServeMux
+	targets    *collector.TargetCache // nil disables /probe
+	mutex      sync.Mutex
+	httpServer *http.Server
+	pprof      *http.Server  // nil when pprof is disabled
+	reloader   *certReloader // nil when TLS is disabled
+	listener   net.Listener  // nil until Listen or SetListener
+	shutdown   bool          // Shutdown was called, Start must not serve
 }
 
 // Config interface for server configuration
 type Config interface {
 	GetListenAddr() string
 	GetMetricsPath() string
+	GetHandlerOptions() HandlerOptions
+	GetTLSOptions() TLSOptions
+	GetAuthOptions() AuthOptions
+}
+
+// HandlerOptions holds the settings of the metrics handler
+type HandlerOptions struct {
+	EnableOpenMetrics  bool
+	DisableCompression bool
+	Timeout            time.Duration
+	// ScrapeAuditSize is the number of recent scrapes listed at
+	// /debug/scrapes (0 disables the audit log)
+	ScrapeAuditSize int
+	// ExtraLabels are constant labels added to every exported metric
+	ExtraLabels map[string]string
+	// MaxRequestsInFlight limits the number of concurrent metric requests
+	// (0 = no limit)
+	MaxRequestsInFlight int
+	// ContinueOnError serves the metrics collected successfully when some
+	// fail, instead of an HTTP error
+	ContinueOnError bool
+	// RuntimeMetrics adds the Go runtime and process metrics of the exporter
+	// to the metrics endpoint
+	RuntimeMetrics bool
+	// PprofAddress is the address of a separate listener serving the pprof
+	// profiling endpoints (empty disables pprof)
+	PprofAddress string
+	// ScrapeTimeoutOffset is subtracted from the timeout announced by
+	// Prometheus in the X-Prometheus-Scrape-Timeout-Seconds header to leave
+	// time for sending the response
+	ScrapeTimeoutOffset time.Duration
+}
+
+// TLSOptions holds the HTTPS settings; TLS is disabled when CertFile is empty
+type TLSOptions struct {
+	CertFile       string
+	KeyFile        string
+	ReloadInterval time.Duration
 }
 
 // NewServer creates a new HTTP server
@@ -32,6 +86,7 @@ func NewServer(config Config, collector *collector.LibvirtCollector) *Server {
 	return &Server{
 		config:    config,
 		collector: collector,
+		mux:       http.NewServeMux(),
 	}
 }
 
@@ -39,38 +94,351 @@ func NewServer(config Config, collector *collector.LibvirtCollector) *Server {
 func (s *Server) SetupHandlers() {
 	// Create a custom registry and register only our collector
 	registry := prometheus.NewRegistry()
-	registry.MustRegister(s.collector)
+	scrape := &deadlineCollector{collector: s.collector}
+	s.registerer(registry).MustRegister(scrape)
 
 	// Metrics endpoint using custom registry
-	http.Handle(
-		s.config.GetMetricsPath(),
-		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
-	)
+	s.mux.Handle(s.config.GetMetricsPath(), s.authenticate(s.metricsHandler(registry, scrape, s.runtimeRegistry())))
+
+	// Recent scrapes endpoint
+	if size := s.config.GetHandlerOptions().ScrapeAuditSize; size > 0 {
+		s.audit = newScrapeAudit(size)
+		s.mux.Handle("/debug/scrapes", s.authenticate(s.audit))
+	}
+
+	// Remote libvirt hosts
+	if s.targets != nil {
+		s.mux.Handle("/probe", s.authenticate(s.probeHandler()))
+	}
+
+	// Host snapshot for automation
+	s.mux.Handle("/api/v1/host", s.authenticate(http.HandlerFunc(s.hostHandler)))
+
+	// Domain inventory page
+	s.mux.Handle("/domains", s.authenticate(http.HandlerFunc(s.domainsHandler)))
 
 	// Root endpoint
-	http.HandleFunc("/", s.rootHandler)
+	s.mux.Handle("/", s.authenticate(http.HandlerFunc(s.rootHandler)))
 }
 
-// rootHandler handles the root endpoint
-func (s *Server) rootHandler(w http.ResponseWriter, r *http.Request) {
-	html := fmt.Sprintf(`<html>
+// metricsHandler serves the metrics of the registry. Requests may restrict
+// the scrape to some collectors with collect[] query parameters, e.g.
+// /metrics?collect[]=disk&collect[]=network, and to some domains (by name or
+// UUID) with domain query parameters, e.g. /metrics?domain=vm1&domain=vm2.
+// When Prometheus announces its scrape timeout, the scrape is cut off before
+// it and returns the domains collected so far. Unfiltered scrapes are served
+// by the prebuilt registry, whose collector is scrape.
+func (s *Server) metricsHandler(
+	registry *prometheus.Registry,
+	scrape *deadlineCollector,
+	runtime *prometheus.Registry,
+) http.Handler {
+	handlerOpts := s.config.GetHandlerOptions()
+	opts := promHandlerOpts(handlerOpts)
+
+	return limitInFlight(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel, err := scrapeContext(r, handlerOpts.ScrapeTimeoutOffset)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer cancel()
+
+		gatherer := scrape.gatherer(registry, ctx)
+		query := r.URL.Query()
+		names := query["collect[]"]
+		domains := query["domain"]
+		if len(names) > 0 || len(domains) > 0 {
+			selected, err := s.collector.Select(ctx, names, domains)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			filtered := prometheus.NewRegistry()
+			if err := s.registerer(filtered).Register(selected); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			gatherer = filtered
+		}
+		if runtime != nil {
+			gatherer = prometheus.Gatherers{gatherer, runtime}
+		}
+
+		if s.audit != nil {
+			gatherer = s.audit.gatherer(gatherer, r)
+		}
+		promhttp.HandlerFor(gatherer, opts).ServeHTTP(w, r)
+	}), handlerOpts.MaxRequestsInFlight)
+}
+
+// deadlineCollector is the collector of the prebuilt registry. It runs the
+// scrape with the deadline of the request being gathered; scrapes of the
+// libvirt collector are serialized anyway, so requests take turns.
+type deadlineCollector struct {
+	collector *collector.LibvirtCollector
+	mutex     sync.Mutex      // held while a request gathers the registry
+	ctx       context.Context // deadline of that request, nil for none
+}
+
+// Describe implements the prometheus.Collector interface
+func (d *deadlineCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.collector.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface
+func (d *deadlineCollector) Collect(ch chan<- prometheus.Metric) {
+	if d.ctx == nil {
+		d.collector.Collect(ch)
+		return
+	}
+	selected, err := d.collector.Select(d.ctx, nil, nil)
+	if err != nil {
+		d.collector.Collect(ch)
+		return
+	}
+	selected.Collect(ch)
+}
+
+// gatherer returns a gatherer of registry whose scrape is bounded by ctx
+func (d *deadlineCollector) gatherer(registry prometheus.Gatherer, ctx context.Context) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+
+		d.ctx = ctx
+		defer func() { d.ctx = nil }()
+		return registry.Gather()
+	})
+}
+
+// promHandlerOpts returns the options of the promhttp handlers. The in-flight
+// limit is applied by limitInFlight since handlers are built per request.
+func promHandlerOpts(handlerOpts HandlerOptions) promhttp.HandlerOpts {
+	opts := promhttp.HandlerOpts{
+		ErrorLog:           slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+		EnableOpenMetrics:  handlerOpts.EnableOpenMetrics,
+		DisableCompression: handlerOpts.DisableCompression,
+		Timeout:            handlerOpts.Timeout,
+	}
+	if handlerOpts.ContinueOnError {
+		opts.ErrorHandling = promhttp.ContinueOnError
+	}
+	return opts
+}
+
+// limitInFlight rejects requests with 503 Service Unavailable while max
+// requests are already being served by handler (0 = no limit)
+func limitInFlight(handler http.Handler, max int) http.Handler {
+	if max <= 0 {
+		return handler
+	}
+
+	inFlight := make(chan struct{}, max)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case inFlight <- struct{}{}:
+			defer func() { <-inFlight }()
+			handler.ServeHTTP(w, r)
+		default:
+			http.Error(w, fmt.Sprintf("limit of concurrent requests reached (%d), try again later", max),
+				http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// scrapeContext returns a context expiring before the scrape timeout announced
+// in the X-Prometheus-Scrape-Timeout-Seconds header, or a nil context if the
+// request has none
+func scrapeContext(r *http.Request, offset time.Duration) (context.Context, context.CancelFunc, error) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		return nil, func() {}, nil
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return nil, nil, fmt.Errorf("invalid X-Prometheus-Scrape-Timeout-Seconds header %q", header)
+	}
+	timeout := time.Duration(seconds*float64(time.Second)) - offset
+	if timeout <= 0 {
+		return nil, nil, fmt.Errorf("scrape timeout %s is shorter than the scrape timeout offset", header)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return ctx, cancel, nil
+}
+
+// registerer returns a registerer adding the configured extra labels to every
+// metric registered through it
+func (s *Server) registerer(registry *prometheus.Registry) prometheus.Registerer {
+	extraLabels := s.config.GetHandlerOptions().ExtraLabels
+	if len(extraLabels) == 0 {
+		return registry
+	}
+	return prometheus.WrapRegistererWith(prometheus.Labels(extraLabels), registry)
+}
+
+// runtimeRegistry returns a registry holding the Go runtime and process
+// collectors, or nil when runtime metrics are disabled. It is built once and
+// gathered alongside the exporter metrics of every scrape.
+func (s *Server) runtimeRegistry() *prometheus.Registry {
+	if !s.config.GetHandlerOptions().RuntimeMetrics {
+		return nil
+	}
+	registry := prometheus.NewRegistry()
+	s.registerer(registry).MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// statusTemplate renders the landing page
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"since": formatSince,
+	"uptime": func(t time.Time) string {
+		return time.Since(t).Truncate(time.Second).String()
+	},
+}).Parse(`<html>
 <head><title>UOS Libvirt Exporter</title></head>
 <body>
 <h1>UOS Libvirt Exporter</h1>
-<p><a href='%s'>Metrics</a></p>
-<p>Build version: %s</p>
+<p><a href='{{.MetricsPath}}'>Metrics</a></p>
+<p><a href='/domains'>Domains</a></p>
+<h2>Status</h2>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Version</th><td>{{.Version}}</td></tr>
+<tr><th>Commit</th><td>{{.Commit}}</td></tr>
+<tr><th>Libvirt URI</th><td>{{if .URI}}{{.URI}}{{else}}disconnected{{end}}</td></tr>
+<tr><th>Uptime</th><td>{{uptime .StartTime}}</td></tr>
+<tr><th>Last scrape</th><td>{{since .LastScrape}}</td></tr>
+<tr><th>Last scrape duration</th><td>{{if .LastScrape.IsZero}}-{{else}}{{.LastScrapeDuration}}{{end}}</td></tr>
+<tr><th>Last scrape status</th><td>{{if .LastScrapeError}}error: {{.LastScrapeError}}{{else}}ok{{end}}</td></tr>
+</table>
+<h2>Collectors</h2>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Name</th><th>Enabled</th><th>Last error</th></tr>
+{{range .Collectors}}<tr>
+<td>{{.Name}}</td>
+<td>{{if .Enabled}}yes{{else}}no{{end}}</td>
+<td>{{if .LastError}}{{.LastError}} ({{since .LastErrorTime}}){{else}}-{{end}}</td>
+</tr>
+{{end}}</table>
 </body>
-</html>`, s.config.GetMetricsPath(), version)
+</html>`))
+
+// statusPage is the data of the landing page
+type statusPage struct {
+	collector.Status
+	MetricsPath string
+}
+
+// rootHandler serves the landing page with the exporter status
+func (s *Server) rootHandler(w http.ResponseWriter, r *http.Request) {
+	status := s.collector.Status()
+	if status.Version == "" {
+		status.Version = version
+	}
+	page := statusPage{Status: status, MetricsPath: s.config.GetMetricsPath()}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(html))
+	if err := statusTemplate.Execute(w, page); err != nil {
+		slog.Warn("Failed to render status page", "err", err)
+	}
+}
+
+// SetListener makes the server accept connections on an existing listener,
+// e.g. a socket passed by systemd, instead of the configured listen address
+func (s *Server) SetListener(listener net.Listener) {
+	s.listener = listener
 }
 
-// Start starts the HTTP server
+// Listen binds the configured listen address, unless a listener was set. Once
+// it returns, connections are queued until Start serves them.
+func (s *Server) Listen() error {
+	if s.listener != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.config.GetListenAddr())
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.config.GetListenAddr(), err)
+	}
+	s.listener = listener
+	return nil
+}
+
+// Start starts the HTTP server and blocks until it fails or Shutdown is called
 func (s *Server) Start() error {
-	log.Printf("Starting HTTP server on %s", s.config.GetListenAddr())
-	if err := http.ListenAndServe(s.config.GetListenAddr(), nil); err != nil {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	httpServer := &http.Server{
+		Addr:    s.listener.Addr().String(),
+		Handler: s.mux,
+	}
+
+	var reloader *certReloader
+	tlsOpts := s.config.GetTLSOptions()
+	if tlsOpts.CertFile != "" {
+		var err error
+		reloader, err = newCertReloader(tlsOpts.CertFile, tlsOpts.KeyFile)
+		if err != nil {
+			return err
+		}
+		reloader.watch(tlsOpts.ReloadInterval)
+
+		httpServer.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.getCertificate,
+		}
+	}
+
+	s.mutex.Lock()
+	if s.shutdown {
+		s.mutex.Unlock()
+		reloader.Stop()
+		s.listener.Close()
+		return nil
+	}
+	s.httpServer = httpServer
+	s.reloader = reloader
+	if address := s.config.GetHandlerOptions().PprofAddress; address != "" {
+		s.pprof = startPprof(address)
+	}
+	s.mutex.Unlock()
+
+	var err error
+	if httpServer.TLSConfig == nil {
+		slog.Info("Starting HTTP server", "address", httpServer.Addr)
+		err = httpServer.Serve(s.listener)
+	} else {
+		slog.Info("Starting HTTPS server", "address", httpServer.Addr)
+		err = httpServer.ServeTLS(s.listener, "", "")
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("failed to start HTTP server: %w", err)
 	}
 	return nil
 }
+
+// Shutdown stops accepting connections and waits for in-flight requests to
+// finish until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.shutdown = true
+	httpServer := s.httpServer
+	pprof := s.pprof
+	reloader := s.reloader
+	s.mutex.Unlock()
+
+	if pprof != nil {
+		pprof.Close()
+	}
+	reloader.Stop()
+	if httpServer == nil {
+		return nil
+	}
+	return httpServer.Shutdown(ctx)
+}
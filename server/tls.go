@@ -0,0 +1,146 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// certReloader serves a TLS certificate that is reloaded from disk when the
+// files change or SIGHUP is received, so rotated certificates are picked up
+// without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex   sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+
+	stop chan struct{} // closed by Stop, nil until watch is called
+	done chan struct{}
+}
+
+// newCertReloader loads the certificate and key, failing if they are unusable
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload reads the certificate and key from disk. On failure the previous
+// certificate stays in use.
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mutex.Unlock()
+	return nil
+}
+
+// latestModTime returns the most recent modification time of the certificate files
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// changed reports whether the certificate files were modified since the last load
+func (r *certReloader) changed() bool {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		// Files may be briefly missing while being replaced
+		return false
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return !modTime.Equal(r.modTime)
+}
+
+// watch reloads the certificate on SIGHUP and, if interval is positive, when
+// the files change, until Stop is called
+func (r *certReloader) watch(interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		defer signal.Stop(hup)
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-hup:
+				slog.Info("Received SIGHUP, reloading TLS certificate")
+			case <-tick:
+				if !r.changed() {
+					continue
+				}
+				slog.Info("TLS certificate files changed, reloading")
+			}
+
+			if err := r.reload(); err != nil {
+				slog.Warn("Failed to reload TLS certificate, keeping the previous certificate", "err", err)
+				continue
+			}
+			slog.Info("TLS certificate reloaded")
+		}
+	}()
+}
+
+// Stop stops watching for certificate changes and SIGHUP
+func (r *certReloader) Stop() {
+	if r == nil || r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+// getCertificate implements tls.Config.GetCertificate
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, nil
+}
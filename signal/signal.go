@@ -1,25 +1,41 @@
 package signal
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"gitee.com/openeuler/uos-libvirtd-exporter/collector"
+	"gitee.com/openeuler/uos-libvirtd-exporter/systemd"
 )
 
+// Server is an HTTP server that can be shut down gracefully
+type Server interface {
+	Shutdown(ctx context.Context) error
+}
+
 // Handler handles OS signals for graceful shutdown
 type Handler struct {
-	collector *collector.LibvirtCollector
-	sigChan   chan os.Signal
+	collector    *collector.LibvirtCollector
+	server       Server
+	drainTimeout time.Duration
+	sigChan      chan os.Signal
+	done         chan struct{}
 }
 
-// NewHandler creates a new signal handler
-func NewHandler(collector *collector.LibvirtCollector) *Handler {
+// NewHandler creates a new signal handler. On shutdown in-flight requests of
+// server are given up to drainTimeout to finish before the libvirt
+// connection is closed.
+func NewHandler(collector *collector.LibvirtCollector, server Server, drainTimeout time.Duration) *Handler {
 	return &Handler{
-		collector: collector,
-		sigChan:   make(chan os.Signal, 1),
+		collector:    collector,
+		server:       server,
+		drainTimeout: drainTimeout,
+		sigChan:      make(chan os.Signal, 1),
+		done:         make(chan struct{}),
 	}
 }
 
@@ -29,16 +45,31 @@ func (s *Handler) Start() {
 
 	go func() {
 		<-s.sigChan
-		log.Println("Shutting down...")
+		slog.Info("Shutting down")
+		if _, err := systemd.Notify("STOPPING=1"); err != nil {
+			slog.Warn("Failed to notify systemd", "err", err)
+		}
 		s.shutdown()
-		os.Exit(0)
+		close(s.done)
 	}()
 }
 
+// Done returns a channel closed once the shutdown has completed
+func (s *Handler) Done() <-chan struct{} {
+	return s.done
+}
+
 // shutdown performs cleanup operations
 func (s *Handler) shutdown() {
+	if s.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s.drainTimeout)
+		defer cancel()
+		if err := s.server.Shutdown(ctx); err != nil {
+			slog.Warn("Failed to drain HTTP connections", "err", err)
+		}
+	}
 	if s.collector != nil {
 		s.collector.Close()
 	}
-	log.Println("Shutdown complete")
+	slog.Info("Shutdown complete")
 }
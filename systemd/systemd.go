@@ -0,0 +1,148 @@
+package systemd
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// listenFDsStart is the first file descriptor passed by socket activation
+const listenFDsStart = 3
+
+// Listeners returns the sockets passed by systemd socket activation, nil if
+// the process was not socket activated. The activation environment is
+// cleared so child processes do not inherit it.
+func Listeners() ([]net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	listeners := make([]net.Listener, 0, count)
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			for _, listener := range listeners {
+				listener.Close()
+			}
+			return nil, fmt.Errorf("socket activation file descriptor %d is not a listening socket: %w", fd, err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// Notify sends a state change such as "READY=1" to the service manager and
+// reports whether it was sent; nothing is sent unless systemd expects
+// notifications (Type=notify)
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// Abstract namespace sockets start with a NUL byte
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to send notification: %w", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the watchdog timeout configured with WatchdogSec,
+// 0 if the watchdog is disabled for this process
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Watchdog sends keep-alive notifications to the systemd watchdog
+type Watchdog struct {
+	timeout  time.Duration
+	interval time.Duration
+	healthy  func(timeout time.Duration) bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// StartWatchdog starts sending WATCHDOG=1 at half the watchdog timeout, so
+// systemd restarts the exporter once it hangs. Notifications are withheld
+// while healthy, called with the watchdog timeout, reports that the exporter
+// makes no progress. It returns nil if the watchdog is disabled.
+func StartWatchdog(healthy func(timeout time.Duration) bool) *Watchdog {
+	timeout := WatchdogInterval()
+	if timeout <= 0 {
+		return nil
+	}
+
+	w := &Watchdog{
+		timeout:  timeout,
+		interval: timeout / 2,
+		healthy:  healthy,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	slog.Info("Starting systemd watchdog", "timeout", timeout)
+
+	go w.run()
+	return w
+}
+
+// run sends keep-alive notifications until Stop is called
+func (w *Watchdog) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !w.healthy(w.timeout) {
+				slog.Warn("Exporter is not making progress, skipping systemd watchdog notification")
+				continue
+			}
+			if _, err := Notify("WATCHDOG=1"); err != nil {
+				slog.Warn("Failed to notify systemd watchdog", "err", err)
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Stop stops sending keep-alive notifications
+func (w *Watchdog) Stop() {
+	if w == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+}